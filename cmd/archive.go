@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"myfacebook-dialog/internal/blobstore"
+	"myfacebook-dialog/internal/config"
+	"myfacebook-dialog/internal/db"
+	"myfacebook-dialog/internal/repository/archived"
+	sqlxrepo "myfacebook-dialog/internal/repository/sqlx"
+)
+
+const archiveMonthLayout = "2006-01"
+
+var (
+	errArchivePathRequired     = errors.New("DIALOG_ARCHIVE_LOCAL_PATH is required for archiving")
+	errArchiveDuringResharding = errors.New("dialogs cannot be archived or restored while DB_NEXT_SHARD_HOSTS is set")
+	errArchiveRangeRequired    = errors.New("both -from and -to months are required")
+)
+
+// newBlobStore returns the store of dialog archives, nil when archiving is not configured.
+func newBlobStore(envConfig *config.EnvConfig) blobstore.BlobStore { //nolint:ireturn
+	if envConfig.DialogArchiveLocalPath == "" {
+		return nil
+	}
+
+	return blobstore.NewLocalDisk(envConfig.DialogArchiveLocalPath)
+}
+
+// archive moves messages created in a range of months into archive files, restore moves them back.
+func archive(ctx context.Context, envConfig *config.EnvConfig, command string, args []string) error {
+	flagSet := flag.NewFlagSet(command, flag.ContinueOnError)
+	fromMonth := flagSet.String("from", "", "first month of the range as YYYY-MM")
+	toMonth := flagSet.String("to", "", "month after the last month of the range as YYYY-MM")
+
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf("invalid %s arguments: %w", command, err)
+	}
+
+	if *fromMonth == "" || *toMonth == "" {
+		return errArchiveRangeRequired
+	}
+
+	from, err := time.Parse(archiveMonthLayout, *fromMonth)
+	if err != nil {
+		return fmt.Errorf("invalid -from month: %w", err)
+	}
+
+	to, err := time.Parse(archiveMonthLayout, *toMonth)
+	if err != nil {
+		return fmt.Errorf("invalid -to month: %w", err)
+	}
+
+	blobStore := newBlobStore(envConfig)
+	if blobStore == nil {
+		return errArchivePathRequired
+	}
+
+	if err := requirePostgres(envConfig, []postgresSetting{{"DIALOG_ARCHIVE_LOCAL_PATH", true}}); err != nil {
+		return err
+	}
+
+	if len(envConfig.DBNextShardHosts) > 0 {
+		return errArchiveDuringResharding
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Archiving moves whole months in batches, its queries are not bound by the request timeouts.
+	ctx = db.WithStatementTimeout(ctx, 0)
+
+	appDB, err := connectAppDB(ctx, envConfig)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := appDB.Disconnect(); err != nil {
+			log.Fatalf("Failed to disconnect from app db: %s", err)
+		}
+	}()
+
+	shardMap, shardDBs, disconnectShards, err := connectCurrentShardMap(ctx, envConfig, appDB)
+	if err != nil {
+		return err
+	}
+
+	defer disconnectShards()
+
+	shards := make([]archived.Shard, 0, len(shardDBs))
+	for _, shardDB := range shardDBs {
+		shards = append(shards, sqlxrepo.NewDialogRepository(shardDB))
+	}
+
+	archiver := archived.NewArchiver(shards, shardMap.ShardIndex, sqlxrepo.NewDialogArchiveRepository(appDB), blobStore)
+
+	if command == "restore" {
+		err = archiver.Restore(ctx, from, to)
+	} else {
+		err = archiver.Archive(ctx, from, to)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s failed: %w", command, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+// Command fakemonolith serves the internal user and legacy dialog endpoints of the MyFacebook monolith from memory for local runs:
+//
+//	go run ./cmd/fakemonolith -seed users.json
+//
+// The seed is a JSON list of users, [{"id": "...", "tokens": ["..."]}]. Users and faults can be changed
+// at runtime through the /_fake/ endpoints, see fakemonolith.Server.ControlHandler.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"myfacebook-dialog/internal/fakemonolith"
+	"myfacebook-dialog/internal/httpserver"
+)
+
+const readHeaderTimeoutMilliseconds = 2000
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatalf("Fake monolith error: %s", err)
+	}
+}
+
+func run() error {
+	port := flag.String("port", "9092", "HTTP port, MYFACEBOOK_API_BASE_URL of the dialog service should point to it")
+	seedPath := flag.String("seed", "", "JSON file with users to serve")
+	latency := flag.Duration("latency", 0, "delay of every answer")
+	statusCode := flag.Int("status", 0, "answer every request with this status code instead of the user")
+
+	flag.Parse()
+
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+
+	server := fakemonolith.New()
+
+	if *seedPath != "" {
+		users, err := readSeed(*seedPath)
+		if err != nil {
+			return err
+		}
+
+		server.Seed(users)
+
+		slog.Info(fmt.Sprintf("Seeded %d users", len(users)))
+	}
+
+	if *latency > 0 || *statusCode != 0 {
+		server.SetFault(fakemonolith.Fault{Latency: *latency, StatusCode: *statusCode})
+	}
+
+	httpServer := httpserver.New(httpserver.Config{
+		Port:                          *port,
+		ReadHeaderTimeoutMilliseconds: readHeaderTimeoutMilliseconds,
+	}, server.Handler())
+
+	httpServerErrCh := httpServer.Start()
+	defer httpServer.Shutdown()
+
+	osSignals := make(chan os.Signal, 1)
+	signal.Notify(osSignals, os.Interrupt, syscall.SIGTERM)
+
+	select {
+	case osSignal := <-osSignals:
+		slog.Info(fmt.Sprintf("got signal from OS: %v. Exit...", osSignal))
+	case err := <-httpServerErrCh:
+		return fmt.Errorf("http server error: %w", err)
+	}
+
+	return nil
+}
+
+func readSeed(path string) ([]fakemonolith.User, error) {
+	seed, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seed: %w", err)
+	}
+
+	var users []fakemonolith.User
+
+	if err := json.Unmarshal(seed, &users); err != nil {
+		return nil, fmt.Errorf("failed to parse seed %q: %w", path, err)
+	}
+
+	return users, nil
+}
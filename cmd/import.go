@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"myfacebook-dialog/internal/config"
+	"myfacebook-dialog/internal/db"
+	"myfacebook-dialog/internal/legacyimport"
+	"myfacebook-dialog/internal/repository"
+	"myfacebook-dialog/internal/repository/sharded"
+	sqlxrepo "myfacebook-dialog/internal/repository/sqlx"
+)
+
+// importLockKey lets a single import run at a time, concurrent runs would overwrite each other's checkpoints.
+const importLockKey int64 = 0x696d706f7274 // "import"
+
+const (
+	importProgressInterval = 5 * time.Second
+	importTxAttempts       = 3
+	importTxRetryDelay     = 100 * time.Millisecond
+)
+
+var (
+	errImportFileRequired     = errors.New("-file is required")
+	errImportBatchSize        = errors.New("-batch-size must be positive")
+	errImportDuringResharding = errors.New("dialogs cannot be imported while DB_NEXT_SHARD_HOSTS is set")
+	errImportRunning          = errors.New("another import is running")
+	errImportRequiresPostgres = errors.New("import requires DB_DRIVER_NAME=postgres, it loads messages with COPY")
+)
+
+// importLegacyDialogs loads a JSONL or CSV export of messages of the monolith into the dialog shards.
+func importLegacyDialogs(ctx context.Context, envConfig *config.EnvConfig, args []string) error {
+	flagSet := flag.NewFlagSet("import", flag.ContinueOnError)
+	path := flagSet.String("file", "", "export of legacy messages")
+	format := flagSet.String("format", "", "format of the export, jsonl or csv, taken from the file extension when empty")
+	batchSize := flagSet.Int("batch-size", 1000, "rows imported in a transaction")
+	checkpointPath := flagSet.String("checkpoint", "", "file the progress is saved to, FILE.checkpoint when empty")
+	rejectedPath := flagSet.String("rejected", "", "file rejected rows are written to, FILE.rejected.jsonl when empty")
+
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf("invalid import arguments: %w", err)
+	}
+
+	importConfig, err := legacyImportConfig(*path, *format, *batchSize, *checkpointPath, *rejectedPath)
+	if err != nil {
+		return err
+	}
+
+	if envConfig.DBDriverName != db.DriverPostgres {
+		return errImportRequiresPostgres
+	}
+
+	if len(envConfig.DBNextShardHosts) > 0 {
+		return errImportDuringResharding
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// COPY of a large batch may take longer than the request timeouts.
+	ctx = db.WithStatementTimeout(ctx, 0)
+
+	appDB, err := connectAppDB(ctx, envConfig)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := appDB.Disconnect(); err != nil {
+			log.Fatalf("Failed to disconnect from app db: %s", err)
+		}
+	}()
+
+	unlock, locked, err := appDB.TryAdvisoryLock(ctx, importLockKey)
+	if err != nil {
+		return fmt.Errorf("failed to take import lock: %w", err)
+	}
+
+	if !locked {
+		return errImportRunning
+	}
+
+	defer unlock()
+
+	shardMap, shardDBs, disconnectShards, err := connectCurrentShardMap(ctx, envConfig, appDB)
+	if err != nil {
+		return err
+	}
+
+	defer disconnectShards()
+
+	importRepositories := make([]repository.DialogImportRepository, 0, len(shardDBs))
+	for _, shardDB := range shardDBs {
+		txManager := db.NewTxManager(shardDB, db.TxConfig{MaxAttempts: importTxAttempts, RetryDelay: importTxRetryDelay})
+		importRepositories = append(importRepositories, sqlxrepo.NewDialogImportRepository(shardDB, txManager))
+	}
+
+	importRepository, err := sharded.NewDialogImportRepository(shardMap, importRepositories)
+	if err != nil {
+		return fmt.Errorf("cannot create dialog import repository: %w", err)
+	}
+
+	totals, err := legacyimport.NewImporter(importRepository, importConfig).Import(ctx)
+	logImportTotals(importConfig, totals)
+
+	if err != nil {
+		return fmt.Errorf("import failed, run it again to resume: %w", err)
+	}
+
+	return nil
+}
+
+func legacyImportConfig(path, format string, batchSize int, checkpointPath, rejectedPath string) (legacyimport.Config, error) {
+	if path == "" {
+		return legacyimport.Config{}, errImportFileRequired
+	}
+
+	if batchSize < 1 {
+		return legacyimport.Config{}, errImportBatchSize
+	}
+
+	if format == "" {
+		var err error
+
+		format, err = legacyimport.FormatOf(path)
+		if err != nil {
+			return legacyimport.Config{}, err //nolint:wrapcheck
+		}
+	}
+
+	if checkpointPath == "" {
+		checkpointPath = path + ".checkpoint"
+	}
+
+	if rejectedPath == "" {
+		rejectedPath = path + ".rejected.jsonl"
+	}
+
+	return legacyimport.Config{
+		Path:             path,
+		Format:           format,
+		CheckpointPath:   checkpointPath,
+		RejectedPath:     rejectedPath,
+		BatchSize:        batchSize,
+		ProgressInterval: importProgressInterval,
+	}, nil
+}
+
+func logImportTotals(importConfig legacyimport.Config, totals legacyimport.Checkpoint) {
+	slog.Info(fmt.Sprintf("Import of %s: %d rows read, %d imported, %d skipped as imported before, %d rejected",
+		importConfig.Path, totals.Rows, totals.Imported, totals.Skipped, totals.RejectedCount()))
+
+	reasons := make([]string, 0, len(totals.Rejected))
+	for reason := range totals.Rejected {
+		reasons = append(reasons, reason)
+	}
+
+	sort.Strings(reasons)
+
+	for _, reason := range reasons {
+		slog.Info(fmt.Sprintf("Rejected %d rows: %s", totals.Rejected[reason], reason))
+	}
+
+	if len(reasons) > 0 {
+		slog.Info(fmt.Sprintf("Rejected rows are written to %s", importConfig.RejectedPath))
+	}
+}
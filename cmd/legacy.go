@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"myfacebook-dialog/internal/config"
+	"myfacebook-dialog/internal/myfacebookapiclient"
+	"myfacebook-dialog/internal/repository"
+	"myfacebook-dialog/internal/repository/rest"
+	"myfacebook-dialog/internal/repository/strangler"
+)
+
+var errInvalidShadowReadPercent = errors.New("LEGACY_DIALOG_SHADOW_READ_PERCENT must be from 0 to 100")
+
+// newAPIDialogRepository returns the dialog repository of the public API, which keeps the legacy dialogs
+// of the monolith in step while LEGACY_DIALOG_DUAL_WRITE or shadow reads are on. The internal API serves the monolith
+// and keeps using dialogRepository, so messages the monolith sends are not sent back to it.
+func newAPIDialogRepository(envConfig *config.EnvConfig, dialogRepository repository.DialogRepository,
+	myfacebookAPIClient *myfacebookapiclient.Client,
+) (repository.DialogRepository, error) { //nolint:ireturn
+	if envConfig.LegacyDialogShadowReadPercent < 0 || envConfig.LegacyDialogShadowReadPercent > 100 {
+		return nil, fmt.Errorf("%w: %d", errInvalidShadowReadPercent, envConfig.LegacyDialogShadowReadPercent)
+	}
+
+	if !envConfig.LegacyDialogDualWrite && envConfig.LegacyDialogShadowReadPercent == 0 {
+		return dialogRepository, nil
+	}
+
+	stranglerDialogRepository, err := strangler.NewDialogRepository(dialogRepository, rest.NewLegacyDialogRepository(myfacebookAPIClient),
+		strangler.DialogRepositoryConfig{
+			DualWrite:             envConfig.LegacyDialogDualWrite,
+			ShadowReadPercent:     envConfig.LegacyDialogShadowReadPercent,
+			ShadowReadConcurrency: envConfig.LegacyDialogShadowReadConcurrency,
+			Timeout:               time.Duration(envConfig.LegacyDialogTimeoutMilliseconds) * time.Millisecond,
+		})
+	if err != nil {
+		return nil, fmt.Errorf("cannot create strangler dialog repository: %w", err)
+	}
+
+	return stranglerDialogRepository, nil
+}
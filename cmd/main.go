@@ -2,45 +2,46 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"log/slog"
 	"os"
-	"os/signal"
-	"syscall"
+	"time"
 
-	"github.com/inbugay1/httprouter"
-	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
 	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
 	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
-	"myfacebook-dialog/internal/apiclient"
-	apiv1handler "myfacebook-dialog/internal/apiv1/handler"
-	apiv1middleware "myfacebook-dialog/internal/apiv1/middleware"
 	"myfacebook-dialog/internal/config"
 	"myfacebook-dialog/internal/db"
-	"myfacebook-dialog/internal/httpclient"
-	"myfacebook-dialog/internal/httphandler"
-	httproutermiddleware "myfacebook-dialog/internal/httprouter/middleware"
-	"myfacebook-dialog/internal/httpserver"
-	internalapihandler "myfacebook-dialog/internal/internalapi/handler"
-	internalapimiddleware "myfacebook-dialog/internal/internalapi/middleware"
-	"myfacebook-dialog/internal/myfacebookapiclient"
-	"myfacebook-dialog/internal/repository/rest"
-	sqlxrepo "myfacebook-dialog/internal/repository/sqlx"
+	"myfacebook-dialog/storage/migrations"
+)
+
+var (
+	errUnknownCommand = errors.New("unknown command")
+	errUnknownDriver  = errors.New("unknown database driver")
+
+	errInvalidReplicaCheckInterval = errors.New("DB_REPLICA_CHECK_INTERVAL_SECONDS must be positive when DB_REPLICA_HOSTS is set")
+
+	errInvalidMetricExportInterval = errors.New("OTEL_METRIC_EXPORT_INTERVAL_SECONDS must be positive")
 )
 
 func main() {
-	if err := run(); err != nil {
+	if err := run(os.Args[1:]); err != nil {
 		log.Fatalf("Application error: %s", err)
 	}
 }
 
-func run() error {
+// run starts the HTTP server with the serve command or when no command is given, other commands are used for maintenance.
+func run(args []string) error {
 	envConfig := config.GetConfigFromEnv()
 
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
@@ -50,123 +51,107 @@ func run() error {
 
 	ctx := context.Background()
 
-	tracerShutdown, err := initTracerProvider(ctx, envConfig)
-	if err != nil {
-		return fmt.Errorf("failed to init tracer provider: %w", err)
+	if len(args) == 0 {
+		return serve(ctx, envConfig)
 	}
 
-	defer func() {
-		if err := tracerShutdown(ctx); err != nil {
-			log.Fatalf("Failed to shutdown TracerProvider: %s", err)
-		}
-	}()
-
-	appDB := db.New(db.Config{
-		DriverName:         envConfig.DBDriverName,
-		Host:               envConfig.DBHost,
-		Port:               envConfig.DBPort,
-		User:               envConfig.DBUsername,
-		Password:           envConfig.DBPassword,
-		DBName:             envConfig.DBName,
-		SSLMode:            envConfig.DBSSLMode,
-		MaxOpenConnections: envConfig.DBMaxOpenConnections,
-		MigrationPath:      "./storage/migrations",
-	})
-
-	if err := appDB.Connect(context.Background()); err != nil {
-		return fmt.Errorf("cannot connect to appDB: %w", err)
-	}
-
-	defer func() {
-		if err := appDB.Disconnect(); err != nil {
-			log.Fatalf("Failed to disconnect from app db: %s", err)
-		}
-	}()
-
-	if err := appDB.Migrate(); err != nil {
-		return fmt.Errorf("appDB migration failed: %w", err)
+	switch args[0] {
+	case "serve":
+		return serve(ctx, envConfig)
+	case "migrate":
+		return migrateCommand(ctx, envConfig, args[1:])
+	case "reshard":
+		return reshard(ctx, envConfig)
+	case "archive", "restore":
+		return archive(ctx, envConfig, args[0], args[1:])
+	case "import":
+		return importLegacyDialogs(ctx, envConfig, args[1:])
+	default:
+		return fmt.Errorf("%w %q", errUnknownCommand, args[0])
 	}
+}
 
-	httpClient := httpclient.New(&httpclient.Config{
-		InsecureSkipVerify: true,
-	})
-
-	apiClient := apiclient.New(envConfig.MyfacbookAPIBaseURL, httpClient)
-
-	myfacebookAPIClient := myfacebookapiclient.New(apiClient)
-
-	dialogRepository := sqlxrepo.NewDialogRepository(appDB)
-	userRepository := rest.NewUserRepository(myfacebookAPIClient)
-
-	router := httprouter.New(httprouter.NewRegexRouteFactory())
-
-	requestResponseMiddleware := httproutermiddleware.NewRequestResponseLog()
-
-	apiV1ErrorResponseMiddleware := apiv1middleware.NewErrorResponse()
-	apiV1ErrorLogMiddleware := apiv1middleware.NewErrorLog()
-	apiV1AuthMiddleware := apiv1middleware.NewAuth(userRepository)
-
-	router.Use(httproutermiddleware.NewRenameTraceRootSpan())
-	router.Use(requestResponseMiddleware)
-
-	router.Get("/health", &httphandler.Health{}, "")
+// connectAppDB connects to the app database and runs its migrations unless DB_AUTO_MIGRATE is off.
+func connectAppDB(ctx context.Context, envConfig *config.EnvConfig) (*db.DB, error) {
+	appDBConfig, err := appDBConfig(envConfig)
+	if err != nil {
+		return nil, err
+	}
 
-	router.Group(func(router httprouter.Router) {
-		router.Use(
-			apiV1ErrorResponseMiddleware,
-			apiV1ErrorLogMiddleware,
-			apiV1AuthMiddleware,
-		)
+	appDB := db.New(appDBConfig)
 
-		router.Post(`/dialog/{user_id:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}}/send`,
-			&apiv1handler.SendDialog{
-				DialogRepository: dialogRepository,
-			}, "/dialog/{user_id}/send")
+	if err := appDB.Connect(ctx); err != nil {
+		return nil, fmt.Errorf("cannot connect to appDB: %w", err)
+	}
 
-		router.Get(`/dialog/{user_id:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}}/list`,
-			&apiv1handler.ListDialog{
-				DialogRepository: dialogRepository,
-			}, "/dialog/{user_id}/list")
-	})
+	if !envConfig.DBAutoMigrate {
+		return appDB, nil
+	}
 
-	internalAPIErrorResponseMiddleware := internalapimiddleware.NewErrorResponse()
-	internalAPIErrorLogMiddleware := internalapimiddleware.NewErrorLog()
+	if err := appDB.Migrate(ctx); err != nil {
+		_ = appDB.Disconnect()
 
-	router.Group(func(router httprouter.Router) {
-		router.Use(internalAPIErrorResponseMiddleware, internalAPIErrorLogMiddleware)
+		return nil, fmt.Errorf("appDB migration failed: %w", err)
+	}
 
-		router.Post("/int/dialog/send", &internalapihandler.SendDialog{
-			DialogRepository: dialogRepository,
-			UserRepository:   userRepository,
-		}, "")
+	return appDB, nil
+}
 
-		router.Get("/int/dialog/list", &internalapihandler.ListDialog{
-			DialogRepository: dialogRepository,
-		}, "")
-	})
+func appDBConfig(envConfig *config.EnvConfig) (db.Config, error) {
+	if len(envConfig.DBReplicaHosts) > 0 && envConfig.DBReplicaCheckIntervalSeconds <= 0 {
+		return db.Config{}, errInvalidReplicaCheckInterval
+	}
 
-	httpHandler := otelhttp.NewHandler(router, "")
+	replicas := make([]db.ReplicaConfig, 0, len(envConfig.DBReplicaHosts))
 
-	httpServer := httpserver.New(httpserver.Config{
-		Port:                          envConfig.HTTPPort,
-		RequestMaxHeaderBytes:         envConfig.RequestHeaderMaxSize,
-		ReadHeaderTimeoutMilliseconds: envConfig.RequestReadHeaderTimeoutMilliseconds,
-	}, httpHandler)
+	for _, replicaHost := range envConfig.DBReplicaHosts {
+		host, port, dbName, err := parseDBHost(replicaHost, envConfig.DBName)
+		if err != nil {
+			return db.Config{}, fmt.Errorf("invalid replica host: %w", err)
+		}
 
-	httpServerErrCh := httpServer.Start()
-	defer httpServer.Shutdown()
+		replicas = append(replicas, db.ReplicaConfig{Host: host, Port: port, DBName: dbName})
+	}
 
-	osSignals := make(chan os.Signal, 1)
-	signal.Notify(osSignals, os.Interrupt, syscall.SIGTERM)
+	var driverMigrations fs.FS
 
-	select {
-	case osSignal := <-osSignals:
-		slog.Info(fmt.Sprintf("got signal from OS: %v. Exit...", osSignal))
-	case err := <-httpServerErrCh:
-		return fmt.Errorf("http server error: %w", err)
+	switch envConfig.DBDriverName {
+	case db.DriverPostgres:
+		driverMigrations = migrations.Postgres
+	case db.DriverSQLite:
+		driverMigrations = migrations.SQLite
+	default:
+		return db.Config{}, fmt.Errorf("%w %q", errUnknownDriver, envConfig.DBDriverName)
 	}
 
-	return nil
+	return db.Config{
+		DriverName:               envConfig.DBDriverName,
+		Host:                     envConfig.DBHost,
+		Port:                     envConfig.DBPort,
+		User:                     envConfig.DBUsername,
+		Password:                 envConfig.DBPassword,
+		DBName:                   envConfig.DBName,
+		SSLMode:                  envConfig.DBSSLMode,
+		MaxOpenConnections:       envConfig.DBMaxOpenConnections,
+		MaxIdleConnections:       envConfig.DBMaxIdleConnections,
+		ConnectionMaxLifetime:    time.Duration(envConfig.DBConnectionMaxLifetimeSeconds) * time.Second,
+		ConnectionMaxIdleTime:    time.Duration(envConfig.DBConnectionMaxIdleTimeSeconds) * time.Second,
+		PoolWaitWarningThreshold: time.Duration(envConfig.DBPoolWaitWarningMilliseconds) * time.Millisecond,
+		PoolCheckInterval:        time.Duration(envConfig.DBPoolCheckIntervalSeconds) * time.Second,
+		ConnectAttempts:          envConfig.DBConnectAttempts,
+		ConnectRetryDelay:        time.Duration(envConfig.DBConnectRetryDelayMilliseconds) * time.Millisecond,
+		ConnectMaxRetryDelay:     time.Duration(envConfig.DBConnectMaxRetryDelayMilliseconds) * time.Millisecond,
+		HealthCheckInterval:      time.Duration(envConfig.DBHealthCheckIntervalSeconds) * time.Second,
+		ReadTimeout:              time.Duration(envConfig.DBReadTimeoutMilliseconds) * time.Millisecond,
+		WriteTimeout:             time.Duration(envConfig.DBWriteTimeoutMilliseconds) * time.Millisecond,
+		ReadRetryAttempts:        envConfig.DBReadRetryAttempts,
+		ReadRetryDelay:           time.Duration(envConfig.DBReadRetryDelayMilliseconds) * time.Millisecond,
+		Migrations:               driverMigrations,
+		Path:                     envConfig.DBPath,
+		Replicas:                 replicas,
+		ReplicaMaxLag:            time.Duration(envConfig.DBReplicaMaxLagSeconds) * time.Second,
+		ReplicaCheckInterval:     time.Duration(envConfig.DBReplicaCheckIntervalSeconds) * time.Second,
+	}, nil
 }
 
 func logLevel(lvl string) slog.Level {
@@ -185,13 +170,9 @@ func logLevel(lvl string) slog.Level {
 }
 
 func initTracerProvider(ctx context.Context, envConfig *config.EnvConfig) (func(context.Context) error, error) {
-	res, err := resource.Merge(resource.Default(),
-		resource.NewWithAttributes(semconv.SchemaURL,
-			semconv.ServiceName(envConfig.ServiceName),
-			semconv.ServiceVersion(envConfig.Version),
-		))
+	res, err := serviceResource(envConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create resource: %w", err)
+		return nil, err
 	}
 
 	traceExporter, err := getTraceExporter(ctx, envConfig)
@@ -231,3 +212,72 @@ func getTraceExporter(ctx context.Context, envConfig *config.EnvConfig) (sdktrac
 
 	return traceExporter, nil
 }
+
+func serviceResource(envConfig *config.EnvConfig) (*resource.Resource, error) {
+	res, err := resource.Merge(resource.Default(),
+		resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceName(envConfig.ServiceName),
+			semconv.ServiceVersion(envConfig.Version),
+		))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %w", err)
+	}
+
+	return res, nil
+}
+
+// initMeterProvider exports the metrics of otel.Meter, like the db.pool, dialog.purge and dialog.legacy ones.
+// Without it they are recorded by a no-op provider and never leave the process.
+func initMeterProvider(ctx context.Context, envConfig *config.EnvConfig) (func(context.Context) error, error) {
+	if envConfig.OTelMetricsExporterType == "none" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	if envConfig.OTelMetricExportIntervalSeconds <= 0 {
+		return nil, errInvalidMetricExportInterval
+	}
+
+	res, err := serviceResource(envConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	metricExporter, err := getMetricExporter(ctx, envConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create exporter: %w", err)
+	}
+
+	meterProvider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter,
+			sdkmetric.WithInterval(time.Duration(envConfig.OTelMetricExportIntervalSeconds)*time.Second))),
+		sdkmetric.WithResource(res),
+	)
+	otel.SetMeterProvider(meterProvider)
+
+	// Shutdown will export the last collected metrics and shut down the exporter.
+	return meterProvider.Shutdown, nil
+}
+
+func getMetricExporter(ctx context.Context, envConfig *config.EnvConfig) (sdkmetric.Exporter, error) { //nolint:ireturn
+	switch envConfig.OTelMetricsExporterType { //nolint:gocritic
+	case "otel_http":
+		endpoint := envConfig.OTelExporterOTLPMetricsEndpoint
+		if endpoint == "" {
+			endpoint = envConfig.OTelExporterOTLPEndpoint
+		}
+
+		metricExporter, err := otlpmetrichttp.New(ctx, otlpmetrichttp.WithEndpoint(endpoint), otlpmetrichttp.WithInsecure())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create otlp exporter: %w", err)
+		}
+
+		return metricExporter, nil
+	}
+
+	metricExporter, err := stdoutmetric.New()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdout exporter: %w", err)
+	}
+
+	return metricExporter, nil
+}
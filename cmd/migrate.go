@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"myfacebook-dialog/internal/config"
+	"myfacebook-dialog/internal/db"
+)
+
+const appDBTarget = "app"
+
+var (
+	errUnknownMigrateCommand = errors.New("unknown migrate command, expected up, down N, status or force V")
+	errMigrateArgument       = errors.New("invalid migrate argument")
+	errUnknownMigrateTarget  = errors.New("database is neither the app database nor a configured shard")
+)
+
+// migrateTarget is a database migrated by the migrate command.
+type migrateTarget struct {
+	name   string
+	config db.Config
+}
+
+// migrateCommand migrates the app database and every shard of DB_SHARD_HOSTS and DB_NEXT_SHARD_HOSTS:
+//
+//	migrate up         applies pending migrations
+//	migrate down N     reverts the last N migrations, fails without changes when one of them is irreversible
+//	migrate status     logs the applied and the pending migrations
+//	migrate force V    records V as applied and clears the dirty flag after a failed migration was fixed by hand
+//
+// -db limits the command to the app database ("app") or to a shard given as in DB_SHARD_HOSTS.
+func migrateCommand(ctx context.Context, envConfig *config.EnvConfig, args []string) error {
+	flagSet := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	only := flagSet.String("db", "", `database to migrate, "app" or a shard host, every database when empty`)
+
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf("invalid migrate arguments: %w", err)
+	}
+
+	migrate, err := migrateAction(flagSet.Args())
+	if err != nil {
+		return err
+	}
+
+	targets, err := migrateTargets(envConfig, *only)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for _, target := range targets {
+		if err := migrateTargetDB(ctx, target, migrate); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func migrateAction(args []string) (func(ctx context.Context, targetDB *db.DB, name string) error, error) {
+	if len(args) == 0 {
+		return nil, errUnknownMigrateCommand
+	}
+
+	command, args := args[0], args[1:]
+
+	switch {
+	case command == "up" && len(args) == 0:
+		return func(ctx context.Context, targetDB *db.DB, _ string) error {
+			return targetDB.Migrate(ctx) //nolint:wrapcheck
+		}, nil
+	case command == "down" && len(args) == 1:
+		steps, err := strconv.Atoi(args[0])
+		if err != nil || steps < 1 {
+			return nil, fmt.Errorf("%w: down expects a positive number of migrations, got %q", errMigrateArgument, args[0])
+		}
+
+		return func(ctx context.Context, targetDB *db.DB, _ string) error {
+			return targetDB.MigrateDown(ctx, steps) //nolint:wrapcheck
+		}, nil
+	case command == "force" && len(args) == 1:
+		version, err := strconv.Atoi(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("%w: force expects a migration version, got %q", errMigrateArgument, args[0])
+		}
+
+		return func(ctx context.Context, targetDB *db.DB, _ string) error {
+			return targetDB.ForceMigrationVersion(ctx, version) //nolint:wrapcheck
+		}, nil
+	case command == "status" && len(args) == 0:
+		return logMigrationStatus, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", errUnknownMigrateCommand, command)
+	}
+}
+
+func logMigrationStatus(ctx context.Context, targetDB *db.DB, name string) error {
+	status, err := targetDB.MigrationStatus(ctx)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	slog.Info(fmt.Sprintf("Migration status of %s: version %d, dirty %t, latest %d, %d pending %v",
+		name, status.Version, status.Dirty, status.Latest, len(status.Pending), status.Pending))
+
+	return nil
+}
+
+// migrateTargets lists the app database and the shards, only the one named by only when it is set.
+func migrateTargets(envConfig *config.EnvConfig, only string) ([]migrateTarget, error) {
+	appDBConfig, err := appDBConfig(envConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := []migrateTarget{{name: appDBTarget, config: appDBConfig}}
+
+	// Migrations take no replicas, they follow the primary.
+	targets[0].config.Replicas = nil
+
+	shardHosts := make([]string, 0, len(envConfig.DBShardHosts)+len(envConfig.DBNextShardHosts))
+	shardHosts = append(shardHosts, envConfig.DBShardHosts...)
+	shardHosts = append(shardHosts, envConfig.DBNextShardHosts...)
+
+	for _, shardHost := range shardHosts {
+		shardDBConfig, err := shardDBConfig(envConfig, shardHost)
+		if err != nil {
+			return nil, err
+		}
+
+		targets = append(targets, migrateTarget{name: "shard " + shardHost, config: shardDBConfig})
+	}
+
+	if only == "" {
+		return targets, nil
+	}
+
+	for _, target := range targets {
+		if target.name == only || target.name == "shard "+only {
+			return []migrateTarget{target}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %q", errUnknownMigrateTarget, only)
+}
+
+func migrateTargetDB(ctx context.Context, target migrateTarget,
+	migrate func(ctx context.Context, targetDB *db.DB, name string) error,
+) error {
+	targetDB := db.New(target.config)
+
+	if err := targetDB.Connect(ctx); err != nil {
+		return fmt.Errorf("cannot connect to %s: %w", target.name, err)
+	}
+
+	defer func() {
+		if err := targetDB.Disconnect(); err != nil {
+			slog.Error(fmt.Sprintf("Failed to disconnect from %s: %s", target.name, err))
+		}
+	}()
+
+	if err := migrate(ctx, targetDB, target.name); err != nil {
+		return fmt.Errorf("%s: %w", target.name, err)
+	}
+
+	return nil
+}
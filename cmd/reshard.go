@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"myfacebook-dialog/internal/config"
+	"myfacebook-dialog/internal/db"
+	"myfacebook-dialog/internal/repository/sharded"
+	sqlxrepo "myfacebook-dialog/internal/repository/sqlx"
+)
+
+var errNextShardHostsRequired = errors.New("DB_NEXT_SHARD_HOSTS is required for resharding")
+
+// reshard moves dialogs from the current shard map to the next one, a rerun continues an interrupted move.
+func reshard(ctx context.Context, envConfig *config.EnvConfig) error {
+	if len(envConfig.DBNextShardHosts) == 0 {
+		return errNextShardHostsRequired
+	}
+
+	if err := requirePostgres(envConfig, []postgresSetting{{"DB_NEXT_SHARD_HOSTS", true}}); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Resharding copies and verifies dialogs in batches, its queries are not bound by the request timeouts.
+	ctx = db.WithStatementTimeout(ctx, 0)
+
+	appDB, err := connectAppDB(ctx, envConfig)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := appDB.Disconnect(); err != nil {
+			log.Fatalf("Failed to disconnect from app db: %s", err)
+		}
+	}()
+
+	currentShardMap, _, disconnectCurrent, err := connectCurrentShardMap(ctx, envConfig, appDB)
+	if err != nil {
+		return err
+	}
+
+	defer disconnectCurrent()
+
+	nextShardMap, _, disconnectNext, err := connectNextShardMap(ctx, envConfig)
+	if err != nil {
+		return err
+	}
+
+	defer disconnectNext()
+
+	resharder := sharded.NewResharder(currentShardMap, nextShardMap, sqlxrepo.NewShardMigrationRepository(appDB), sharded.ResharderConfig{
+		BatchSize:      envConfig.ReshardingBatchSize,
+		DualWriteWait:  time.Duration(envConfig.ReshardingDualWriteWaitSeconds) * time.Second,
+		VerifyAttempts: envConfig.ReshardingVerifyAttempts,
+	})
+
+	if err := resharder.Run(ctx); err != nil {
+		return fmt.Errorf("resharding failed: %w", err)
+	}
+
+	return nil
+}
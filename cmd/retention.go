@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"myfacebook-dialog/internal/config"
+	"myfacebook-dialog/internal/db"
+	"myfacebook-dialog/internal/maintenance"
+	sqlxrepo "myfacebook-dialog/internal/repository/sqlx"
+)
+
+var (
+	errInvalidRetention      = errors.New("retention must be message_type:days with non-negative days")
+	errInvalidPurgeBatchSize = errors.New("purge batch size must be positive")
+	errInvalidPurgeInterval  = errors.New("purge interval must be positive")
+)
+
+const day = 24 * time.Hour
+
+// startDialogPurger purges dialog messages past their retention in the background, nothing runs without a retention.
+func startDialogPurger(ctx context.Context, envConfig *config.EnvConfig, dialogDBs []*db.DB) error {
+	retentionPolicy, err := newRetentionPolicy(envConfig)
+	if err != nil {
+		return err
+	}
+
+	if retentionPolicy.IsEmpty() {
+		return nil
+	}
+
+	if envConfig.DialogPurgeBatchSize <= 0 {
+		return fmt.Errorf("%w: %d", errInvalidPurgeBatchSize, envConfig.DialogPurgeBatchSize)
+	}
+
+	if envConfig.DialogPurgeIntervalMinutes <= 0 {
+		return fmt.Errorf("%w: %d", errInvalidPurgeInterval, envConfig.DialogPurgeIntervalMinutes)
+	}
+
+	targets := make([]maintenance.DialogPurgeTarget, 0, len(dialogDBs))
+	for _, dialogDB := range dialogDBs {
+		targets = append(targets, maintenance.DialogPurgeTarget{
+			Locker:                dialogDB,
+			DialogPurgeRepository: sqlxrepo.NewDialogPurgeRepository(dialogDB),
+		})
+	}
+
+	dialogPurger, err := maintenance.NewDialogPurger(targets, maintenance.DialogPurgerConfig{
+		Policy:     retentionPolicy,
+		BatchSize:  envConfig.DialogPurgeBatchSize,
+		BatchPause: time.Duration(envConfig.DialogPurgeBatchPauseMilliseconds) * time.Millisecond,
+		DryRun:     envConfig.DialogPurgeDryRun,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot create dialog purger: %w", err)
+	}
+
+	go dialogPurger.Run(ctx, time.Duration(envConfig.DialogPurgeIntervalMinutes)*time.Minute)
+
+	return nil
+}
+
+func newRetentionPolicy(envConfig *config.EnvConfig) (maintenance.RetentionPolicy, error) {
+	if envConfig.DialogRetentionDays < 0 {
+		return maintenance.RetentionPolicy{}, fmt.Errorf("%w: %d", errInvalidRetention, envConfig.DialogRetentionDays)
+	}
+
+	retentionPolicy := maintenance.RetentionPolicy{
+		Default:       time.Duration(envConfig.DialogRetentionDays) * day,
+		ByMessageType: make(map[string]time.Duration, len(envConfig.DialogRetentionDaysByMessageType)),
+	}
+
+	for _, retention := range envConfig.DialogRetentionDaysByMessageType {
+		messageType, daysStr, found := strings.Cut(retention, ":")
+
+		days, err := strconv.Atoi(daysStr)
+		if !found || messageType == "" || err != nil || days < 0 {
+			return maintenance.RetentionPolicy{}, fmt.Errorf("%w: %q", errInvalidRetention, retention)
+		}
+
+		retentionPolicy.ByMessageType[messageType] = time.Duration(days) * day
+	}
+
+	return retentionPolicy, nil
+}
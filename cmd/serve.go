@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/inbugay1/httprouter"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"myfacebook-dialog/internal/apiclient"
+	apiv1handler "myfacebook-dialog/internal/apiv1/handler"
+	apiv1middleware "myfacebook-dialog/internal/apiv1/middleware"
+	"myfacebook-dialog/internal/config"
+	"myfacebook-dialog/internal/httpclient"
+	"myfacebook-dialog/internal/httphandler"
+	httproutermiddleware "myfacebook-dialog/internal/httprouter/middleware"
+	"myfacebook-dialog/internal/httpserver"
+	internalapihandler "myfacebook-dialog/internal/internalapi/handler"
+	internalapimiddleware "myfacebook-dialog/internal/internalapi/middleware"
+	"myfacebook-dialog/internal/myfacebookapiclient"
+	"myfacebook-dialog/internal/repository/rest"
+)
+
+const (
+	userIDRoutePattern    = `{user_id:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}}`
+	messageIDRoutePattern = `{message_id:[0-9]+|[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}}`
+)
+
+func serve(ctx context.Context, envConfig *config.EnvConfig) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	tracerShutdown, err := initTracerProvider(ctx, envConfig)
+	if err != nil {
+		return fmt.Errorf("failed to init tracer provider: %w", err)
+	}
+
+	defer func() {
+		if err := tracerShutdown(ctx); err != nil {
+			log.Fatalf("Failed to shutdown TracerProvider: %s", err)
+		}
+	}()
+
+	meterShutdown, err := initMeterProvider(ctx, envConfig)
+	if err != nil {
+		return fmt.Errorf("failed to init meter provider: %w", err)
+	}
+
+	defer func() {
+		if err := meterShutdown(ctx); err != nil {
+			log.Fatalf("Failed to shutdown MeterProvider: %s", err)
+		}
+	}()
+
+	appDB, err := connectAppDB(ctx, envConfig)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := appDB.Disconnect(); err != nil {
+			log.Fatalf("Failed to disconnect from app db: %s", err)
+		}
+	}()
+
+	go appDB.WatchReplicas(ctx)
+
+	dialogStorage, err := newStorage(ctx, envConfig, appDB)
+	if err != nil {
+		return err
+	}
+
+	defer dialogStorage.close()
+
+	readinessDependencies := make([]httphandler.Dependency, 0, len(dialogStorage.dbs))
+	for _, storageDB := range dialogStorage.dbs {
+		go storageDB.WatchHealth(ctx)
+
+		readinessDependencies = append(readinessDependencies, storageDB)
+	}
+
+	dialogRepository := dialogStorage.dialogRepository
+	dialogPinRepository := dialogStorage.dialogPinRepository
+	dialogSettingsRepository := dialogStorage.dialogSettingsRepository
+	dialogStarRepository := dialogStorage.dialogStarRepository
+	dialogDraftRepository := dialogStorage.dialogDraftRepository
+	dialogInboxRepository := dialogStorage.dialogInboxRepository
+
+	httpClient := httpclient.New(&httpclient.Config{
+		InsecureSkipVerify: true,
+	})
+
+	apiClient := apiclient.New(envConfig.MyfacbookAPIBaseURL, httpClient)
+
+	myfacebookAPIClient := myfacebookapiclient.New(apiClient)
+
+	userRepository := rest.NewUserRepository(myfacebookAPIClient)
+
+	apiDialogRepository, err := newAPIDialogRepository(envConfig, dialogRepository, myfacebookAPIClient)
+	if err != nil {
+		return err
+	}
+
+	router := httprouter.New(httprouter.NewRegexRouteFactory())
+
+	requestResponseMiddleware := httproutermiddleware.NewRequestResponseLog()
+
+	apiV1ErrorResponseMiddleware := apiv1middleware.NewErrorResponse()
+	apiV1ErrorLogMiddleware := apiv1middleware.NewErrorLog()
+	apiV1AuthMiddleware := apiv1middleware.NewAuth(userRepository)
+
+	router.Use(httproutermiddleware.NewRenameTraceRootSpan())
+	router.Use(requestResponseMiddleware)
+
+	router.Get("/health", &httphandler.Health{}, "")
+	router.Get("/ready", &httphandler.Ready{Dependencies: readinessDependencies}, "")
+
+	router.Group(func(router httprouter.Router) {
+		router.Use(
+			apiV1ErrorResponseMiddleware,
+			apiV1ErrorLogMiddleware,
+			apiV1AuthMiddleware,
+		)
+
+		router.Post("/dialog/"+userIDRoutePattern+"/send",
+			&apiv1handler.SendDialog{
+				DialogRepository:      apiDialogRepository,
+				DialogDraftRepository: dialogDraftRepository,
+			}, "/dialog/{user_id}/send")
+
+		router.Get("/dialog/"+userIDRoutePattern+"/list",
+			&apiv1handler.ListDialog{
+				DialogRepository:    apiDialogRepository,
+				DialogPinRepository: dialogPinRepository,
+			}, "/dialog/{user_id}/list")
+
+		router.Post("/dialog/"+userIDRoutePattern+"/pin",
+			&apiv1handler.PinDialogMessage{
+				DialogRepository:    apiDialogRepository,
+				DialogPinRepository: dialogPinRepository,
+				MaxPinnedMessages:   envConfig.DialogMaxPinnedMessages,
+			}, "/dialog/{user_id}/pin")
+
+		router.Post("/dialog/"+userIDRoutePattern+"/unpin",
+			&apiv1handler.UnpinDialogMessage{
+				DialogRepository:    apiDialogRepository,
+				DialogPinRepository: dialogPinRepository,
+			}, "/dialog/{user_id}/unpin")
+
+		router.Get("/dialog/"+userIDRoutePattern+"/pins",
+			&apiv1handler.ListDialogPins{
+				DialogRepository:    apiDialogRepository,
+				DialogPinRepository: dialogPinRepository,
+			}, "/dialog/{user_id}/pins")
+
+		router.Post("/dialog/"+userIDRoutePattern+"/read",
+			&apiv1handler.ReadDialog{
+				DialogInboxRepository: dialogInboxRepository,
+			}, "/dialog/{user_id}/read")
+
+		router.Get("/dialog/inbox",
+			&apiv1handler.ListDialogInbox{
+				DialogInboxRepository: dialogInboxRepository,
+			}, "")
+
+		router.Get("/dialog/settings",
+			&apiv1handler.ListDialogSettings{
+				DialogSettingsRepository: dialogSettingsRepository,
+			}, "")
+
+		router.Get("/dialog/"+userIDRoutePattern+"/settings",
+			&apiv1handler.GetDialogSettings{
+				DialogSettingsRepository: dialogSettingsRepository,
+			}, "/dialog/{user_id}/settings")
+
+		router.Put("/dialog/"+userIDRoutePattern+"/settings",
+			&apiv1handler.UpdateDialogSettings{
+				DialogSettingsRepository: dialogSettingsRepository,
+			}, "/dialog/{user_id}/settings")
+
+		router.Delete("/dialog/"+userIDRoutePattern+"/settings",
+			&apiv1handler.DeleteDialogSettings{
+				DialogSettingsRepository: dialogSettingsRepository,
+			}, "/dialog/{user_id}/settings")
+
+		router.Post("/dialog/message/"+messageIDRoutePattern+"/star",
+			&apiv1handler.StarDialogMessage{
+				DialogRepository:     apiDialogRepository,
+				DialogStarRepository: dialogStarRepository,
+			}, "/dialog/message/{message_id}/star")
+
+		router.Post("/dialog/message/"+messageIDRoutePattern+"/unstar",
+			&apiv1handler.UnstarDialogMessage{
+				DialogRepository:     apiDialogRepository,
+				DialogStarRepository: dialogStarRepository,
+			}, "/dialog/message/{message_id}/unstar")
+
+		router.Post("/dialog/message/"+messageIDRoutePattern+"/forward",
+			&apiv1handler.ForwardDialogMessage{
+				DialogRepository: apiDialogRepository,
+				UserRepository:   userRepository,
+			}, "/dialog/message/{message_id}/forward")
+
+		router.Get("/dialog/drafts",
+			&apiv1handler.ListDialogDrafts{
+				DialogDraftRepository: dialogDraftRepository,
+			}, "")
+
+		router.Get("/dialog/"+userIDRoutePattern+"/draft",
+			&apiv1handler.GetDialogDraft{
+				DialogDraftRepository: dialogDraftRepository,
+			}, "/dialog/{user_id}/draft")
+
+		router.Put("/dialog/"+userIDRoutePattern+"/draft",
+			&apiv1handler.SaveDialogDraft{
+				DialogDraftRepository: dialogDraftRepository,
+			}, "/dialog/{user_id}/draft")
+
+		router.Delete("/dialog/"+userIDRoutePattern+"/draft",
+			&apiv1handler.DeleteDialogDraft{
+				DialogDraftRepository: dialogDraftRepository,
+			}, "/dialog/{user_id}/draft")
+
+		router.Get("/dialog/starred",
+			&apiv1handler.ListStarredDialogMessages{
+				DialogRepository:     apiDialogRepository,
+				DialogStarRepository: dialogStarRepository,
+			}, "")
+	})
+
+	internalAPIErrorResponseMiddleware := internalapimiddleware.NewErrorResponse()
+	internalAPIErrorLogMiddleware := internalapimiddleware.NewErrorLog()
+
+	router.Group(func(router httprouter.Router) {
+		router.Use(internalAPIErrorResponseMiddleware, internalAPIErrorLogMiddleware)
+
+		router.Post("/int/dialog/send", &internalapihandler.SendDialog{
+			DialogRepository:      dialogRepository,
+			DialogDraftRepository: dialogDraftRepository,
+			UserRepository:        userRepository,
+		}, "")
+
+		router.Get("/int/dialog/list", &internalapihandler.ListDialog{
+			DialogRepository: dialogRepository,
+		}, "")
+	})
+
+	httpHandler := otelhttp.NewHandler(router, "")
+
+	httpServer := httpserver.New(httpserver.Config{
+		Port:                          envConfig.HTTPPort,
+		RequestMaxHeaderBytes:         envConfig.RequestHeaderMaxSize,
+		ReadHeaderTimeoutMilliseconds: envConfig.RequestReadHeaderTimeoutMilliseconds,
+	}, httpHandler)
+
+	httpServerErrCh := httpServer.Start()
+	defer httpServer.Shutdown()
+
+	osSignals := make(chan os.Signal, 1)
+	signal.Notify(osSignals, os.Interrupt, syscall.SIGTERM)
+
+	select {
+	case osSignal := <-osSignals:
+		slog.Info(fmt.Sprintf("got signal from OS: %v. Exit...", osSignal))
+	case err := <-httpServerErrCh:
+		return fmt.Errorf("http server error: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"myfacebook-dialog/internal/config"
+	"myfacebook-dialog/internal/db"
+	"myfacebook-dialog/internal/repository"
+	"myfacebook-dialog/internal/repository/sharded"
+	sqlxrepo "myfacebook-dialog/internal/repository/sqlx"
+	"myfacebook-dialog/storage/migrations"
+)
+
+var (
+	errInvalidNextShardMapVersion  = errors.New("next shard map version must differ from the current one")
+	errInvalidPhaseRefreshInterval = errors.New("RESHARDING_PHASE_REFRESH_SECONDS must be positive when DB_NEXT_SHARD_HOSTS is set")
+)
+
+// newDialogRepository routes dialogs to shards, the app database is the only shard when none are configured.
+// While resharding is configured, dialogs follow the phase of the shard migration.
+// It also returns every database that stores dialogs.
+func newDialogRepository(ctx context.Context, envConfig *config.EnvConfig, appDB *db.DB) (repository.DialogRepository, []*db.DB, func(), error) { //nolint:ireturn
+	if len(envConfig.DBNextShardHosts) > 0 && envConfig.ReshardingPhaseRefreshSeconds <= 0 {
+		return nil, nil, nil, errInvalidPhaseRefreshInterval
+	}
+
+	currentShardMap, currentShardDBs, disconnectCurrent, err := connectCurrentShardMap(ctx, envConfig, appDB)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if len(envConfig.DBNextShardHosts) == 0 {
+		return sharded.NewDialogRepository(currentShardMap), currentShardDBs, disconnectCurrent, nil
+	}
+
+	nextShardMap, nextShardDBs, disconnectNext, err := connectNextShardMap(ctx, envConfig)
+	if err != nil {
+		disconnectCurrent()
+
+		return nil, nil, nil, err
+	}
+
+	disconnect := func() {
+		disconnectNext()
+		disconnectCurrent()
+	}
+
+	reshardingDialogRepository := sharded.NewReshardingDialogRepository(currentShardMap, nextShardMap,
+		sqlxrepo.NewShardMigrationRepository(appDB))
+
+	if err := reshardingDialogRepository.RefreshPhase(ctx); err != nil {
+		disconnect()
+
+		return nil, nil, nil, fmt.Errorf("cannot load shard migration phase: %w", err)
+	}
+
+	go reshardingDialogRepository.Watch(ctx, time.Duration(envConfig.ReshardingPhaseRefreshSeconds)*time.Second)
+
+	return reshardingDialogRepository, append(currentShardDBs, nextShardDBs...), disconnect, nil
+}
+
+func connectCurrentShardMap(ctx context.Context, envConfig *config.EnvConfig, appDB *db.DB) (*sharded.ShardMap, []*db.DB, func(), error) {
+	shardDBs := []*db.DB{appDB}
+	disconnect := func() {}
+
+	if len(envConfig.DBShardHosts) > 0 {
+		var err error
+
+		shardDBs, err = connectShardDBs(ctx, envConfig, envConfig.DBShardHosts)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("cannot connect to dialog shards: %w", err)
+		}
+
+		disconnect = func() {
+			disconnectShardDBs(shardDBs)
+		}
+	}
+
+	shardMap, err := newShardMap(ctx, envConfig.DBShardMapVersion, shardDBs)
+	if err != nil {
+		disconnect()
+
+		return nil, nil, nil, fmt.Errorf("cannot create dialog shard map: %w", err)
+	}
+
+	return shardMap, shardDBs, disconnect, nil
+}
+
+func connectNextShardMap(ctx context.Context, envConfig *config.EnvConfig) (*sharded.ShardMap, []*db.DB, func(), error) {
+	if envConfig.DBNextShardMapVersion == envConfig.DBShardMapVersion {
+		return nil, nil, nil, fmt.Errorf("%w: %d", errInvalidNextShardMapVersion, envConfig.DBNextShardMapVersion)
+	}
+
+	shardDBs, err := connectShardDBs(ctx, envConfig, envConfig.DBNextShardHosts)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("cannot connect to next dialog shards: %w", err)
+	}
+
+	shardMap, err := newShardMap(ctx, envConfig.DBNextShardMapVersion, shardDBs)
+	if err != nil {
+		disconnectShardDBs(shardDBs)
+
+		return nil, nil, nil, fmt.Errorf("cannot create next dialog shard map: %w", err)
+	}
+
+	return shardMap, shardDBs, func() { disconnectShardDBs(shardDBs) }, nil
+}
+
+// connectShardDBs connects to every shard and runs its migrations unless DB_AUTO_MIGRATE is off.
+func connectShardDBs(ctx context.Context, envConfig *config.EnvConfig, shardHosts []string) ([]*db.DB, error) {
+	shardDBs := make([]*db.DB, 0, len(shardHosts))
+
+	for _, shardHost := range shardHosts {
+		shardDB, err := connectShardDB(ctx, envConfig, shardHost)
+		if err != nil {
+			disconnectShardDBs(shardDBs)
+
+			return nil, err
+		}
+
+		shardDBs = append(shardDBs, shardDB)
+	}
+
+	return shardDBs, nil
+}
+
+func connectShardDB(ctx context.Context, envConfig *config.EnvConfig, shardHost string) (*db.DB, error) {
+	shardDBConfig, err := shardDBConfig(envConfig, shardHost)
+	if err != nil {
+		return nil, err
+	}
+
+	shardDB := db.New(shardDBConfig)
+
+	if err := shardDB.Connect(ctx); err != nil {
+		return nil, fmt.Errorf("cannot connect to shard %q: %w", shardHost, err)
+	}
+
+	if envConfig.DBAutoMigrate {
+		if err := shardDB.Migrate(ctx); err != nil {
+			_ = shardDB.Disconnect()
+
+			return nil, fmt.Errorf("shard %q migration failed: %w", shardHost, err)
+		}
+	}
+
+	return shardDB, nil
+}
+
+func disconnectShardDBs(shardDBs []*db.DB) {
+	for _, shardDB := range shardDBs {
+		if err := shardDB.Disconnect(); err != nil {
+			slog.Error(fmt.Sprintf("Failed to disconnect from shard db: %s", err))
+		}
+	}
+}
+
+// shardDBConfig builds a shard config from host:port or host:port/dbname, the rest is shared with the app database.
+func shardDBConfig(envConfig *config.EnvConfig, shardHost string) (db.Config, error) {
+	host, port, dbName, err := parseDBHost(shardHost, envConfig.DBName)
+	if err != nil {
+		return db.Config{}, fmt.Errorf("invalid shard host: %w", err)
+	}
+
+	return db.Config{
+		DriverName:               envConfig.DBDriverName,
+		Host:                     host,
+		Port:                     port,
+		User:                     envConfig.DBUsername,
+		Password:                 envConfig.DBPassword,
+		DBName:                   dbName,
+		SSLMode:                  envConfig.DBSSLMode,
+		MaxOpenConnections:       envConfig.DBMaxOpenConnections,
+		MaxIdleConnections:       envConfig.DBMaxIdleConnections,
+		ConnectionMaxLifetime:    time.Duration(envConfig.DBConnectionMaxLifetimeSeconds) * time.Second,
+		ConnectionMaxIdleTime:    time.Duration(envConfig.DBConnectionMaxIdleTimeSeconds) * time.Second,
+		PoolWaitWarningThreshold: time.Duration(envConfig.DBPoolWaitWarningMilliseconds) * time.Millisecond,
+		PoolCheckInterval:        time.Duration(envConfig.DBPoolCheckIntervalSeconds) * time.Second,
+		ConnectAttempts:          envConfig.DBConnectAttempts,
+		ConnectRetryDelay:        time.Duration(envConfig.DBConnectRetryDelayMilliseconds) * time.Millisecond,
+		ConnectMaxRetryDelay:     time.Duration(envConfig.DBConnectMaxRetryDelayMilliseconds) * time.Millisecond,
+		HealthCheckInterval:      time.Duration(envConfig.DBHealthCheckIntervalSeconds) * time.Second,
+		ReadTimeout:              time.Duration(envConfig.DBReadTimeoutMilliseconds) * time.Millisecond,
+		WriteTimeout:             time.Duration(envConfig.DBWriteTimeoutMilliseconds) * time.Millisecond,
+		ReadRetryAttempts:        envConfig.DBReadRetryAttempts,
+		ReadRetryDelay:           time.Duration(envConfig.DBReadRetryDelayMilliseconds) * time.Millisecond,
+		Migrations:               migrations.Postgres,
+	}, nil
+}
+
+// parseDBHost parses host:port or host:port/dbname, defaultDBName is used when the database is omitted.
+func parseDBHost(dbHost, defaultDBName string) (string, int, string, error) {
+	address, dbName, found := strings.Cut(dbHost, "/")
+	if !found {
+		dbName = defaultDBName
+	}
+
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", 0, "", fmt.Errorf("%q: %w", dbHost, err)
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return "", 0, "", fmt.Errorf("%q: %w", dbHost, err)
+	}
+
+	return host, port, dbName, nil
+}
+
+func newShardMap(ctx context.Context, version int, shardDBs []*db.DB) (*sharded.ShardMap, error) {
+	shards := make([]sharded.Shard, 0, len(shardDBs))
+
+	for shardIndex, shardDB := range shardDBs {
+		err := sqlxrepo.NewShardMapRepository(shardDB).Register(ctx, version, shardIndex, len(shardDBs))
+		if err != nil {
+			return nil, fmt.Errorf("cannot register shard %d: %w", shardIndex, err)
+		}
+
+		shards = append(shards, sqlxrepo.NewDialogRepository(shardDB))
+	}
+
+	shardMap, err := sharded.NewShardMap(version, shards)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create shard map: %w", err)
+	}
+
+	return shardMap, nil
+}
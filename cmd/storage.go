@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"myfacebook-dialog/internal/config"
+	"myfacebook-dialog/internal/db"
+	"myfacebook-dialog/internal/maintenance"
+	"myfacebook-dialog/internal/repository"
+	"myfacebook-dialog/internal/repository/archived"
+	"myfacebook-dialog/internal/repository/inbox"
+	sqliterepo "myfacebook-dialog/internal/repository/sqlite"
+	sqlxrepo "myfacebook-dialog/internal/repository/sqlx"
+)
+
+var (
+	errPostgresRequired                    = errors.New("the setting requires DB_DRIVER_NAME=postgres")
+	errInvalidPartitionMaintenanceInterval = errors.New("partition maintenance interval must be positive")
+)
+
+const (
+	storageTxAttempts   = 3
+	storageTxRetryDelay = 50 * time.Millisecond
+)
+
+// storage holds the repositories of the service on the backend selected by DB_DRIVER_NAME.
+type storage struct {
+	dialogRepository         repository.DialogRepository
+	dialogPinRepository      repository.DialogPinRepository
+	dialogSettingsRepository repository.DialogSettingsRepository
+	dialogStarRepository     repository.DialogStarRepository
+	dialogDraftRepository    repository.DialogDraftRepository
+	dialogInboxRepository    repository.DialogInboxRepository
+	// dbs are the databases the repositories run on, the app database first.
+	dbs   []*db.DB
+	close func()
+}
+
+func newStorage(ctx context.Context, envConfig *config.EnvConfig, appDB *db.DB) (*storage, error) {
+	if envConfig.DBDriverName == db.DriverSQLite {
+		return newSQLiteStorage(envConfig, appDB)
+	}
+
+	return newPostgresStorage(ctx, envConfig, appDB)
+}
+
+// newPostgresStorage shards dialogs and runs the background maintenance of dialog databases.
+func newPostgresStorage(ctx context.Context, envConfig *config.EnvConfig, appDB *db.DB) (*storage, error) {
+	if envConfig.DialogPartitionMaintenanceIntervalMinutes <= 0 {
+		return nil, fmt.Errorf("%w: %d", errInvalidPartitionMaintenanceInterval, envConfig.DialogPartitionMaintenanceIntervalMinutes)
+	}
+
+	dialogRepository, dialogDBs, disconnectShards, err := newDialogRepository(ctx, envConfig, appDB)
+	if err != nil {
+		return nil, err
+	}
+
+	dialogPartitionRepositories := make([]repository.DialogPartitionRepository, 0, len(dialogDBs))
+	for _, dialogDB := range dialogDBs {
+		dialogPartitionRepositories = append(dialogPartitionRepositories, sqlxrepo.NewDialogPartitionRepository(dialogDB))
+	}
+
+	dialogPartitionMaintainer := maintenance.NewDialogPartitionMaintainer(dialogPartitionRepositories, maintenance.DialogPartitionMaintainerConfig{
+		PremakeMonths:   envConfig.DialogPartitionPremakeMonths,
+		RetentionMonths: envConfig.DialogPartitionRetentionMonths,
+		DropExpired:     envConfig.DialogPartitionDropExpired,
+	})
+
+	// Partition DDL waits for locks held by running queries, it is not bound by the request timeouts.
+	go dialogPartitionMaintainer.Run(db.WithStatementTimeout(ctx, 0), time.Duration(envConfig.DialogPartitionMaintenanceIntervalMinutes)*time.Minute)
+
+	if err := startDialogPurger(ctx, envConfig, dialogDBs); err != nil {
+		disconnectShards()
+
+		return nil, err
+	}
+
+	if blobStore := newBlobStore(envConfig); blobStore != nil {
+		dialogRepository = archived.NewDialogRepository(dialogRepository, sqlxrepo.NewDialogArchiveRepository(appDB), blobStore)
+	}
+
+	dialogInboxRepository := sqlxrepo.NewDialogInboxRepository(appDB)
+
+	txManager := db.NewTxManager(appDB, db.TxConfig{MaxAttempts: storageTxAttempts, RetryDelay: storageTxRetryDelay})
+
+	return &storage{
+		dialogRepository:         inbox.NewDialogRepository(dialogRepository, dialogInboxRepository),
+		dialogPinRepository:      sqlxrepo.NewDialogPinRepository(appDB, txManager),
+		dialogSettingsRepository: sqlxrepo.NewDialogSettingsRepository(appDB),
+		dialogStarRepository:     sqlxrepo.NewDialogStarRepository(appDB),
+		dialogDraftRepository:    sqlxrepo.NewDialogDraftRepository(appDB),
+		dialogInboxRepository:    dialogInboxRepository,
+		dbs:                      storageDBs(appDB, dialogDBs),
+		close:                    disconnectShards,
+	}, nil
+}
+
+// newSQLiteStorage keeps everything in the app database file, features built on Postgres are refused rather than ignored.
+func newSQLiteStorage(envConfig *config.EnvConfig, appDB *db.DB) (*storage, error) {
+	if err := requirePostgres(envConfig, []postgresSetting{
+		{"DB_REPLICA_HOSTS", len(envConfig.DBReplicaHosts) > 0},
+		{"DB_SHARD_HOSTS", len(envConfig.DBShardHosts) > 0},
+		{"DB_NEXT_SHARD_HOSTS", len(envConfig.DBNextShardHosts) > 0},
+		{"DIALOG_PARTITION_RETENTION_MONTHS", envConfig.DialogPartitionRetentionMonths > 0},
+		{"DIALOG_RETENTION_DAYS", envConfig.DialogRetentionDays > 0},
+		{"DIALOG_RETENTION_DAYS_BY_MESSAGE_TYPE", len(envConfig.DialogRetentionDaysByMessageType) > 0},
+		{"DIALOG_ARCHIVE_LOCAL_PATH", envConfig.DialogArchiveLocalPath != ""},
+	}); err != nil {
+		return nil, err
+	}
+
+	dialogInboxRepository := sqliterepo.NewDialogInboxRepository(appDB)
+
+	return &storage{
+		dialogRepository:         inbox.NewDialogRepository(sqliterepo.NewDialogRepository(appDB), dialogInboxRepository),
+		dialogPinRepository:      sqliterepo.NewDialogPinRepository(appDB),
+		dialogSettingsRepository: sqliterepo.NewDialogSettingsRepository(appDB),
+		dialogStarRepository:     sqliterepo.NewDialogStarRepository(appDB),
+		dialogDraftRepository:    sqliterepo.NewDialogDraftRepository(appDB),
+		dialogInboxRepository:    dialogInboxRepository,
+		dbs:                      []*db.DB{appDB},
+		close:                    func() {},
+	}, nil
+}
+
+// storageDBs lists the app database and the dialog databases once, the app database is a shard when none are configured.
+func storageDBs(appDB *db.DB, dialogDBs []*db.DB) []*db.DB {
+	dbs := []*db.DB{appDB}
+
+	for _, dialogDB := range dialogDBs {
+		if dialogDB != appDB {
+			dbs = append(dbs, dialogDB)
+		}
+	}
+
+	return dbs
+}
+
+type postgresSetting struct {
+	name  string
+	inUse bool
+}
+
+// requirePostgres fails when any of the given settings is in use with another backend.
+func requirePostgres(envConfig *config.EnvConfig, settings []postgresSetting) error {
+	if envConfig.DBDriverName == db.DriverPostgres {
+		return nil
+	}
+
+	for _, setting := range settings {
+		if setting.inUse {
+			return fmt.Errorf("%w: %s", errPostgresRequired, setting.name)
+		}
+	}
+
+	return nil
+}
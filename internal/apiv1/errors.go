@@ -1,8 +1,11 @@
 package apiv1
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+
+	"myfacebook-dialog/internal/repository"
 )
 
 const (
@@ -11,6 +14,8 @@ const (
 	errorCodeEntityNotFound      = 102
 	errorCodeInvalidCredentials  = 103
 	errorCodeInvalidTokenCode    = 104
+	errorCodeServiceUnavailable  = 105
+	errorCodeEntityConflict      = 106
 
 	ErrorLogLevelInfo    = "info"
 	ErrorLogLevelWarning = "warning"
@@ -81,6 +86,42 @@ func NewServerError(err error) *Error {
 	}
 }
 
+// NewRepositoryError reports a failed repository call. Repository errors the client can act on keep their meaning:
+// a record violating storage constraints is an invalid request, and a storage outage asks the client to retry.
+// Other errors are server errors.
+func NewRepositoryError(err error) *Error {
+	switch {
+	case errors.Is(err, repository.ErrConstraintViolation):
+		return NewInvalidRequestError("request does not fit storage constraints", err)
+	case errors.Is(err, repository.ErrAlreadyExists):
+		return NewEntityConflictError(err)
+	case errors.Is(err, repository.ErrUnavailable), errors.Is(err, repository.ErrTimeout), errors.Is(err, repository.ErrConflict):
+		return NewServiceUnavailableError(err)
+	}
+
+	return NewServerError(err)
+}
+
+func NewServiceUnavailableError(err error) *Error {
+	return &Error{
+		statusCode: http.StatusServiceUnavailable,
+		message:    "service temporarily unavailable",
+		code:       errorCodeServiceUnavailable,
+		err:        err,
+		logLevel:   ErrorLogLevelWarning,
+	}
+}
+
+func NewEntityConflictError(err error) *Error {
+	return &Error{
+		statusCode: http.StatusConflict,
+		message:    "entity already exists",
+		code:       errorCodeEntityConflict,
+		err:        err,
+		logLevel:   ErrorLogLevelInfo,
+	}
+}
+
 func NewEntityNotFoundError(err error) *Error {
 	return &Error{
 		statusCode: http.StatusNotFound,
@@ -0,0 +1,19 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"myfacebook-dialog/internal/apiv1"
+	"myfacebook-dialog/internal/consistency"
+)
+
+// withConsistencyToken maps a malformed consistency token of the request to an invalid request error.
+func withConsistencyToken(ctx context.Context, request *http.Request) (context.Context, error) {
+	ctx, err := consistency.WithRequestToken(ctx, request)
+	if err != nil {
+		return nil, apiv1.NewInvalidRequestErrorInvalidParameter(consistency.TokenHeader, err)
+	}
+
+	return ctx, nil
+}
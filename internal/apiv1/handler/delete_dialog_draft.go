@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/inbugay1/httprouter"
+	"myfacebook-dialog/internal/apiv1"
+	"myfacebook-dialog/internal/repository"
+)
+
+type DeleteDialogDraft struct {
+	DialogDraftRepository repository.DialogDraftRepository
+}
+
+func (h *DeleteDialogDraft) Handle(responseWriter http.ResponseWriter, request *http.Request) error {
+	ctx := request.Context()
+
+	userID := ctx.Value("user_id").(string)
+	peerID := httprouter.RouteParam(ctx, "user_id")
+
+	// A draft saved on another device after the deletion was made must survive it.
+	updatedBefore := time.Now()
+
+	if request.URL.Query().Has("updated_at") {
+		var err error
+
+		updatedBefore, err = time.Parse(time.RFC3339Nano, request.URL.Query().Get("updated_at"))
+		if err != nil {
+			return apiv1.NewInvalidRequestErrorInvalidParameter("updated_at", err)
+		}
+	}
+
+	err := h.DialogDraftRepository.Delete(ctx, userID, peerID, updatedBefore)
+	if err != nil {
+		return apiv1.NewRepositoryError(fmt.Errorf("delete dialog draft handler, failed to delete dialog draft from repository: %w", err))
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json; utf-8")
+	responseWriter.WriteHeader(http.StatusOK)
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/inbugay1/httprouter"
+	"myfacebook-dialog/internal/apiv1"
+	"myfacebook-dialog/internal/repository"
+)
+
+type DeleteDialogSettings struct {
+	DialogSettingsRepository repository.DialogSettingsRepository
+}
+
+func (h *DeleteDialogSettings) Handle(responseWriter http.ResponseWriter, request *http.Request) error {
+	ctx := request.Context()
+
+	userID := ctx.Value("user_id").(string)
+	peerID := httprouter.RouteParam(ctx, "user_id")
+
+	err := h.DialogSettingsRepository.Delete(ctx, userID, peerID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return apiv1.NewRepositoryError(fmt.Errorf("delete dialog settings handler, failed to delete dialog settings from repository: %w", err))
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json; utf-8")
+	responseWriter.WriteHeader(http.StatusOK)
+
+	return nil
+}
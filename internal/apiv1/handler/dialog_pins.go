@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"myfacebook-dialog/internal/repository"
+)
+
+var errAmbiguousMessageID = errors.New("legacy message id matches messages of several dialogs")
+
+// messageIDRegexp accepts message ids as well as legacy integer ids.
+var messageIDRegexp = regexp.MustCompile(`^(?:[0-9]+|[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})$`)
+
+// validMessageID reports whether the id is a message id or a legacy id that fits the integer legacy_id column.
+func validMessageID(messageID string) bool {
+	if !messageIDRegexp.MatchString(messageID) {
+		return false
+	}
+
+	if strings.Contains(messageID, "-") {
+		return true
+	}
+
+	_, err := strconv.ParseInt(messageID, 10, 32)
+
+	return err == nil
+}
+
+// resolveDialogPins returns pinned messages of the dialog in pin order.
+// Pins whose messages no longer exist are removed, so they stop counting towards the limit.
+func resolveDialogPins(ctx context.Context, dialogRepository repository.DialogRepository,
+	dialogPinRepository repository.DialogPinRepository, userID, peerID string,
+) ([]repository.DialogMessage, error) {
+	dialogPins, err := dialogPinRepository.GetDialogPins(ctx, userID, peerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dialog pins: %w", err)
+	}
+
+	liveDialogPins, pinnedMessages, err := liveDialogPins(ctx, dialogRepository, userID, peerID, dialogPins)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, messageID := range repository.StaleDialogPinMessageIDs(dialogPins, liveDialogPins) {
+		err = dialogPinRepository.Delete(ctx, userID, peerID, messageID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("failed to delete stale dialog pin: %w", err)
+		}
+	}
+
+	return pinnedMessages, nil
+}
+
+// liveDialogPins returns the pins of the dialog whose messages exist, with their messages in pin order.
+func liveDialogPins(ctx context.Context, dialogRepository repository.DialogRepository, userID, peerID string,
+	dialogPins []repository.DialogPin,
+) ([]repository.DialogPin, []repository.DialogMessage, error) {
+	if len(dialogPins) == 0 {
+		return nil, nil, nil
+	}
+
+	messageIDs := make([]string, 0, len(dialogPins))
+	for _, dialogPin := range dialogPins {
+		messageIDs = append(messageIDs, dialogPin.MessageID)
+	}
+
+	// Pinned messages that are all gone are not found, their pins are all stale.
+	dialogMessages, err := dialogRepository.GetDialogMessagesByIDs(ctx, messageIDs)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, nil, fmt.Errorf("failed to fetch pinned dialog messages: %w", err)
+	}
+
+	dialogMessagesByID := indexDialogMessages(dialogMessages, func(dialogMsg repository.DialogMessage) bool {
+		return dialogMsg.IsBetween(userID, peerID)
+	})
+
+	livePins := make([]repository.DialogPin, 0, len(dialogPins))
+	pinnedMessages := make([]repository.DialogMessage, 0, len(dialogPins))
+	pinnedMessageIDs := make(map[string]struct{}, len(dialogPins))
+
+	for _, dialogPin := range dialogPins {
+		dialogMsg, ok := dialogMessagesByID[dialogPin.MessageID]
+
+		// A message pinned by its legacy id and by its id again is listed once, the second pin counts as stale.
+		if _, isPinned := pinnedMessageIDs[dialogMsg.ID]; ok && !isPinned {
+			pinnedMessageIDs[dialogMsg.ID] = struct{}{}
+			livePins = append(livePins, dialogPin)
+			pinnedMessages = append(pinnedMessages, dialogMsg)
+		}
+	}
+
+	return livePins, pinnedMessages, nil
+}
+
+// indexDialogMessages maps every id a message resolves by to the messages accepted by the filter.
+func indexDialogMessages(dialogMessages []repository.DialogMessage, filter func(repository.DialogMessage) bool) map[string]repository.DialogMessage {
+	dialogMessagesByID := make(map[string]repository.DialogMessage, len(dialogMessages))
+
+	for _, dialogMsg := range dialogMessages {
+		if !filter(dialogMsg) {
+			continue
+		}
+
+		for _, id := range dialogMsg.IDs() {
+			dialogMessagesByID[id] = dialogMsg
+		}
+	}
+
+	return dialogMessagesByID
+}
+
+// findDialogMessage returns the single message accepted by the filter, or repository.ErrNotFound. Legacy ids are unique on a shard only,
+// so a legacy id may match messages of several dialogs: it resolves when the filter leaves one of them,
+// and errAmbiguousMessageID is returned otherwise.
+func findDialogMessage(dialogMessages []repository.DialogMessage, filter func(repository.DialogMessage) bool) (*repository.DialogMessage, error) {
+	var found *repository.DialogMessage
+
+	for i := range dialogMessages {
+		if !filter(dialogMessages[i]) {
+			continue
+		}
+
+		if found != nil && found.ID != dialogMessages[i].ID {
+			return nil, errAmbiguousMessageID
+		}
+
+		found = &dialogMessages[i]
+	}
+
+	if found == nil {
+		return nil, repository.ErrNotFound
+	}
+
+	return found, nil
+}
+
+// deleteByMessageIDs retries a delete by the other ids of the message, since a pin or a star keeps the id it was made with.
+func deleteByMessageIDs(ctx context.Context, dialogRepository repository.DialogRepository, messageID string,
+	deleteByMessageID func(messageID string) error,
+) error {
+	err := deleteByMessageID(messageID)
+	if !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+
+	dialogMessages, lookupErr := dialogRepository.GetDialogMessagesByIDs(ctx, []string{messageID})
+	if errors.Is(lookupErr, repository.ErrNotFound) {
+		return err
+	}
+
+	if lookupErr != nil {
+		return fmt.Errorf("failed to fetch dialog message: %w", lookupErr)
+	}
+
+	for _, dialogMsg := range dialogMessages {
+		for _, id := range dialogMsg.IDs() {
+			if id == messageID {
+				continue
+			}
+
+			if err = deleteByMessageID(id); !errors.Is(err, repository.ErrNotFound) {
+				return err
+			}
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+
+	"github.com/inbugay1/httprouter"
+	"myfacebook-dialog/internal/apiv1"
+	"myfacebook-dialog/internal/repository"
+)
+
+const maxForwardTargets = 20
+
+var userIDRegexp = regexp.MustCompile(`(?i)^[a-f\d]{8}-[a-f\d]{4}-[a-f\d]{4}-[a-f\d]{4}-[a-f\d]{12}$`)
+
+type ForwardDialogMessage struct {
+	DialogRepository repository.DialogRepository
+	UserRepository   repository.UserRepository
+}
+
+type forwardDialogMessageRequest struct {
+	UserIDs []string `json:"user_ids"`
+}
+
+type forwardDialogMessageResponse struct {
+	ForwardedUserIDs []string `json:"forwarded_user_ids"`
+	FailedUserIDs    []string `json:"failed_user_ids"`
+}
+
+func (h *ForwardDialogMessage) Handle(responseWriter http.ResponseWriter, request *http.Request) error {
+	var forwardDialogMessageReq forwardDialogMessageRequest
+	if err := json.NewDecoder(request.Body).Decode(&forwardDialogMessageReq); err != nil {
+		return apiv1.NewInvalidRequestError("invalid request body", fmt.Errorf("forward dialog message handler, cannot decode request body: %w", err))
+	}
+
+	defer request.Body.Close()
+
+	err := h.validateForwardDialogMessageRequest(forwardDialogMessageReq)
+	if err != nil {
+		return err
+	}
+
+	ctx := request.Context()
+
+	userID := ctx.Value("user_id").(string)
+	messageID := httprouter.RouteParam(ctx, "message_id")
+	if !validMessageID(messageID) {
+		return apiv1.NewInvalidRequestErrorInvalidParameter("message_id", nil)
+	}
+
+	dialogMessages, err := h.DialogRepository.GetDialogMessagesByIDs(ctx, []string{messageID})
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return apiv1.NewRepositoryError(fmt.Errorf("forward dialog message handler, failed to fetch dialog message from repository: %w", err))
+	}
+
+	dialogMsg, err := findDialogMessage(dialogMessages, func(dialogMsg repository.DialogMessage) bool {
+		return dialogMsg.HasParticipant(userID)
+	})
+	if err != nil {
+		if errors.Is(err, errAmbiguousMessageID) {
+			return apiv1.NewInvalidRequestError("legacy message id is ambiguous, use the message id",
+				fmt.Errorf("forward dialog message handler, message %q: %w", messageID, err))
+		}
+
+		return apiv1.NewEntityNotFoundError(fmt.Errorf("forward dialog message handler, message %q not found: %w", messageID, err))
+	}
+
+	peerIDs := uniqueStrings(forwardDialogMessageReq.UserIDs)
+
+	// Blocking and rate limits are not enforced, the service has neither block lists nor rate limits, so a forward
+	// is checked like a send plus the existence of every target. Targets are checked before sending anything,
+	// so a bad target does not leave copies behind.
+	for _, peerID := range peerIDs {
+		_, err = h.UserRepository.GetUserByID(ctx, peerID)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return apiv1.NewInvalidRequestErrorInvalidParameter("user_ids", fmt.Errorf("user %q not found: %w", peerID, err))
+			}
+
+			return apiv1.NewRepositoryError(fmt.Errorf("forward dialog message handler, failed to get user by id: %w", err))
+		}
+	}
+
+	// Copies to different targets may land on different shards, so they are not written atomically.
+	// Every target is tried and the response tells which copies were written.
+	forwardDialogMessageResp := forwardDialogMessageResponse{
+		ForwardedUserIDs: make([]string, 0, len(peerIDs)),
+		FailedUserIDs:    make([]string, 0),
+	}
+
+	var errs []error
+
+	for _, peerID := range peerIDs {
+		_, err = h.DialogRepository.Add(ctx, dialogMsg.Forward(userID, peerID))
+		if err != nil {
+			forwardDialogMessageResp.FailedUserIDs = append(forwardDialogMessageResp.FailedUserIDs, peerID)
+			errs = append(errs, fmt.Errorf("failed to forward to user %q: %w", peerID, err))
+
+			continue
+		}
+
+		forwardDialogMessageResp.ForwardedUserIDs = append(forwardDialogMessageResp.ForwardedUserIDs, peerID)
+	}
+
+	if len(forwardDialogMessageResp.ForwardedUserIDs) == 0 {
+		return apiv1.NewRepositoryError(fmt.Errorf("forward dialog message handler, failed to add dialog messages to repository: %w", errors.Join(errs...)))
+	}
+
+	// 207 tells clients that look at the status only that some targets did not get the copy.
+	status := http.StatusOK
+
+	if len(errs) > 0 {
+		slog.Warn(fmt.Sprintf("forward dialog message handler, message %q was forwarded partially: %s", messageID, errors.Join(errs...)))
+
+		status = http.StatusMultiStatus
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json; utf-8")
+	responseWriter.WriteHeader(status)
+
+	err = json.NewEncoder(responseWriter).Encode(&forwardDialogMessageResp)
+	if err != nil {
+		return apiv1.NewServerError(fmt.Errorf("forward dialog message handler, cannot encode response: %w", err))
+	}
+
+	return nil
+}
+
+func (h *ForwardDialogMessage) validateForwardDialogMessageRequest(forwardDialogMessageReq forwardDialogMessageRequest) error {
+	if len(forwardDialogMessageReq.UserIDs) == 0 {
+		return apiv1.NewInvalidRequestErrorMissingRequiredParameter("user_ids")
+	}
+
+	if len(forwardDialogMessageReq.UserIDs) > maxForwardTargets {
+		return apiv1.NewInvalidRequestError(fmt.Sprintf("a message can be forwarded to no more than %d users at once", maxForwardTargets), nil)
+	}
+
+	for _, userID := range forwardDialogMessageReq.UserIDs {
+		if !userIDRegexp.MatchString(userID) {
+			return apiv1.NewInvalidRequestErrorInvalidParameter("user_ids", nil)
+		}
+	}
+
+	return nil
+}
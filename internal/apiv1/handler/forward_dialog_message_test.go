@@ -0,0 +1,234 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/inbugay1/httprouter"
+	"myfacebook-dialog/internal/apiclient"
+	"myfacebook-dialog/internal/apiv1/handler"
+	"myfacebook-dialog/internal/apiv1/middleware"
+	"myfacebook-dialog/internal/fakemonolith"
+	"myfacebook-dialog/internal/httpclient"
+	"myfacebook-dialog/internal/myfacebookapiclient"
+	"myfacebook-dialog/internal/repository"
+	"myfacebook-dialog/internal/repository/memory"
+	"myfacebook-dialog/internal/repository/rest"
+)
+
+const (
+	senderID    = "00000000-0000-4000-8000-000000000001"
+	senderToken = "sender-token"
+	peerID      = "00000000-0000-4000-8000-000000000002"
+	targetID    = "00000000-0000-4000-8000-000000000003"
+	unknownID   = "00000000-0000-4000-8000-000000000004"
+)
+
+const forwardRoutePattern = `/dialog/message/{message_id:[0-9]+|[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}}/forward`
+
+var errAddFailed = errors.New("add failed")
+
+// failingDialogRepository fails to store messages sent to the given receivers.
+type failingDialogRepository struct {
+	*memory.DialogRepository
+	failedReceiverIDs []string
+}
+
+func (r *failingDialogRepository) Add(ctx context.Context, dialogMessage repository.DialogMessage) (*repository.DialogMessage, error) {
+	for _, receiverID := range r.failedReceiverIDs {
+		if dialogMessage.To == receiverID {
+			return nil, fmt.Errorf("%w: receiver %s", errAddFailed, receiverID)
+		}
+	}
+
+	return r.DialogRepository.Add(ctx, dialogMessage)
+}
+
+func TestForwardDialogMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		// messageID picks the forwarded message from the message of the dialog of the sender
+		// and the message of a dialog of other users, the former is forwarded when it is nil.
+		messageID         func(own, other *repository.DialogMessage) string
+		userIDs           []string
+		fault             *fakemonolith.Fault
+		failedReceiverIDs []string
+		wantStatus        int
+		wantForwarded     []string
+		wantFailed        []string
+	}{
+		{
+			name:          "forwards once to repeated targets",
+			userIDs:       []string{targetID, targetID},
+			wantStatus:    http.StatusOK,
+			wantForwarded: []string{targetID},
+			wantFailed:    []string{},
+		},
+		{
+			name:              "reports targets that did not get the copy",
+			userIDs:           []string{peerID, targetID},
+			failedReceiverIDs: []string{targetID},
+			wantStatus:        http.StatusMultiStatus,
+			wantForwarded:     []string{peerID},
+			wantFailed:        []string{targetID},
+		},
+		{
+			name:              "fails when no target got the copy",
+			userIDs:           []string{targetID},
+			failedReceiverIDs: []string{targetID},
+			wantStatus:        http.StatusInternalServerError,
+		},
+		{
+			name:       "unknown target",
+			userIDs:    []string{targetID, unknownID},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "monolith fails to find a target",
+			userIDs:    []string{targetID},
+			fault:      &fakemonolith.Fault{StatusCode: http.StatusInternalServerError},
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name: "unknown message",
+			messageID: func(_, _ *repository.DialogMessage) string {
+				return unknownID
+			},
+			userIDs:    []string{targetID},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "legacy id out of range",
+			messageID: func(_, _ *repository.DialogMessage) string {
+				return "2147483648"
+			},
+			userIDs:    []string{targetID},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "message of another dialog",
+			messageID: func(_, other *repository.DialogMessage) string {
+				return other.ID
+			},
+			userIDs:    []string{targetID},
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			monolith := fakemonolith.New()
+			monolith.AddUser(senderID, senderToken)
+			monolith.AddUser(peerID)
+			monolith.AddUser(targetID)
+
+			if test.fault != nil {
+				monolith.SetKeyFault(targetID, *test.fault)
+			}
+
+			monolithServer := httptest.NewServer(monolith)
+			t.Cleanup(monolithServer.Close)
+
+			apiClient := apiclient.New(monolithServer.URL, httpclient.New(&httpclient.Config{}))
+			userRepository := rest.NewUserRepository(myfacebookapiclient.New(apiClient))
+
+			memoryDialogRepository := memory.NewDialogRepository()
+
+			own, err := memoryDialogRepository.Add(ctx, repository.DialogMessage{From: peerID, To: senderID, Text: "hello"})
+			if err != nil {
+				t.Fatalf("failed to add dialog message: %s", err)
+			}
+
+			other, err := memoryDialogRepository.Add(ctx, repository.DialogMessage{From: peerID, To: targetID, Text: "private"})
+			if err != nil {
+				t.Fatalf("failed to add dialog message: %s", err)
+			}
+
+			router := httprouter.New(httprouter.NewRegexRouteFactory())
+			router.Use(middleware.NewErrorResponse(), middleware.NewAuth(userRepository))
+			router.Post(forwardRoutePattern, &handler.ForwardDialogMessage{
+				DialogRepository: &failingDialogRepository{
+					DialogRepository:  memoryDialogRepository,
+					failedReceiverIDs: test.failedReceiverIDs,
+				},
+				UserRepository: userRepository,
+			}, "/dialog/message/{message_id}/forward")
+
+			messageID := own.ID
+			if test.messageID != nil {
+				messageID = test.messageID(own, other)
+			}
+
+			body, err := json.Marshal(map[string][]string{"user_ids": test.userIDs})
+			if err != nil {
+				t.Fatalf("failed to encode request: %s", err)
+			}
+
+			request := httptest.NewRequest(http.MethodPost, "/dialog/message/"+messageID+"/forward", strings.NewReader(string(body)))
+			request.Header.Set("Authorization", "Bearer "+senderToken)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			if recorder.Code != test.wantStatus {
+				t.Fatalf("got status %d, want %d: %s", recorder.Code, test.wantStatus, recorder.Body)
+			}
+
+			if test.wantForwarded != nil {
+				var response struct {
+					ForwardedUserIDs []string `json:"forwarded_user_ids"`
+					FailedUserIDs    []string `json:"failed_user_ids"`
+				}
+
+				if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+					t.Fatalf("failed to decode response: %s", err)
+				}
+
+				if fmt.Sprint(response.ForwardedUserIDs) != fmt.Sprint(test.wantForwarded) || fmt.Sprint(response.FailedUserIDs) != fmt.Sprint(test.wantFailed) {
+					t.Errorf("got forwarded %v and failed %v, want forwarded %v and failed %v",
+						response.ForwardedUserIDs, response.FailedUserIDs, test.wantForwarded, test.wantFailed)
+				}
+			}
+
+			// Only targets reported as forwarded have a copy, a failed forward leaves no copies behind.
+			for _, userID := range []string{peerID, targetID} {
+				copies, err := memoryDialogRepository.GetDialogMessagesBySenderIDAndReceiverID(ctx, senderID, userID)
+				if err != nil {
+					t.Fatalf("failed to fetch dialog messages: %s", err)
+				}
+
+				var forwardedCopies []repository.DialogMessage
+
+				for _, dialogMsg := range copies {
+					if dialogMsg.From == senderID && dialogMsg.ForwardedFromMessageID != nil {
+						forwardedCopies = append(forwardedCopies, dialogMsg)
+					}
+				}
+
+				wantCopies := 0
+				if slices.Contains(test.wantForwarded, userID) {
+					wantCopies = 1
+				}
+
+				if len(forwardedCopies) != wantCopies {
+					t.Fatalf("got %d copies in the dialog with %s, want %d", len(forwardedCopies), userID, wantCopies)
+				}
+
+				if wantCopies == 1 && (forwardedCopies[0].Text != own.Text || *forwardedCopies[0].ForwardedFromMessageID != own.ID) {
+					t.Errorf("got copy %+v, want a forward of %+v by the sender", forwardedCopies[0], *own)
+				}
+			}
+		})
+	}
+}
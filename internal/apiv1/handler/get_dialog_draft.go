@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/inbugay1/httprouter"
+	"myfacebook-dialog/internal/apiv1"
+	"myfacebook-dialog/internal/repository"
+)
+
+type GetDialogDraft struct {
+	DialogDraftRepository repository.DialogDraftRepository
+}
+
+type dialogDraft struct {
+	UserID    string    `json:"user_id"`
+	Text      string    `json:"text"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (h *GetDialogDraft) Handle(responseWriter http.ResponseWriter, request *http.Request) error {
+	ctx := request.Context()
+
+	userID := ctx.Value("user_id").(string)
+	peerID := httprouter.RouteParam(ctx, "user_id")
+
+	draft, err := h.DialogDraftRepository.GetDialogDraft(ctx, userID, peerID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return apiv1.NewEntityNotFoundError(fmt.Errorf("get dialog draft handler, draft not found: %w", err))
+		}
+
+		return apiv1.NewRepositoryError(fmt.Errorf("get dialog draft handler, failed to fetch dialog draft from repository: %w", err))
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json; utf-8")
+	responseWriter.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(responseWriter).Encode(newDialogDraft(*draft))
+	if err != nil {
+		return apiv1.NewServerError(fmt.Errorf("get dialog draft handler, cannot encode response: %w", err))
+	}
+
+	return nil
+}
+
+func newDialogDraft(draft repository.DialogDraft) dialogDraft {
+	return dialogDraft{
+		UserID:    draft.PeerID,
+		Text:      draft.Text,
+		UpdatedAt: draft.UpdatedAt,
+	}
+}
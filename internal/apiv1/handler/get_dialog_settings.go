@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/inbugay1/httprouter"
+	"myfacebook-dialog/internal/apiv1"
+	"myfacebook-dialog/internal/repository"
+)
+
+type GetDialogSettings struct {
+	DialogSettingsRepository repository.DialogSettingsRepository
+}
+
+type dialogSettings struct {
+	UserID             string     `json:"user_id"`
+	MutedUntil         *time.Time `json:"muted_until"`
+	NotificationsMuted bool       `json:"notifications_muted"`
+	Archived           bool       `json:"archived"`
+	Folders            []string   `json:"folders"`
+}
+
+func (h *GetDialogSettings) Handle(responseWriter http.ResponseWriter, request *http.Request) error {
+	ctx := request.Context()
+
+	userID := ctx.Value("user_id").(string)
+	peerID := httprouter.RouteParam(ctx, "user_id")
+
+	settings, err := h.DialogSettingsRepository.GetDialogSettings(ctx, userID, peerID)
+	if err != nil {
+		if !errors.Is(err, repository.ErrNotFound) {
+			return apiv1.NewRepositoryError(fmt.Errorf("get dialog settings handler, failed to fetch dialog settings from repository: %w", err))
+		}
+
+		settings = &repository.DialogSettings{
+			UserID: userID,
+			PeerID: peerID,
+		}
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json; utf-8")
+	responseWriter.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(responseWriter).Encode(newDialogSettings(*settings, time.Now()))
+	if err != nil {
+		return apiv1.NewServerError(fmt.Errorf("get dialog settings handler, cannot encode response: %w", err))
+	}
+
+	return nil
+}
+
+func newDialogSettings(settings repository.DialogSettings, now time.Time) dialogSettings {
+	folders := settings.Folders
+	if folders == nil {
+		folders = []string{}
+	}
+
+	return dialogSettings{
+		UserID:             settings.PeerID,
+		MutedUntil:         settings.MutedUntil,
+		NotificationsMuted: settings.IsMuted(now),
+		Archived:           settings.Archived,
+		Folders:            folders,
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/inbugay1/httprouter"
 	"myfacebook-dialog/internal/apiv1"
@@ -11,13 +14,25 @@ import (
 )
 
 type ListDialog struct {
-	DialogRepository repository.DialogRepository
+	DialogRepository    repository.DialogRepository
+	DialogPinRepository repository.DialogPinRepository
 }
 
 type dialogMessage struct {
-	From string `json:"from"`
-	To   string `json:"to"`
-	Text string `json:"text"`
+	ID            string         `json:"id"`
+	LegacyID      *string        `json:"legacy_id,omitempty"`
+	From          string         `json:"from"`
+	To            string         `json:"to"`
+	Text          string         `json:"text"`
+	Seq           int64          `json:"seq"`
+	CreatedAt     time.Time      `json:"created_at"`
+	IsPinned      bool           `json:"is_pinned"`
+	ForwardedFrom *forwardedFrom `json:"forwarded_from,omitempty"`
+}
+
+type forwardedFrom struct {
+	UserID    string `json:"user_id"`
+	MessageID string `json:"message_id"`
 }
 
 func (h *ListDialog) Handle(responseWriter http.ResponseWriter, request *http.Request) error {
@@ -26,19 +41,43 @@ func (h *ListDialog) Handle(responseWriter http.ResponseWriter, request *http.Re
 	senderID := ctx.Value("user_id").(string)
 	receiverID := httprouter.RouteParam(ctx, "user_id")
 
-	dialogMessages, err := h.DialogRepository.GetDialogMessagesBySenderIDAndReceiverID(ctx, senderID, receiverID)
+	afterSeq, limit, err := parseSeqPage(request.URL.Query())
+	if err != nil {
+		return err
+	}
+
+	ctx, err = withConsistencyToken(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	dialogMessages, err := h.DialogRepository.GetDialogMessagesAfterSeq(ctx, senderID, receiverID, afterSeq, limit)
 	if err != nil {
-		return apiv1.NewServerError(fmt.Errorf("list dialog handler, failed to fetch dialoag messages from repository: %w", err))
+		return apiv1.NewRepositoryError(fmt.Errorf("list dialog handler, failed to fetch dialoag messages from repository: %w", err))
+	}
+
+	dialogPins, err := h.DialogPinRepository.GetDialogPins(ctx, senderID, receiverID)
+	if err != nil {
+		return apiv1.NewRepositoryError(fmt.Errorf("list dialog handler, failed to fetch dialog pins from repository: %w", err))
+	}
+
+	pinnedMessageIDs := make(map[string]struct{}, len(dialogPins))
+	for _, dialogPin := range dialogPins {
+		pinnedMessageIDs[dialogPin.MessageID] = struct{}{}
 	}
 
 	listDialogResponse := make([]dialogMessage, 0, len(dialogMessages))
 
 	for _, dialogMsg := range dialogMessages {
-		listDialogResponse = append(listDialogResponse, dialogMessage{
-			From: dialogMsg.From,
-			To:   dialogMsg.To,
-			Text: dialogMsg.Text,
-		})
+		isPinned := false
+
+		for _, id := range dialogMsg.IDs() {
+			if _, ok := pinnedMessageIDs[id]; ok {
+				isPinned = true
+			}
+		}
+
+		listDialogResponse = append(listDialogResponse, newDialogMessage(dialogMsg, isPinned))
 	}
 
 	responseWriter.Header().Set("Content-Type", "application/json; utf-8")
@@ -51,3 +90,52 @@ func (h *ListDialog) Handle(responseWriter http.ResponseWriter, request *http.Re
 
 	return nil
 }
+
+func newDialogMessage(dialogMsg repository.DialogMessage, isPinned bool) dialogMessage {
+	message := dialogMessage{
+		ID:        dialogMsg.ID,
+		LegacyID:  dialogMsg.LegacyID,
+		From:      dialogMsg.From,
+		To:        dialogMsg.To,
+		Text:      dialogMsg.Text,
+		Seq:       dialogMsg.Seq,
+		CreatedAt: dialogMsg.CreatedAt.UTC(),
+		IsPinned:  isPinned,
+	}
+
+	if dialogMsg.ForwardedFromUserID != nil && dialogMsg.ForwardedFromMessageID != nil {
+		message.ForwardedFrom = &forwardedFrom{
+			UserID:    *dialogMsg.ForwardedFromUserID,
+			MessageID: *dialogMsg.ForwardedFromMessageID,
+		}
+	}
+
+	return message
+}
+
+// parseSeqPage reads the page of a dialog following after_seq, the whole rest of the dialog is returned without a limit.
+func parseSeqPage(query url.Values) (int64, int, error) {
+	var afterSeq int64
+
+	if query.Has("after_seq") {
+		value, err := strconv.ParseInt(query.Get("after_seq"), 10, 64)
+		if err != nil || value < 0 {
+			return 0, 0, apiv1.NewInvalidRequestErrorInvalidParameter("after_seq", err)
+		}
+
+		afterSeq = value
+	}
+
+	limit := 0
+
+	if query.Has("limit") {
+		value, err := strconv.Atoi(query.Get("limit"))
+		if err != nil || value < 1 || value > maxPageLimit {
+			return 0, 0, apiv1.NewInvalidRequestErrorInvalidParameter("limit", err)
+		}
+
+		limit = value
+	}
+
+	return afterSeq, limit, nil
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"myfacebook-dialog/internal/apiv1"
+	"myfacebook-dialog/internal/repository"
+)
+
+type ListDialogDrafts struct {
+	DialogDraftRepository repository.DialogDraftRepository
+}
+
+func (h *ListDialogDrafts) Handle(responseWriter http.ResponseWriter, request *http.Request) error {
+	ctx := request.Context()
+
+	userID := ctx.Value("user_id").(string)
+
+	drafts, err := h.DialogDraftRepository.GetDialogDraftsByUserID(ctx, userID)
+	if err != nil {
+		return apiv1.NewRepositoryError(fmt.Errorf("list dialog drafts handler, failed to fetch dialog drafts from repository: %w", err))
+	}
+
+	listDialogDraftsResponse := make([]dialogDraft, 0, len(drafts))
+	for _, draft := range drafts {
+		listDialogDraftsResponse = append(listDialogDraftsResponse, newDialogDraft(draft))
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json; utf-8")
+	responseWriter.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(responseWriter).Encode(&listDialogDraftsResponse)
+	if err != nil {
+		return apiv1.NewServerError(fmt.Errorf("list dialog drafts handler, cannot encode response: %w", err))
+	}
+
+	return nil
+}
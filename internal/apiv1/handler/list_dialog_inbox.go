@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"myfacebook-dialog/internal/apiv1"
+	"myfacebook-dialog/internal/repository"
+)
+
+type ListDialogInbox struct {
+	DialogInboxRepository repository.DialogInboxRepository
+}
+
+// dialogInboxEntry carries the settings of the user, notifications_muted flags muted dialogs whose messages still count as unread.
+// Draft is null when the user has no draft in the dialog.
+type dialogInboxEntry struct {
+	dialogSettings
+	LastMessage dialogMessage `json:"last_message"`
+	UnreadCount int           `json:"unread_count"`
+	Draft       *dialogDraft  `json:"draft"`
+}
+
+type listDialogInboxResponse struct {
+	// UnreadCount sums all dialogs of the user regardless of the filters, muted and archived dialogs included.
+	UnreadCount int                `json:"unread_count"`
+	Dialogs     []dialogInboxEntry `json:"dialogs"`
+	NextCursor  string             `json:"next_cursor,omitempty"`
+}
+
+func (h *ListDialogInbox) Handle(responseWriter http.ResponseWriter, request *http.Request) error {
+	ctx := request.Context()
+
+	userID := ctx.Value("user_id").(string)
+
+	filter, err := h.getDialogInboxFilter(request.URL.Query())
+	if err != nil {
+		return err
+	}
+
+	after, limit, err := parseDialogInboxPage(request.URL.Query())
+	if err != nil {
+		return err
+	}
+
+	dialogInbox, err := h.DialogInboxRepository.GetDialogInbox(ctx, userID, filter, after, limit)
+	if err != nil {
+		return apiv1.NewRepositoryError(fmt.Errorf("list dialog inbox handler, failed to fetch dialog inbox from repository: %w", err))
+	}
+
+	unreadCount, err := h.DialogInboxRepository.GetUnreadCount(ctx, userID)
+	if err != nil {
+		return apiv1.NewRepositoryError(fmt.Errorf("list dialog inbox handler, failed to fetch unread count from repository: %w", err))
+	}
+
+	now := time.Now()
+
+	listDialogInboxResp := listDialogInboxResponse{
+		UnreadCount: unreadCount,
+		Dialogs:     make([]dialogInboxEntry, 0, len(dialogInbox)),
+	}
+
+	for _, entry := range dialogInbox {
+		inboxEntry := dialogInboxEntry{
+			dialogSettings: newDialogSettings(entry.Settings, now),
+			LastMessage:    newDialogMessage(entry.LastMessage, false),
+			UnreadCount:    entry.UnreadCount,
+		}
+
+		if entry.Draft != nil {
+			draft := newDialogDraft(*entry.Draft)
+			inboxEntry.Draft = &draft
+		}
+
+		listDialogInboxResp.Dialogs = append(listDialogInboxResp.Dialogs, inboxEntry)
+	}
+
+	if len(dialogInbox) == limit {
+		last := dialogInbox[len(dialogInbox)-1]
+		listDialogInboxResp.NextCursor = encodePageCursor(last.LastMessage.CreatedAt, last.PeerID)
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json; utf-8")
+	responseWriter.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(responseWriter).Encode(&listDialogInboxResp)
+	if err != nil {
+		return apiv1.NewServerError(fmt.Errorf("list dialog inbox handler, cannot encode response: %w", err))
+	}
+
+	return nil
+}
+
+func (h *ListDialogInbox) getDialogInboxFilter(query url.Values) (repository.DialogInboxFilter, error) {
+	archived, err := parseOptionalBool(query, "archived")
+	if err != nil {
+		return repository.DialogInboxFilter{}, err
+	}
+
+	muted, err := parseOptionalBool(query, "muted")
+	if err != nil {
+		return repository.DialogInboxFilter{}, err
+	}
+
+	return repository.DialogInboxFilter{
+		Archived: archived,
+		Muted:    muted,
+		Folder:   query.Get("folder"),
+	}, nil
+}
+
+// parseDialogInboxPage reads the page of the inbox following cursor, the first page is returned without it.
+func parseDialogInboxPage(query url.Values) (*repository.DialogInboxCursor, int, error) {
+	limit, err := parsePageLimit(query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if !query.Has("cursor") {
+		return nil, limit, nil
+	}
+
+	lastMessageAt, peerID, err := decodePageCursor(query.Get("cursor"))
+	if err != nil {
+		return nil, 0, apiv1.NewInvalidRequestErrorInvalidParameter("cursor", err)
+	}
+
+	return &repository.DialogInboxCursor{LastMessageAt: lastMessageAt, PeerID: peerID}, limit, nil
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/inbugay1/httprouter"
+	"myfacebook-dialog/internal/apiv1"
+	"myfacebook-dialog/internal/repository"
+)
+
+type ListDialogPins struct {
+	DialogRepository    repository.DialogRepository
+	DialogPinRepository repository.DialogPinRepository
+}
+
+func (h *ListDialogPins) Handle(responseWriter http.ResponseWriter, request *http.Request) error {
+	ctx := request.Context()
+
+	userID := ctx.Value("user_id").(string)
+	peerID := httprouter.RouteParam(ctx, "user_id")
+
+	pinnedMessages, err := resolveDialogPins(ctx, h.DialogRepository, h.DialogPinRepository, userID, peerID)
+	if err != nil {
+		return apiv1.NewRepositoryError(fmt.Errorf("list dialog pins handler: %w", err))
+	}
+
+	listDialogPinsResponse := make([]dialogMessage, 0, len(pinnedMessages))
+
+	for _, dialogMsg := range pinnedMessages {
+		listDialogPinsResponse = append(listDialogPinsResponse, newDialogMessage(dialogMsg, true))
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json; utf-8")
+	responseWriter.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(responseWriter).Encode(&listDialogPinsResponse)
+	if err != nil {
+		return apiv1.NewServerError(fmt.Errorf("list dialog pins handler, cannot encode response: %w", err))
+	}
+
+	return nil
+}
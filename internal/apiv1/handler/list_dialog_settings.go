@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"myfacebook-dialog/internal/apiv1"
+	"myfacebook-dialog/internal/repository"
+)
+
+type ListDialogSettings struct {
+	DialogSettingsRepository repository.DialogSettingsRepository
+}
+
+func (h *ListDialogSettings) Handle(responseWriter http.ResponseWriter, request *http.Request) error {
+	ctx := request.Context()
+
+	userID := ctx.Value("user_id").(string)
+
+	filter, err := h.getDialogSettingsFilter(request)
+	if err != nil {
+		return err
+	}
+
+	settings, err := h.DialogSettingsRepository.GetDialogSettingsByUserID(ctx, userID, filter)
+	if err != nil {
+		return apiv1.NewRepositoryError(fmt.Errorf("list dialog settings handler, failed to fetch dialog settings from repository: %w", err))
+	}
+
+	now := time.Now()
+
+	listDialogSettingsResponse := make([]dialogSettings, 0, len(settings))
+	for _, dialogSettings := range settings {
+		listDialogSettingsResponse = append(listDialogSettingsResponse, newDialogSettings(dialogSettings, now))
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json; utf-8")
+	responseWriter.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(responseWriter).Encode(&listDialogSettingsResponse)
+	if err != nil {
+		return apiv1.NewServerError(fmt.Errorf("list dialog settings handler, cannot encode response: %w", err))
+	}
+
+	return nil
+}
+
+func (h *ListDialogSettings) getDialogSettingsFilter(request *http.Request) (repository.DialogSettingsFilter, error) {
+	query := request.URL.Query()
+
+	archived, err := parseOptionalBool(query, "archived")
+	if err != nil {
+		return repository.DialogSettingsFilter{}, err
+	}
+
+	muted, err := parseOptionalBool(query, "muted")
+	if err != nil {
+		return repository.DialogSettingsFilter{}, err
+	}
+
+	return repository.DialogSettingsFilter{
+		Archived: archived,
+		Muted:    muted,
+		Folder:   query.Get("folder"),
+	}, nil
+}
+
+func parseOptionalBool(query url.Values, param string) (*bool, error) {
+	if !query.Has(param) {
+		return nil, nil //nolint:nilnil
+	}
+
+	value, err := strconv.ParseBool(query.Get(param))
+	if err != nil {
+		return nil, apiv1.NewInvalidRequestErrorInvalidParameter(param, err)
+	}
+
+	return &value, nil
+}
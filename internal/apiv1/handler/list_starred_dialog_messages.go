@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"myfacebook-dialog/internal/apiv1"
+	"myfacebook-dialog/internal/repository"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+type ListStarredDialogMessages struct {
+	DialogRepository     repository.DialogRepository
+	DialogStarRepository repository.DialogStarRepository
+}
+
+type starredDialogMessage struct {
+	dialogMessage
+	StarredAt time.Time `json:"starred_at"`
+}
+
+type listStarredDialogMessagesResponse struct {
+	Messages   []starredDialogMessage `json:"messages"`
+	NextCursor string                 `json:"next_cursor,omitempty"`
+}
+
+// Handle pages through the stars of the user. Stars of messages deleted since are dropped from the page and deleted,
+// more stars are read until the page is full, so a short page is the last one.
+func (h *ListStarredDialogMessages) Handle(responseWriter http.ResponseWriter, request *http.Request) error {
+	ctx := request.Context()
+
+	userID := ctx.Value("user_id").(string)
+
+	after, limit, err := parseStarredPage(request.URL.Query())
+	if err != nil {
+		return err
+	}
+
+	listStarredDialogMessagesResp := listStarredDialogMessagesResponse{
+		Messages: make([]starredDialogMessage, 0, limit),
+	}
+
+	for len(listStarredDialogMessagesResp.Messages) < limit {
+		batchLimit := limit - len(listStarredDialogMessagesResp.Messages)
+
+		dialogStars, err := h.DialogStarRepository.GetDialogStarsByUserID(ctx, userID, after, batchLimit)
+		if err != nil {
+			return apiv1.NewRepositoryError(fmt.Errorf("list starred dialog messages handler, failed to fetch dialog stars from repository: %w", err))
+		}
+
+		if len(dialogStars) == 0 {
+			break
+		}
+
+		starredDialogMessages, err := h.liveStarredDialogMessages(ctx, userID, dialogStars)
+		if err != nil {
+			return err
+		}
+
+		listStarredDialogMessagesResp.Messages = append(listStarredDialogMessagesResp.Messages, starredDialogMessages...)
+
+		lastDialogStar := dialogStars[len(dialogStars)-1]
+		after = &repository.DialogStarCursor{CreatedAt: lastDialogStar.CreatedAt, MessageID: lastDialogStar.MessageID}
+
+		if len(dialogStars) < batchLimit {
+			after = nil
+
+			break
+		}
+	}
+
+	if after != nil && len(listStarredDialogMessagesResp.Messages) == limit {
+		listStarredDialogMessagesResp.NextCursor = encodePageCursor(after.CreatedAt, after.MessageID)
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json; utf-8")
+	responseWriter.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(responseWriter).Encode(&listStarredDialogMessagesResp)
+	if err != nil {
+		return apiv1.NewServerError(fmt.Errorf("list starred dialog messages handler, cannot encode response: %w", err))
+	}
+
+	return nil
+}
+
+// liveStarredDialogMessages resolves the starred messages still visible to the user and deletes the other stars.
+func (h *ListStarredDialogMessages) liveStarredDialogMessages(ctx context.Context, userID string,
+	dialogStars []repository.DialogStar,
+) ([]starredDialogMessage, error) {
+	messageIDs := make([]string, 0, len(dialogStars))
+	for _, dialogStar := range dialogStars {
+		messageIDs = append(messageIDs, dialogStar.MessageID)
+	}
+
+	dialogMessages, err := h.DialogRepository.GetDialogMessagesByIDs(ctx, messageIDs)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, apiv1.NewRepositoryError(fmt.Errorf("list starred dialog messages handler, failed to fetch dialog messages from repository: %w", err))
+	}
+
+	dialogMessagesByID := indexDialogMessages(dialogMessages, func(dialogMsg repository.DialogMessage) bool {
+		return dialogMsg.HasParticipant(userID)
+	})
+
+	starredDialogMessages := make([]starredDialogMessage, 0, len(dialogStars))
+
+	var staleMessageIDs []string
+
+	for _, dialogStar := range dialogStars {
+		dialogMsg, ok := dialogMessagesByID[dialogStar.MessageID]
+		if !ok {
+			staleMessageIDs = append(staleMessageIDs, dialogStar.MessageID)
+
+			continue
+		}
+
+		starredDialogMessages = append(starredDialogMessages, starredDialogMessage{
+			dialogMessage: newDialogMessage(dialogMsg, false),
+			StarredAt:     dialogStar.CreatedAt,
+		})
+	}
+
+	// The page is complete without them, stale stars left behind are deleted by the next listing.
+	if err := h.DialogStarRepository.DeleteByMessageIDs(ctx, userID, staleMessageIDs); err != nil {
+		slog.Warn(fmt.Sprintf("list starred dialog messages handler, failed to delete stale dialog stars: %s", err))
+	}
+
+	return starredDialogMessages, nil
+}
+
+// parseStarredPage reads the page of starred messages following cursor, the first page is returned without it.
+func parseStarredPage(query url.Values) (*repository.DialogStarCursor, int, error) {
+	limit, err := parsePageLimit(query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if !query.Has("cursor") {
+		return nil, limit, nil
+	}
+
+	createdAt, messageID, err := decodePageCursor(query.Get("cursor"))
+	if err != nil {
+		return nil, 0, apiv1.NewInvalidRequestErrorInvalidParameter("cursor", err)
+	}
+
+	return &repository.DialogStarCursor{CreatedAt: createdAt, MessageID: messageID}, limit, nil
+}
+
+func parsePageLimit(query url.Values) (int, error) {
+	if !query.Has("limit") {
+		return defaultPageLimit, nil
+	}
+
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		return 0, apiv1.NewInvalidRequestErrorInvalidParameter("limit", err)
+	}
+
+	return limit, nil
+}
@@ -0,0 +1,115 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"myfacebook-dialog/internal/apiv1/handler"
+	"myfacebook-dialog/internal/db/dbtest"
+	"myfacebook-dialog/internal/repository"
+	"myfacebook-dialog/internal/repository/memory"
+	"myfacebook-dialog/internal/repository/sqlite"
+)
+
+func TestListStarredDialogMessagesSkipsDeletedMessages(t *testing.T) {
+	ctx := context.Background()
+
+	dialogRepository := memory.NewDialogRepository()
+	dialogStarRepository := sqlite.NewDialogStarRepository(dbtest.SQLite(t))
+
+	var wantMessageIDs []string
+
+	// Stars of unknown ids stand for messages deleted after they were starred, every other star is stale.
+	deletedMessageIDs := []string{
+		"00000000-0000-4000-8000-0000000000d1",
+		"00000000-0000-4000-8000-0000000000d2",
+		"00000000-0000-4000-8000-0000000000d3",
+	}
+
+	for _, deletedMessageID := range deletedMessageIDs {
+		message, err := dialogRepository.Add(ctx, repository.DialogMessage{From: peerID, To: senderID, Text: "hello"})
+		if err != nil {
+			t.Fatalf("failed to add dialog message: %s", err)
+		}
+
+		for _, messageID := range []string{message.ID, deletedMessageID} {
+			if err := dialogStarRepository.Add(ctx, senderID, messageID); err != nil {
+				t.Fatalf("failed to add dialog star: %s", err)
+			}
+		}
+
+		wantMessageIDs = append([]string{message.ID}, wantMessageIDs...)
+	}
+
+	listStarredDialogMessages := &handler.ListStarredDialogMessages{
+		DialogRepository:     dialogRepository,
+		DialogStarRepository: dialogStarRepository,
+	}
+
+	var (
+		listedMessageIDs []string
+		pages            int
+	)
+
+	query := url.Values{"limit": {"2"}}
+
+	for {
+		request := httptest.NewRequest(http.MethodGet, "/dialog/starred?"+query.Encode(), nil)
+		request = request.WithContext(context.WithValue(request.Context(), "user_id", senderID)) //nolint:revive,staticcheck
+
+		recorder := httptest.NewRecorder()
+		if err := listStarredDialogMessages.Handle(recorder, request); err != nil {
+			t.Fatalf("failed to list starred dialog messages: %s", err)
+		}
+
+		var response struct {
+			Messages []struct {
+				ID string `json:"id"`
+			} `json:"messages"`
+			NextCursor string `json:"next_cursor"`
+		}
+
+		if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %s", err)
+		}
+
+		pages++
+
+		if response.NextCursor != "" && len(response.Messages) != 2 {
+			t.Errorf("page %d has %d messages and a next page, want full pages", pages, len(response.Messages))
+		}
+
+		for _, message := range response.Messages {
+			listedMessageIDs = append(listedMessageIDs, message.ID)
+		}
+
+		if response.NextCursor == "" {
+			break
+		}
+
+		query.Set("cursor", response.NextCursor)
+	}
+
+	if pages != 2 || len(listedMessageIDs) != len(wantMessageIDs) {
+		t.Fatalf("got messages %v in %d pages, want %v in 2 pages", listedMessageIDs, pages, wantMessageIDs)
+	}
+
+	for i := range wantMessageIDs {
+		if listedMessageIDs[i] != wantMessageIDs[i] {
+			t.Fatalf("got messages %v, want %v newest first", listedMessageIDs, wantMessageIDs)
+		}
+	}
+
+	dialogStars, err := dialogStarRepository.GetDialogStarsByUserID(ctx, senderID, nil, 10)
+	if err != nil {
+		t.Fatalf("failed to fetch dialog stars: %s", err)
+	}
+
+	if len(dialogStars) != len(wantMessageIDs) {
+		t.Errorf("got %d stars left, want the %d stars of stored messages", len(dialogStars), len(wantMessageIDs))
+	}
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var errInvalidPageCursor = errors.New("invalid page cursor")
+
+// encodePageCursor points to the last item of a keyset page by its time and a key that breaks ties.
+// The cursor is opaque to clients, they pass next_cursor back as is.
+func encodePageCursor(at time.Time, key string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(at.UTC().Format(time.RFC3339Nano) + " " + key))
+}
+
+func decodePageCursor(value string) (time.Time, string, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("%w: %w", errInvalidPageCursor, err)
+	}
+
+	encodedAt, key, ok := strings.Cut(string(decoded), " ")
+	if !ok || key == "" {
+		return time.Time{}, "", errInvalidPageCursor
+	}
+
+	at, err := time.Parse(time.RFC3339Nano, encodedAt)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("%w: %w", errInvalidPageCursor, err)
+	}
+
+	return at, key, nil
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/inbugay1/httprouter"
+	"myfacebook-dialog/internal/apiv1"
+	"myfacebook-dialog/internal/repository"
+)
+
+type PinDialogMessage struct {
+	DialogRepository    repository.DialogRepository
+	DialogPinRepository repository.DialogPinRepository
+	MaxPinnedMessages   int
+}
+
+type pinDialogMessageRequest struct {
+	MessageID string `json:"message_id"`
+}
+
+func (h *PinDialogMessage) Handle(responseWriter http.ResponseWriter, request *http.Request) error {
+	var pinDialogMessageReq pinDialogMessageRequest
+	if err := json.NewDecoder(request.Body).Decode(&pinDialogMessageReq); err != nil {
+		return apiv1.NewInvalidRequestError("invalid request body", fmt.Errorf("pin dialog message handler, cannot decode request body: %w", err))
+	}
+
+	defer request.Body.Close()
+
+	if pinDialogMessageReq.MessageID == "" {
+		return apiv1.NewInvalidRequestErrorMissingRequiredParameter("message_id")
+	}
+
+	if !validMessageID(pinDialogMessageReq.MessageID) {
+		return apiv1.NewInvalidRequestErrorInvalidParameter("message_id", nil)
+	}
+
+	ctx := request.Context()
+
+	userID := ctx.Value("user_id").(string)
+	peerID := httprouter.RouteParam(ctx, "user_id")
+
+	dialogMessages, err := h.DialogRepository.GetDialogMessagesByIDs(ctx, []string{pinDialogMessageReq.MessageID})
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return apiv1.NewRepositoryError(fmt.Errorf("pin dialog message handler, failed to fetch dialog message from repository: %w", err))
+	}
+
+	// Only messages of the dialog are looked at, so a legacy id matching messages of other dialogs resolves.
+	dialogMsg, err := findDialogMessage(dialogMessages, func(dialogMsg repository.DialogMessage) bool {
+		return dialogMsg.IsBetween(userID, peerID)
+	})
+	if err != nil {
+		if errors.Is(err, errAmbiguousMessageID) {
+			return apiv1.NewInvalidRequestError("legacy message id is ambiguous, use the message id",
+				fmt.Errorf("pin dialog message handler, message %q: %w", pinDialogMessageReq.MessageID, err))
+		}
+
+		return apiv1.NewEntityNotFoundError(fmt.Errorf("pin dialog message handler, message %q not found in dialog: %w",
+			pinDialogMessageReq.MessageID, err))
+	}
+
+	dialogPin := repository.DialogPin{
+		MessageID: dialogMsg.ID,
+		PinnedBy:  userID,
+	}
+
+	// Only live pins count towards the limit, pins of deleted messages are dropped by the repository.
+	livePins := func(ctx context.Context, dialogPins []repository.DialogPin) ([]repository.DialogPin, error) {
+		liveDialogPins, _, err := liveDialogPins(ctx, h.DialogRepository, userID, peerID, dialogPins)
+
+		return liveDialogPins, err
+	}
+
+	err = h.DialogPinRepository.Add(ctx, userID, peerID, dialogPin, h.MaxPinnedMessages, livePins)
+	if err != nil {
+		if errors.Is(err, repository.ErrPinLimitReached) {
+			return apiv1.NewInvalidRequestError(fmt.Sprintf("no more than %d messages can be pinned", h.MaxPinnedMessages), err)
+		}
+
+		return apiv1.NewRepositoryError(fmt.Errorf("pin dialog message handler, failed to add dialog pin to repository: %w", err))
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json; utf-8")
+	responseWriter.WriteHeader(http.StatusOK)
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/inbugay1/httprouter"
+	"myfacebook-dialog/internal/apiv1"
+	"myfacebook-dialog/internal/repository"
+)
+
+type ReadDialog struct {
+	DialogInboxRepository repository.DialogInboxRepository
+}
+
+// Handle marks the dialog read, a dialog without messages has nothing to read.
+func (h *ReadDialog) Handle(responseWriter http.ResponseWriter, request *http.Request) error {
+	ctx := request.Context()
+
+	userID := ctx.Value("user_id").(string)
+	peerID := httprouter.RouteParam(ctx, "user_id")
+
+	err := h.DialogInboxRepository.MarkRead(ctx, userID, peerID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return apiv1.NewRepositoryError(fmt.Errorf("read dialog handler, failed to mark dialog read in repository: %w", err))
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json; utf-8")
+	responseWriter.WriteHeader(http.StatusOK)
+
+	return nil
+}
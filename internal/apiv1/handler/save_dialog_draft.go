@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/inbugay1/httprouter"
+	"myfacebook-dialog/internal/apiv1"
+	"myfacebook-dialog/internal/repository"
+)
+
+const maxDialogMessageTextLength = 1000
+
+type SaveDialogDraft struct {
+	DialogDraftRepository repository.DialogDraftRepository
+}
+
+type saveDialogDraftRequest struct {
+	Text      string     `json:"text"`
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+func (h *SaveDialogDraft) Handle(responseWriter http.ResponseWriter, request *http.Request) error {
+	var saveDialogDraftReq saveDialogDraftRequest
+	if err := json.NewDecoder(request.Body).Decode(&saveDialogDraftReq); err != nil {
+		return apiv1.NewInvalidRequestError("invalid request body", fmt.Errorf("save dialog draft handler, cannot decode request body: %w", err))
+	}
+
+	defer request.Body.Close()
+
+	if saveDialogDraftReq.Text == "" {
+		return apiv1.NewInvalidRequestErrorMissingRequiredParameter("text")
+	}
+
+	if utf8.RuneCountInString(saveDialogDraftReq.Text) > maxDialogMessageTextLength {
+		return apiv1.NewInvalidRequestErrorInvalidParameter("text", nil)
+	}
+
+	ctx := request.Context()
+
+	// Devices send the time the draft was edited, the server time is only a fallback for clients that do not.
+	updatedAt := time.Now()
+	if saveDialogDraftReq.UpdatedAt != nil {
+		updatedAt = *saveDialogDraftReq.UpdatedAt
+	}
+
+	draft, err := h.DialogDraftRepository.Save(ctx, repository.DialogDraft{
+		UserID:    ctx.Value("user_id").(string),
+		PeerID:    httprouter.RouteParam(ctx, "user_id"),
+		Text:      saveDialogDraftReq.Text,
+		UpdatedAt: updatedAt,
+	})
+	if err != nil {
+		return apiv1.NewRepositoryError(fmt.Errorf("save dialog draft handler, failed to save dialog draft to repository: %w", err))
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json; utf-8")
+	responseWriter.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(responseWriter).Encode(newDialogDraft(*draft))
+	if err != nil {
+		return apiv1.NewServerError(fmt.Errorf("save dialog draft handler, cannot encode response: %w", err))
+	}
+
+	return nil
+}
@@ -3,15 +3,20 @@ package handler
 import (
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"time"
+	"unicode/utf8"
 
 	"github.com/inbugay1/httprouter"
 	"myfacebook-dialog/internal/apiv1"
+	"myfacebook-dialog/internal/consistency"
 	"myfacebook-dialog/internal/repository"
 )
 
 type SendDialog struct {
-	DialogRepository repository.DialogRepository
+	DialogRepository      repository.DialogRepository
+	DialogDraftRepository repository.DialogDraftRepository
 }
 
 type sendDialogRequest struct {
@@ -30,6 +35,10 @@ func (h *SendDialog) Handle(responseWriter http.ResponseWriter, request *http.Re
 		return apiv1.NewInvalidRequestErrorMissingRequiredParameter("text")
 	}
 
+	if utf8.RuneCountInString(sendDialogReq.Text) > maxDialogMessageTextLength {
+		return apiv1.NewInvalidRequestErrorInvalidParameter("text", nil)
+	}
+
 	ctx := request.Context()
 
 	senderID := ctx.Value("user_id").(string)
@@ -41,11 +50,22 @@ func (h *SendDialog) Handle(responseWriter http.ResponseWriter, request *http.Re
 		Text: sendDialogReq.Text,
 	}
 
-	err := h.DialogRepository.Add(ctx, dialogMessage)
+	sentAt := time.Now()
+
+	ctx, consistencyRecorder := repository.WithConsistencyRecorder(ctx)
+
+	_, err := h.DialogRepository.Add(ctx, dialogMessage)
+	if err != nil {
+		return apiv1.NewRepositoryError(fmt.Errorf("send dialog handler, failed to add dialog message to repository: %w", err))
+	}
+
+	// The message is stored already, a draft left behind must not turn the request into a failure.
+	err = h.DialogDraftRepository.Delete(ctx, dialogMessage.From, dialogMessage.To, sentAt)
 	if err != nil {
-		return apiv1.NewServerError(fmt.Errorf("send dialog handler, failed to add dialog message to repository: %w", err))
+		slog.Warn(fmt.Sprintf("send dialog handler, failed to delete dialog draft: %s", err))
 	}
 
+	consistency.SetToken(responseWriter, consistencyRecorder)
 	responseWriter.Header().Set("Content-Type", "application/json; utf-8")
 	responseWriter.WriteHeader(http.StatusOK)
 
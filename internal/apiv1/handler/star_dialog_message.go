@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/inbugay1/httprouter"
+	"myfacebook-dialog/internal/apiv1"
+	"myfacebook-dialog/internal/repository"
+)
+
+type StarDialogMessage struct {
+	DialogRepository     repository.DialogRepository
+	DialogStarRepository repository.DialogStarRepository
+}
+
+func (h *StarDialogMessage) Handle(responseWriter http.ResponseWriter, request *http.Request) error {
+	ctx := request.Context()
+
+	userID := ctx.Value("user_id").(string)
+	messageID := httprouter.RouteParam(ctx, "message_id")
+	if !validMessageID(messageID) {
+		return apiv1.NewInvalidRequestErrorInvalidParameter("message_id", nil)
+	}
+
+	dialogMessages, err := h.DialogRepository.GetDialogMessagesByIDs(ctx, []string{messageID})
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return apiv1.NewRepositoryError(fmt.Errorf("star dialog message handler, failed to fetch dialog message from repository: %w", err))
+	}
+
+	dialogMsg, err := findDialogMessage(dialogMessages, func(dialogMsg repository.DialogMessage) bool {
+		return dialogMsg.HasParticipant(userID)
+	})
+	if err != nil {
+		if errors.Is(err, errAmbiguousMessageID) {
+			return apiv1.NewInvalidRequestError("legacy message id is ambiguous, use the message id",
+				fmt.Errorf("star dialog message handler, message %q: %w", messageID, err))
+		}
+
+		return apiv1.NewEntityNotFoundError(fmt.Errorf("star dialog message handler, message %q not found: %w", messageID, err))
+	}
+
+	err = h.DialogStarRepository.Add(ctx, userID, dialogMsg.ID)
+	if err != nil {
+		return apiv1.NewRepositoryError(fmt.Errorf("star dialog message handler, failed to add dialog star to repository: %w", err))
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json; utf-8")
+	responseWriter.WriteHeader(http.StatusOK)
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/inbugay1/httprouter"
+	"myfacebook-dialog/internal/apiv1"
+	"myfacebook-dialog/internal/repository"
+)
+
+type UnpinDialogMessage struct {
+	DialogRepository    repository.DialogRepository
+	DialogPinRepository repository.DialogPinRepository
+}
+
+type unpinDialogMessageRequest struct {
+	MessageID string `json:"message_id"`
+}
+
+func (h *UnpinDialogMessage) Handle(responseWriter http.ResponseWriter, request *http.Request) error {
+	var unpinDialogMessageReq unpinDialogMessageRequest
+	if err := json.NewDecoder(request.Body).Decode(&unpinDialogMessageReq); err != nil {
+		return apiv1.NewInvalidRequestError("invalid request body", fmt.Errorf("unpin dialog message handler, cannot decode request body: %w", err))
+	}
+
+	defer request.Body.Close()
+
+	if unpinDialogMessageReq.MessageID == "" {
+		return apiv1.NewInvalidRequestErrorMissingRequiredParameter("message_id")
+	}
+
+	if !validMessageID(unpinDialogMessageReq.MessageID) {
+		return apiv1.NewInvalidRequestErrorInvalidParameter("message_id", nil)
+	}
+
+	ctx := request.Context()
+
+	userID := ctx.Value("user_id").(string)
+	peerID := httprouter.RouteParam(ctx, "user_id")
+
+	err := deleteByMessageIDs(ctx, h.DialogRepository, unpinDialogMessageReq.MessageID, func(messageID string) error {
+		return h.DialogPinRepository.Delete(ctx, userID, peerID, messageID)
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return apiv1.NewEntityNotFoundError(fmt.Errorf("unpin dialog message handler, pin of message %q not found: %w",
+				unpinDialogMessageReq.MessageID, err))
+		}
+
+		return apiv1.NewRepositoryError(fmt.Errorf("unpin dialog message handler, failed to delete dialog pin from repository: %w", err))
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json; utf-8")
+	responseWriter.WriteHeader(http.StatusOK)
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/inbugay1/httprouter"
+	"myfacebook-dialog/internal/apiv1"
+	"myfacebook-dialog/internal/repository"
+)
+
+type UnstarDialogMessage struct {
+	DialogRepository     repository.DialogRepository
+	DialogStarRepository repository.DialogStarRepository
+}
+
+func (h *UnstarDialogMessage) Handle(responseWriter http.ResponseWriter, request *http.Request) error {
+	ctx := request.Context()
+
+	userID := ctx.Value("user_id").(string)
+	messageID := httprouter.RouteParam(ctx, "message_id")
+	if !validMessageID(messageID) {
+		return apiv1.NewInvalidRequestErrorInvalidParameter("message_id", nil)
+	}
+
+	err := deleteByMessageIDs(ctx, h.DialogRepository, messageID, func(messageID string) error {
+		return h.DialogStarRepository.Delete(ctx, userID, messageID)
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return apiv1.NewEntityNotFoundError(fmt.Errorf("unstar dialog message handler, star of message %q not found: %w", messageID, err))
+		}
+
+		return apiv1.NewRepositoryError(fmt.Errorf("unstar dialog message handler, failed to delete dialog star from repository: %w", err))
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json; utf-8")
+	responseWriter.WriteHeader(http.StatusOK)
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/inbugay1/httprouter"
+	"myfacebook-dialog/internal/apiv1"
+	"myfacebook-dialog/internal/repository"
+)
+
+const (
+	maxDialogFolders          = 20
+	maxDialogFolderNameLength = 100
+)
+
+type UpdateDialogSettings struct {
+	DialogSettingsRepository repository.DialogSettingsRepository
+}
+
+type updateDialogSettingsRequest struct {
+	MutedUntil *time.Time `json:"muted_until"`
+	Archived   bool       `json:"archived"`
+	Folders    []string   `json:"folders"`
+}
+
+func (h *UpdateDialogSettings) Handle(responseWriter http.ResponseWriter, request *http.Request) error {
+	var updateDialogSettingsReq updateDialogSettingsRequest
+	if err := json.NewDecoder(request.Body).Decode(&updateDialogSettingsReq); err != nil {
+		return apiv1.NewInvalidRequestError("invalid request body", fmt.Errorf("update dialog settings handler, cannot decode request body: %w", err))
+	}
+
+	defer request.Body.Close()
+
+	err := h.validateUpdateDialogSettingsRequest(updateDialogSettingsReq)
+	if err != nil {
+		return err
+	}
+
+	ctx := request.Context()
+
+	settings := repository.DialogSettings{
+		UserID:     ctx.Value("user_id").(string),
+		PeerID:     httprouter.RouteParam(ctx, "user_id"),
+		MutedUntil: updateDialogSettingsReq.MutedUntil,
+		Archived:   updateDialogSettingsReq.Archived,
+		Folders:    uniqueStrings(updateDialogSettingsReq.Folders),
+	}
+
+	err = h.DialogSettingsRepository.Save(ctx, settings)
+	if err != nil {
+		return apiv1.NewRepositoryError(fmt.Errorf("update dialog settings handler, failed to save dialog settings to repository: %w", err))
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json; utf-8")
+	responseWriter.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(responseWriter).Encode(newDialogSettings(settings, time.Now()))
+	if err != nil {
+		return apiv1.NewServerError(fmt.Errorf("update dialog settings handler, cannot encode response: %w", err))
+	}
+
+	return nil
+}
+
+func (h *UpdateDialogSettings) validateUpdateDialogSettingsRequest(updateDialogSettingsReq updateDialogSettingsRequest) error {
+	if len(updateDialogSettingsReq.Folders) > maxDialogFolders {
+		return apiv1.NewInvalidRequestError(fmt.Sprintf("no more than %d folders are allowed", maxDialogFolders), nil)
+	}
+
+	for _, folder := range updateDialogSettingsReq.Folders {
+		if folder == "" || utf8.RuneCountInString(folder) > maxDialogFolderNameLength {
+			return apiv1.NewInvalidRequestErrorInvalidParameter("folders", nil)
+		}
+	}
+
+	return nil
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]struct{}, len(values))
+	unique := make([]string, 0, len(values))
+
+	for _, value := range values {
+		if _, ok := seen[value]; ok {
+			continue
+		}
+
+		seen[value] = struct{}{}
+		unique = append(unique, value)
+	}
+
+	return unique
+}
@@ -36,7 +36,7 @@ func (m *Auth) Handle(responseWriter http.ResponseWriter, request *http.Request)
 				fmt.Errorf("auth middleware, user with token %q not found: %w", token, err))
 		}
 
-		return apiv1.NewServerError(fmt.Errorf("auth middleware, failed to get user by token: %w", err))
+		return apiv1.NewRepositoryError(fmt.Errorf("auth middleware, failed to get user by token: %w", err))
 	}
 
 	ctx = context.WithValue(ctx, "user_id", user.ID) //nolint:revive,staticcheck
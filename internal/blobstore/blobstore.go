@@ -0,0 +1,21 @@
+// Package blobstore keeps opaque objects by key, such as archives of old dialog messages.
+package blobstore
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// BlobStore keys are slash separated paths relative to the root of the store.
+type BlobStore interface {
+	// Put stores the object, replacing an object of the same key as a whole.
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete removes the object, a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+}
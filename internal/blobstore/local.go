@@ -0,0 +1,112 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const (
+	dirPerm  = 0o750
+	filePerm = 0o640
+)
+
+// LocalDisk stores objects as files under a root directory.
+type LocalDisk struct {
+	root string
+}
+
+func NewLocalDisk(root string) *LocalDisk {
+	return &LocalDisk{
+		root: root,
+	}
+}
+
+// Put writes the object to a temporary file first, so readers never see a partly written object.
+func (s *LocalDisk) Put(_ context.Context, key string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), dirPerm); err != nil {
+		return fmt.Errorf("failed to create directory of blob %q: %w", key, err)
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file of blob %q: %w", key, err)
+	}
+
+	defer os.Remove(tmpFile.Name()) //nolint:errcheck
+
+	if _, err := tmpFile.Write(data); err != nil {
+		_ = tmpFile.Close()
+
+		return fmt.Errorf("failed to write blob %q: %w", key, err)
+	}
+
+	if err := tmpFile.Sync(); err != nil {
+		_ = tmpFile.Close()
+
+		return fmt.Errorf("failed to sync blob %q: %w", key, err)
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to close blob %q: %w", key, err)
+	}
+
+	if err := os.Chmod(tmpFile.Name(), filePerm); err != nil {
+		return fmt.Errorf("failed to set permissions of blob %q: %w", key, err)
+	}
+
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		return fmt.Errorf("failed to move blob %q in place: %w", key, err)
+	}
+
+	return nil
+}
+
+func (s *LocalDisk) Get(_ context.Context, key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %q", ErrNotFound, key)
+		}
+
+		return nil, fmt.Errorf("failed to read blob %q: %w", key, err)
+	}
+
+	return data, nil
+}
+
+func (s *LocalDisk) Delete(_ context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete blob %q: %w", key, err)
+	}
+
+	return nil
+}
+
+// path keeps keys inside the root directory.
+func (s *LocalDisk) path(key string) (string, error) {
+	localPath := filepath.FromSlash(key)
+	if !filepath.IsLocal(localPath) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+
+	return filepath.Join(s.root, localPath), nil
+}
@@ -15,7 +15,9 @@ type EnvConfig struct {
 	RequestHeaderMaxSize                 int `env:"REQUEST_HEADER_MAX_SIZE" envDefault:"10000"`
 	RequestReadHeaderTimeoutMilliseconds int `env:"REQUEST_READ_HEADER_TIMEOUT_MILLISECONDS" envDefault:"2000"`
 
+	// DBDriverName selects the storage backend: postgres, or sqlite that keeps everything in the DBPath file.
 	DBDriverName         string `env:"DB_DRIVER_NAME" envDefault:"postgres"`
+	DBPath               string `env:"DB_PATH" envDefault:"./storage/myfacebook-dialog.sqlite"`
 	DBHost               string `env:"DB_HOST" envDefault:"localhost"`
 	DBPort               int    `env:"DB_PORT" envDefault:"5432"`
 	DBUsername           string `env:"DB_USERNAME" envDefault:"postgres"`
@@ -24,10 +26,83 @@ type EnvConfig struct {
 	DBSSLMode            string `env:"DB_SSL_MODE" envDefault:"disable"`
 	DBMaxOpenConnections int    `env:"DB_MAX_OPEN_CONNECTIONS" envDefault:"10"`
 
+	// DBAutoMigrate runs pending migrations of every database on start, turn it off to run them with the migrate command.
+	DBAutoMigrate bool `env:"DB_AUTO_MIGRATE" envDefault:"true"`
+
+	// DBMaxIdleConnections and the connection lifetimes apply to every pool: the app database, replicas and shards.
+	DBMaxIdleConnections           int `env:"DB_MAX_IDLE_CONNECTIONS" envDefault:"10"`
+	DBConnectionMaxLifetimeSeconds int `env:"DB_CONNECTION_MAX_LIFETIME_SECONDS" envDefault:"1800"`
+	DBConnectionMaxIdleTimeSeconds int `env:"DB_CONNECTION_MAX_IDLE_TIME_SECONDS" envDefault:"300"`
+	DBPoolWaitWarningMilliseconds  int `env:"DB_POOL_WAIT_WARNING_MILLISECONDS" envDefault:"100"`
+	DBPoolCheckIntervalSeconds     int `env:"DB_POOL_CHECK_INTERVAL_SECONDS" envDefault:"10"`
+
+	// DBConnectAttempts bounds the attempts to connect to every database at startup, the pause between attempts
+	// doubles from DBConnectRetryDelayMilliseconds up to DBConnectMaxRetryDelayMilliseconds.
+	DBConnectAttempts                  int `env:"DB_CONNECT_ATTEMPTS" envDefault:"10"`
+	DBConnectRetryDelayMilliseconds    int `env:"DB_CONNECT_RETRY_DELAY_MILLISECONDS" envDefault:"500"`
+	DBConnectMaxRetryDelayMilliseconds int `env:"DB_CONNECT_MAX_RETRY_DELAY_MILLISECONDS" envDefault:"5000"`
+	DBHealthCheckIntervalSeconds       int `env:"DB_HEALTH_CHECK_INTERVAL_SECONDS" envDefault:"5"`
+
+	// DBReadTimeoutMilliseconds and DBWriteTimeoutMilliseconds bound every query, zero disables the timeout.
+	DBReadTimeoutMilliseconds    int `env:"DB_READ_TIMEOUT_MILLISECONDS" envDefault:"3000"`
+	DBWriteTimeoutMilliseconds   int `env:"DB_WRITE_TIMEOUT_MILLISECONDS" envDefault:"5000"`
+	DBReadRetryAttempts          int `env:"DB_READ_RETRY_ATTEMPTS" envDefault:"3"`
+	DBReadRetryDelayMilliseconds int `env:"DB_READ_RETRY_DELAY_MILLISECONDS" envDefault:"50"`
+
+	// DBReplicaHosts lists read replicas of the app database as host:port or host:port/dbname.
+	DBReplicaHosts                []string `env:"DB_REPLICA_HOSTS" envSeparator:","`
+	DBReplicaMaxLagSeconds        int      `env:"DB_REPLICA_MAX_LAG_SECONDS" envDefault:"5"`
+	DBReplicaCheckIntervalSeconds int      `env:"DB_REPLICA_CHECK_INTERVAL_SECONDS" envDefault:"5"`
+
+	// DBShardHosts lists dialog shards as host:port or host:port/dbname, the app database is the only shard when empty.
+	DBShardHosts      []string `env:"DB_SHARD_HOSTS" envSeparator:","`
+	DBShardMapVersion int      `env:"DB_SHARD_MAP_VERSION" envDefault:"1"`
+
+	// DBNextShardHosts is the shard map dialogs are moved to by resharding, same format as DBShardHosts.
+	DBNextShardHosts      []string `env:"DB_NEXT_SHARD_HOSTS" envSeparator:","`
+	DBNextShardMapVersion int      `env:"DB_NEXT_SHARD_MAP_VERSION" envDefault:"2"`
+
+	ReshardingBatchSize            int `env:"RESHARDING_BATCH_SIZE" envDefault:"1000"`
+	ReshardingDualWriteWaitSeconds int `env:"RESHARDING_DUAL_WRITE_WAIT_SECONDS" envDefault:"30"`
+	ReshardingVerifyAttempts       int `env:"RESHARDING_VERIFY_ATTEMPTS" envDefault:"3"`
+	ReshardingPhaseRefreshSeconds  int `env:"RESHARDING_PHASE_REFRESH_SECONDS" envDefault:"5"`
+
+	DialogMaxPinnedMessages int `env:"DIALOG_MAX_PINNED_MESSAGES" envDefault:"5"`
+
+	DialogPartitionPremakeMonths              int  `env:"DIALOG_PARTITION_PREMAKE_MONTHS" envDefault:"3"`
+	DialogPartitionRetentionMonths            int  `env:"DIALOG_PARTITION_RETENTION_MONTHS" envDefault:"0"`
+	DialogPartitionDropExpired                bool `env:"DIALOG_PARTITION_DROP_EXPIRED" envDefault:"false"`
+	DialogPartitionMaintenanceIntervalMinutes int  `env:"DIALOG_PARTITION_MAINTENANCE_INTERVAL_MINUTES" envDefault:"60"`
+
+	// DialogRetentionDays is how long messages are kept, zero keeps them forever.
+	// DialogRetentionDaysByMessageType overrides it for message types as message_type:days.
+	DialogRetentionDays               int      `env:"DIALOG_RETENTION_DAYS" envDefault:"0"`
+	DialogRetentionDaysByMessageType  []string `env:"DIALOG_RETENTION_DAYS_BY_MESSAGE_TYPE" envSeparator:","`
+	DialogPurgeBatchSize              int      `env:"DIALOG_PURGE_BATCH_SIZE" envDefault:"1000"`
+	DialogPurgeBatchPauseMilliseconds int      `env:"DIALOG_PURGE_BATCH_PAUSE_MILLISECONDS" envDefault:"100"`
+	DialogPurgeIntervalMinutes        int      `env:"DIALOG_PURGE_INTERVAL_MINUTES" envDefault:"60"`
+	DialogPurgeDryRun                 bool     `env:"DIALOG_PURGE_DRY_RUN" envDefault:"false"`
+
+	// DialogArchiveLocalPath is the directory of dialog archive files, archiving is off when empty.
+	DialogArchiveLocalPath string `env:"DIALOG_ARCHIVE_LOCAL_PATH"`
+
 	MyfacbookAPIBaseURL string `env:"MYFACEBOOK_API_BASE_URL" envDefault:"http://localhost:9090"`
 
+	// LegacyDialogDualWrite sends messages stored through the API to the legacy dialogs of the monolith,
+	// LegacyDialogShadowReadPercent of dialog reads are compared with them.
+	LegacyDialogDualWrite             bool `env:"LEGACY_DIALOG_DUAL_WRITE" envDefault:"false"`
+	LegacyDialogShadowReadPercent     int  `env:"LEGACY_DIALOG_SHADOW_READ_PERCENT" envDefault:"0"`
+	LegacyDialogShadowReadConcurrency int  `env:"LEGACY_DIALOG_SHADOW_READ_CONCURRENCY" envDefault:"10"`
+	LegacyDialogTimeoutMilliseconds   int  `env:"LEGACY_DIALOG_TIMEOUT_MILLISECONDS" envDefault:"1000"`
+
 	OTelExporterType         string `env:"OTEL_EXPORTER_TYPE" envDefault:"stdout"`
 	OTelExporterOTLPEndpoint string `env:"OTEL_EXPORTER_OTLP_ENDPOINT" envDefault:"localhost:4318"`
+
+	// OTelMetricsExporterType is stdout, otel_http or none, metrics are pushed every OTelMetricExportIntervalSeconds.
+	// OTelExporterOTLPMetricsEndpoint takes metrics to another collector than traces, it is OTelExporterOTLPEndpoint when empty.
+	OTelMetricsExporterType         string `env:"OTEL_METRICS_EXPORTER_TYPE" envDefault:"stdout"`
+	OTelExporterOTLPMetricsEndpoint string `env:"OTEL_EXPORTER_OTLP_METRICS_ENDPOINT"`
+	OTelMetricExportIntervalSeconds int    `env:"OTEL_METRIC_EXPORT_INTERVAL_SECONDS" envDefault:"60"`
 }
 
 func GetConfigFromEnv() *EnvConfig {
@@ -0,0 +1,42 @@
+// Package consistency passes consistency tokens of writes between HTTP requests,
+// a read that sends back the token of a write observes that write.
+package consistency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+
+	"myfacebook-dialog/internal/repository"
+)
+
+// TokenHeader is returned by writes, reads that send it back observe those writes.
+const TokenHeader = "X-Consistency-Token"
+
+var ErrInvalidToken = errors.New("invalid consistency token")
+
+// tokenRegexp matches a PostgreSQL WAL position.
+var tokenRegexp = regexp.MustCompile(`^[0-9A-F]{1,8}/[0-9A-F]{1,8}$`)
+
+// SetToken returns the token of the writes recorded by the recorder, if any.
+func SetToken(responseWriter http.ResponseWriter, consistencyRecorder *repository.ConsistencyRecorder) {
+	if token := consistencyRecorder.Token(); token != "" {
+		responseWriter.Header().Set(TokenHeader, token)
+	}
+}
+
+// WithRequestToken makes reads with the returned context observe the writes of the request's token.
+// A request without a token gets the context back as is, a malformed token yields ErrInvalidToken.
+func WithRequestToken(ctx context.Context, request *http.Request) (context.Context, error) {
+	token := request.Header.Get(TokenHeader)
+	if token == "" {
+		return ctx, nil
+	}
+
+	if !tokenRegexp.MatchString(token) {
+		return nil, ErrInvalidToken
+	}
+
+	return repository.WithConsistencyToken(ctx, token), nil
+}
@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// statementTimeoutKey overrides the statement timeouts of the database config for queries run with the context.
+type statementTimeoutKey struct{}
+
+// WithStatementTimeout makes every query run with the context time out after timeout instead of the configured
+// read or write timeout, zero disables timeouts. Maintenance jobs use it for queries that legitimately run long.
+func WithStatementTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, statementTimeoutKey{}, timeout)
+}
+
+// GetConnection returns the connection for writes: the transaction of the context or the primary.
+// Queries time out after the write timeout and are not retried, errors are classified into repository errors.
+func (db *DB) GetConnection(ctx context.Context) Conn { //nolint:ireturn
+	return &conn{
+		Conn:    db.primaryConn(ctx),
+		timeout: statementTimeout(ctx, db.config.WriteTimeout),
+	}
+}
+
+// GetRetryableConnection returns the connection for reads of the primary, queries that fail on a dropped connection,
+// a restarting server or a serialization failure run again. Inside a transaction the transaction decides on retries.
+func (db *DB) GetRetryableConnection(ctx context.Context) Conn { //nolint:ireturn
+	return db.readConn(ctx, db.primaryConn(ctx))
+}
+
+func (db *DB) readConn(ctx context.Context, dbConn Conn) *conn {
+	readConn := &conn{
+		Conn:    dbConn,
+		timeout: statementTimeout(ctx, db.config.ReadTimeout),
+	}
+
+	if !db.InTx(ctx) {
+		readConn.attempts = db.config.ReadRetryAttempts
+		readConn.retryDelay = db.config.ReadRetryDelay
+	}
+
+	return readConn
+}
+
+func statementTimeout(ctx context.Context, configured time.Duration) time.Duration {
+	if timeout, ok := ctx.Value(statementTimeoutKey{}).(time.Duration); ok {
+		return timeout
+	}
+
+	return configured
+}
+
+// conn applies the statement timeout to queries and classifies their errors. Rows of QueryContext, QueryxContext
+// and QueryRowxContext outlive the call, so these are not timed out and are not retried.
+type conn struct {
+	Conn
+	timeout    time.Duration
+	attempts   int
+	retryDelay time.Duration
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+
+	err := c.do(ctx, func(ctx context.Context) error {
+		var err error
+
+		result, err = c.Conn.ExecContext(ctx, query, args...)
+
+		return err //nolint:wrapcheck
+	})
+
+	return result, err
+}
+
+func (c *conn) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	var result sql.Result
+
+	err := c.do(ctx, func(ctx context.Context) error {
+		var err error
+
+		result, err = c.Conn.NamedExecContext(ctx, query, arg)
+
+		return err //nolint:wrapcheck
+	})
+
+	return result, err
+}
+
+func (c *conn) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return c.do(ctx, func(ctx context.Context) error {
+		return c.Conn.GetContext(ctx, dest, query, args...) //nolint:wrapcheck
+	})
+}
+
+func (c *conn) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return c.do(ctx, func(ctx context.Context) error {
+		return c.Conn.SelectContext(ctx, dest, query, args...) //nolint:wrapcheck
+	})
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := c.Conn.QueryContext(ctx, query, args...)
+
+	return rows, classifyError(err)
+}
+
+func (c *conn) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	rows, err := c.Conn.QueryxContext(ctx, query, args...)
+
+	return rows, classifyError(err)
+}
+
+// do runs the query with the timeout, and runs it again on transient errors while attempts last.
+// A query that times out is not retried, another attempt would only add to the wait.
+func (c *conn) do(ctx context.Context, query func(ctx context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		err := c.run(ctx, query)
+		if err == nil || attempt >= c.attempts || !isTransient(err) || ctx.Err() != nil {
+			return err
+		}
+
+		delay := backoff(c.retryDelay, attempt)
+
+		slog.Warn(fmt.Sprintf("Query attempt %d of %d failed, retrying in %s: %s", attempt, c.attempts, delay, err))
+
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			return err
+		}
+	}
+}
+
+func (c *conn) run(ctx context.Context, query func(ctx context.Context) error) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	return classifyError(query(ctx))
+}
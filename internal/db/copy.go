@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+var errCopyOutsideTx = errors.New("copy requires a transaction")
+
+// CopyIn loads rows into the table with COPY FROM STDIN, much faster than inserts for large batches.
+// COPY streams over a single connection, so it runs only in the transaction of the context, see TxManager.InTx.
+// The copy times out after the write timeout, a failed row fails the whole copy.
+func (db *DB) CopyIn(ctx context.Context, table string, columns []string, rows [][]interface{}) error {
+	tx := db.txFromContext(ctx)
+	if tx == nil {
+		return errCopyOutsideTx
+	}
+
+	if timeout := statementTimeout(ctx, db.config.WriteTimeout); timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(table, columns...))
+	if err != nil {
+		return fmt.Errorf("failed to start copy into %s: %w", table, classifyError(err))
+	}
+
+	for _, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			_ = stmt.Close()
+
+			return fmt.Errorf("failed to copy row into %s: %w", table, classifyError(err))
+		}
+	}
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		_ = stmt.Close()
+
+		return fmt.Errorf("failed to copy into %s: %w", table, classifyError(err))
+	}
+
+	if err := stmt.Close(); err != nil {
+		return fmt.Errorf("failed to finish copy into %s: %w", table, classifyError(err))
+	}
+
+	return nil
+}
@@ -4,30 +4,80 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
+	"sync/atomic"
+	"time"
 
-	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file" // enable file migrations
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq" // enable postgres driver
 )
 
+const (
+	DriverPostgres = "postgres"
+	// DriverSQLite keeps the whole service in a single file for single-node and test deployments.
+	DriverSQLite = "sqlite"
+)
+
 type Config struct {
-	DriverName         string
-	Host               string
-	Port               int
-	User               string
-	Password           string
-	DBName             string
-	SSLMode            string
-	MigrationPath      string
-	MaxOpenConnections int
+	DriverName string
+	Host       string
+	Port       int
+	User       string
+	Password   string
+	DBName     string
+	SSLMode    string
+	// Migrations holds the migration files of the driver, see storage/migrations.
+	Migrations fs.FS
+
+	// MaxOpenConnections bounds the connections of the pool, MaxIdleConnections of them are kept open between queries.
+	// A connection is closed after ConnectionMaxLifetime or after ConnectionMaxIdleTime unused, zero keeps it forever.
+	MaxOpenConnections    int
+	MaxIdleConnections    int
+	ConnectionMaxLifetime time.Duration
+	ConnectionMaxIdleTime time.Duration
+	// PoolWaitWarningThreshold is the average wait for a free connection of the pool logged as a warning,
+	// checked every PoolCheckInterval. Zero disables the warning.
+	PoolWaitWarningThreshold time.Duration
+	PoolCheckInterval        time.Duration
+
+	// Connect tries up to ConnectAttempts times while the database is unavailable, the pause between attempts starts
+	// from ConnectRetryDelay and doubles up to ConnectMaxRetryDelay. A database that starts with the service comes up late.
+	ConnectAttempts      int
+	ConnectRetryDelay    time.Duration
+	ConnectMaxRetryDelay time.Duration
+	// HealthCheckInterval is how often WatchHealth pings the primary.
+	HealthCheckInterval time.Duration
+
+	// ReadTimeout and WriteTimeout bound every query of repositories, zero disables them.
+	// Failed reads run up to ReadRetryAttempts times with a jittered backoff starting from ReadRetryDelay.
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	ReadRetryAttempts int
+	ReadRetryDelay    time.Duration
+
+	// DSN replaces the host, port, credentials and options of the postgres driver when set.
+	DSN string
+
+	// Path is the database file of the sqlite driver, the other connection settings are not used by it.
+	Path string
+
+	// Replicas share the credentials of the primary.
+	Replicas             []ReplicaConfig
+	ReplicaMaxLag        time.Duration
+	ReplicaCheckInterval time.Duration
 }
 
+var errNotReplica = errors.New("database is not in recovery")
+
 type DB struct {
-	config Config
-	conn   *sqlx.DB
+	config      Config
+	conn        *sqlx.DB
+	replicas    []*replica
+	nextReplica atomic.Uint64
+
+	stopPoolMonitor func()
+	healthy         atomic.Bool
 }
 
 func New(config Config) *DB {
@@ -36,60 +86,98 @@ func New(config Config) *DB {
 	}
 }
 
+// Connect connects to the primary, retrying while the database is unavailable, and opens the replicas.
 func (db *DB) Connect(ctx context.Context) error {
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		db.config.Host, db.config.Port, db.config.User, db.config.Password, db.config.DBName, db.config.SSLMode)
+	maxAttempts := max(db.config.ConnectAttempts, 1)
+	retryDelay := db.config.ConnectRetryDelay
+
+	for attempt := 1; ; attempt++ {
+		err := db.connect(ctx)
+		if err == nil {
+			db.healthy.Store(true)
+
+			return nil
+		}
+
+		if attempt >= maxAttempts || !isTransient(classifyError(err)) {
+			return err
+		}
+
+		delay := backoff(retryDelay, 1)
+		if db.config.ConnectMaxRetryDelay > 0 {
+			delay = min(delay, db.config.ConnectMaxRetryDelay)
+			retryDelay = min(retryDelay*2, db.config.ConnectMaxRetryDelay)
+		} else {
+			retryDelay *= 2
+		}
+
+		slog.Warn(fmt.Sprintf("Connect attempt %d of %d failed, retrying in %s: %s", attempt, maxAttempts, delay, err))
+
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			return fmt.Errorf("connect retry canceled: %w", errors.Join(sleepErr, err))
+		}
+	}
+}
+
+func (db *DB) connect(ctx context.Context) error {
+	if db.config.DriverName == DriverSQLite {
+		return db.connectSQLite(ctx)
+	}
+
+	dsn := db.config.DSN
+	if dsn == "" {
+		dsn = db.dsn(db.config.Host, db.config.Port, db.config.DBName)
+	}
 
 	conn, err := sqlx.ConnectContext(ctx, db.config.DriverName, dsn)
 	if err != nil {
 		return fmt.Errorf("failed to connect to database %q on %s:%d: %w", db.config.DBName, db.config.Host, db.config.Port, err)
 	}
 
-	conn.SetMaxOpenConns(db.config.MaxOpenConnections)
+	db.configurePool(conn)
 
 	if err := conn.PingContext(ctx); err != nil {
+		_ = conn.Close()
+
 		return fmt.Errorf("failed to ping postgres on %s:%d: %w", db.config.Host, db.config.Port, err)
 	}
 
 	db.conn = conn
 
-	return nil
-}
+	if err := db.connectReplicas(); err != nil {
+		_ = conn.Close()
 
-func (db *DB) Migrate() error {
-	driver, err := postgres.WithInstance(db.conn.DB, &postgres.Config{})
-	if err != nil {
-		return fmt.Errorf("failed to create migration postgres driver: %w", err)
+		return err
 	}
 
-	m, err := migrate.NewWithDatabaseInstance("file://"+db.config.MigrationPath, db.config.DBName, driver)
-	if err != nil {
-		return fmt.Errorf("failed to create migrate instance: %w", err)
-	}
+	db.checkReplicas(ctx)
 
-	if err = m.Up(); err != nil {
-		if errors.Is(err, migrate.ErrNoChange) {
-			slog.Info("No new db migrations to run")
+	if err := db.startPoolMonitor(); err != nil {
+		db.closeReplicas()
+		_ = conn.Close()
 
-			return nil
-		}
-
-		return fmt.Errorf("failed to up migration: %w", err)
+		return err
 	}
 
-	slog.Info("Successfully run db migrations")
-
 	return nil
 }
 
+func (db *DB) dsn(host string, port int, dbName string) string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		host, port, db.config.User, db.config.Password, dbName, db.config.SSLMode)
+}
+
 func (db *DB) Disconnect() error {
+	if db.stopPoolMonitor != nil {
+		db.stopPoolMonitor()
+		db.stopPoolMonitor = nil
+	}
+
+	db.closeReplicas()
+
 	if err := db.conn.Close(); err != nil {
 		return fmt.Errorf("failed to close database connection: %w", err)
 	}
 
 	return nil
 }
-
-func (db *DB) GetConnection() *sqlx.DB {
-	return db.conn
-}
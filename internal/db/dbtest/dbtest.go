@@ -0,0 +1,70 @@
+// Package dbtest connects tests to migrated databases.
+package dbtest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"myfacebook-dialog/internal/db"
+	"myfacebook-dialog/storage/migrations"
+)
+
+// PostgresDSNEnv names the connection string of the Postgres database of tests. The database is migrated
+// and keeps the rows written by tests, so it must not be the one of the service.
+const PostgresDSNEnv = "TEST_POSTGRES_DSN"
+
+// postgresMaxOpenConnections lets tests of concurrent writes actually run in parallel.
+const postgresMaxOpenConnections = 10
+
+// Postgres connects to the database of PostgresDSNEnv and migrates it, the test is skipped when it is not set.
+func Postgres(t testing.TB) *db.DB {
+	t.Helper()
+
+	dsn := os.Getenv(PostgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", PostgresDSNEnv)
+	}
+
+	return connect(t, db.Config{
+		DriverName:         db.DriverPostgres,
+		DSN:                dsn,
+		MaxOpenConnections: postgresMaxOpenConnections,
+		Migrations:         migrations.Postgres,
+	})
+}
+
+// SQLite creates a migrated database in a temporary directory of the test.
+func SQLite(t testing.TB) *db.DB {
+	t.Helper()
+
+	return connect(t, db.Config{
+		DriverName: db.DriverSQLite,
+		Path:       filepath.Join(t.TempDir(), "test.sqlite"),
+		Migrations: migrations.SQLite,
+	})
+}
+
+func connect(t testing.TB, config db.Config) *db.DB {
+	t.Helper()
+
+	ctx := context.Background()
+	testDB := db.New(config)
+
+	if err := testDB.Connect(ctx); err != nil {
+		t.Fatalf("cannot connect to %s test db: %s", config.DriverName, err)
+	}
+
+	t.Cleanup(func() {
+		if err := testDB.Disconnect(); err != nil {
+			t.Errorf("failed to disconnect from %s test db: %s", config.DriverName, err)
+		}
+	})
+
+	if err := testDB.Migrate(ctx); err != nil {
+		t.Fatalf("%s test db migration failed: %s", config.DriverName, err)
+	}
+
+	return testDB
+}
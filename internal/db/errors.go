@@ -0,0 +1,143 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+	"myfacebook-dialog/internal/repository"
+)
+
+// Postgres error classes and codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html.
+const (
+	pqClassConnectionException  = "08"
+	pqClassInsufficientResource = "53"
+
+	pqCodeStringDataRightTruncation = "22001"
+	pqCodeNotNullViolation          = "23502"
+	pqCodeForeignKeyViolation       = "23503"
+	pqCodeUniqueViolation           = "23505"
+	pqCodeCheckViolation            = "23514"
+	pqCodeSerializationFailure      = "40001"
+	pqCodeDeadlockDetected          = "40P01"
+	pqCodeQueryCanceled             = "57014"
+	pqCodeAdminShutdown             = "57P01"
+	pqCodeCrashShutdown             = "57P02"
+	pqCodeCannotConnectNow          = "57P03"
+)
+
+// classifyError wraps driver errors with the matching repository error, other errors are returned as they are.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if kind := errorKind(err); kind != nil && !errors.Is(err, kind) {
+		return fmt.Errorf("%w: %w", kind, err)
+	}
+
+	return err
+}
+
+func errorKind(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErrorKind(pqErr)
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErrorKind(sqliteErr)
+	}
+
+	var netErr net.Error
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return repository.ErrTimeout
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.EPIPE),
+		errors.As(err, &netErr):
+		return repository.ErrUnavailable
+	}
+
+	return nil
+}
+
+func pqErrorKind(pqErr *pq.Error) error {
+	switch pqErr.Code {
+	case pqCodeUniqueViolation:
+		return repository.ErrAlreadyExists
+	case pqCodeCheckViolation, pqCodeNotNullViolation, pqCodeForeignKeyViolation,
+		pqCodeStringDataRightTruncation:
+		return repository.ErrConstraintViolation
+	case pqCodeSerializationFailure, pqCodeDeadlockDetected:
+		return repository.ErrConflict
+	case pqCodeQueryCanceled:
+		return repository.ErrTimeout
+	case pqCodeAdminShutdown, pqCodeCrashShutdown, pqCodeCannotConnectNow:
+		return repository.ErrUnavailable
+	}
+
+	switch pqErr.Code.Class() {
+	case pqClassConnectionException, pqClassInsufficientResource:
+		return repository.ErrUnavailable
+	}
+
+	return nil
+}
+
+func sqliteErrorKind(sqliteErr *sqlite.Error) error {
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		return repository.ErrAlreadyExists
+	case sqlite3.SQLITE_CONSTRAINT_CHECK, sqlite3.SQLITE_CONSTRAINT_NOTNULL, sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+		return repository.ErrConstraintViolation
+	}
+
+	// The low byte of an extended result code is its primary code.
+	switch sqliteErr.Code() & 0xff {
+	case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+		return repository.ErrConflict
+	}
+
+	return nil
+}
+
+// isTransient reports whether running the failed operation again may succeed.
+func isTransient(err error) bool {
+	return errors.Is(err, repository.ErrUnavailable) || errors.Is(err, repository.ErrConflict)
+}
+
+// backoff returns the pause before the next attempt: base doubled for every failed attempt after the first, plus up to as much of random jitter.
+func backoff(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+
+	delay := base << (attempt - 1)
+
+	return delay + time.Duration(rand.Int63n(int64(delay))) //nolint:gosec
+}
+
+// sleep waits for the delay, it returns the context error when the context is done first.
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err() //nolint:wrapcheck
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Ready reports whether the primary answered the last health check, a database that is not ready fails queries
+// with repository.ErrUnavailable until it comes back.
+func (db *DB) Ready() bool {
+	return db.healthy.Load()
+}
+
+// WatchHealth pings the primary every HealthCheckInterval until the context is done. The pool drops connections
+// broken by a restart or a failover and opens new ones on the next query, so a lost database recovers by itself
+// once it is reachable again; meanwhile Ready reports false.
+func (db *DB) WatchHealth(ctx context.Context) {
+	if db.config.HealthCheckInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(db.config.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			db.checkHealth(ctx)
+		}
+	}
+}
+
+func (db *DB) checkHealth(ctx context.Context) {
+	pingCtx, cancel := context.WithTimeout(ctx, db.config.HealthCheckInterval)
+	defer cancel()
+
+	err := db.conn.PingContext(pingCtx)
+	if ctx.Err() != nil {
+		// The service is stopping, the ping was canceled rather than failed.
+		return
+	}
+
+	healthy := err == nil
+
+	switch wasHealthy := db.healthy.Swap(healthy); {
+	case wasHealthy && !healthy:
+		slog.Error(fmt.Sprintf("Database %s is unavailable: %s", db.name(), classifyError(err)))
+	case !wasHealthy && healthy:
+		slog.Info(fmt.Sprintf("Database %s is available again", db.name()))
+	}
+}
+
+func (db *DB) name() string {
+	if db.config.DriverName == DriverSQLite {
+		return fmt.Sprintf("sqlite %q", db.config.Path)
+	}
+
+	return fmt.Sprintf("%q on %s:%d", db.config.DBName, db.config.Host, db.config.Port)
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"log/slog"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// TryAdvisoryLock takes a session advisory lock of the primary without waiting, it reports false when another session holds it.
+// The lock lives on a dedicated connection until unlock is called, so every instance using the database agrees on the holder.
+func (db *DB) TryAdvisoryLock(ctx context.Context, key int64) (func(), bool, error) {
+	conn, err := db.conn.Connx(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get connection for advisory lock: %w", err)
+	}
+
+	var locked bool
+
+	if err := conn.GetContext(ctx, &locked, `SELECT pg_try_advisory_lock($1)`, key); err != nil {
+		_ = conn.Close()
+
+		return nil, false, fmt.Errorf("failed to take advisory lock %d: %w", key, err)
+	}
+
+	if !locked {
+		_ = conn.Close()
+
+		return nil, false, nil
+	}
+
+	return advisoryUnlock(conn, key), true, nil
+}
+
+// advisoryLock takes a session advisory lock of the primary, waiting for another session to release it
+// until the context is done.
+func (db *DB) advisoryLock(ctx context.Context, key int64) (func(), error) {
+	unlock, locked, err := db.TryAdvisoryLock(ctx, key)
+	if err != nil || locked {
+		return unlock, err
+	}
+
+	slog.Info(fmt.Sprintf("Waiting for advisory lock %d held by another session", key))
+
+	conn, err := db.conn.Connx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection for advisory lock: %w", err)
+	}
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, key); err != nil {
+		// The wait may have been canceled after the lock was granted, closing the session releases it.
+		_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+		_ = conn.Close()
+
+		return nil, fmt.Errorf("failed to take advisory lock %d: %w", key, err)
+	}
+
+	return advisoryUnlock(conn, key), nil
+}
+
+func advisoryUnlock(conn *sqlx.Conn, key int64) func() {
+	return func() {
+		// The context of the caller may be done already, the lock has to be released anyway.
+		_, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, key)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Failed to release advisory lock %d: %s", key, err))
+
+			// A connection that still holds the lock must not go back to the pool, closing it ends the session.
+			_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+
+		_ = conn.Close()
+	}
+}
@@ -0,0 +1,274 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// migrationLockKey lets a single instance migrate a database at a time. The lock of golang-migrate gives up
+// after 15 seconds, while a migration that backfills a large table runs much longer than that.
+const migrationLockKey int64 = 0x6d696772617465 // "migrate"
+
+var ErrIrreversibleMigration = errors.New("irreversible migration")
+
+// MigrationStatus describes the schema version of a database against the migrations of the binary.
+type MigrationStatus struct {
+	// Version is the last applied migration, zero when none is.
+	Version uint
+	// Dirty is set when the last migration failed halfway, it has to be fixed by hand and forced.
+	Dirty bool
+	// Latest is the newest migration of the binary, Pending lists the migrations after Version.
+	Latest  uint
+	Pending []uint
+}
+
+// Migrate applies every pending migration.
+func (db *DB) Migrate(ctx context.Context) error {
+	return db.withMigrate(ctx, func(m *migrate.Migrate) error {
+		if err := m.Up(); err != nil {
+			if errors.Is(err, migrate.ErrNoChange) {
+				slog.Info(fmt.Sprintf("No new migrations to run on %s", db.name()))
+
+				return nil
+			}
+
+			return fmt.Errorf("failed to up migration: %w", err)
+		}
+
+		slog.Info(fmt.Sprintf("Successfully run migrations on %s", db.name()))
+
+		return nil
+	})
+}
+
+// MigrateDown reverts the last steps migrations. Only migrations that ship a .down.sql file can be reverted,
+// golang-migrate would otherwise just step the version back and leave the schema as it is. When one of them is
+// irreversible nothing is reverted and ErrIrreversibleMigration names its version.
+func (db *DB) MigrateDown(ctx context.Context, steps int) error {
+	sourceDriver, err := iofs.New(db.config.Migrations, ".")
+	if err != nil {
+		return fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	defer sourceDriver.Close()
+
+	return db.withMigrate(ctx, func(m *migrate.Migrate) error {
+		version, _, err := m.Version()
+		if err != nil {
+			return fmt.Errorf("failed to fetch migration version: %w", err)
+		}
+
+		if err := requireDownMigrations(sourceDriver, version, steps); err != nil {
+			return err
+		}
+
+		if err := m.Steps(-steps); err != nil {
+			return fmt.Errorf("failed to down migration: %w", err)
+		}
+
+		slog.Info(fmt.Sprintf("Successfully reverted %d migrations on %s", steps, db.name()))
+
+		return nil
+	})
+}
+
+// ForceMigrationVersion records version as applied and clears the dirty flag without running any migration,
+// -1 records that none is applied.
+func (db *DB) ForceMigrationVersion(ctx context.Context, version int) error {
+	return db.withMigrate(ctx, func(m *migrate.Migrate) error {
+		if err := m.Force(version); err != nil {
+			return fmt.Errorf("failed to force migration version %d: %w", version, err)
+		}
+
+		slog.Info(fmt.Sprintf("Forced migration version %d on %s", version, db.name()))
+
+		return nil
+	})
+}
+
+func (db *DB) MigrationStatus(ctx context.Context) (MigrationStatus, error) {
+	var status MigrationStatus
+
+	sourceDriver, err := iofs.New(db.config.Migrations, ".")
+	if err != nil {
+		return status, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	defer sourceDriver.Close()
+
+	// The status does not wait for a running migration, it reports the version that is dirty meanwhile.
+	err = db.runMigrate(ctx, func(m *migrate.Migrate) error {
+		version, dirty, err := m.Version()
+		if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+			return fmt.Errorf("failed to fetch migration version: %w", err)
+		}
+
+		status.Version, status.Dirty = version, dirty
+
+		return nil
+	})
+	if err != nil {
+		return status, err
+	}
+
+	versions, err := migrationVersions(sourceDriver)
+	if err != nil {
+		return status, err
+	}
+
+	for _, version := range versions {
+		if version > status.Version {
+			status.Pending = append(status.Pending, version)
+		}
+
+		status.Latest = version
+	}
+
+	return status, nil
+}
+
+// requireDownMigrations checks that the steps migrations up to version can be reverted.
+func requireDownMigrations(sourceDriver source.Driver, version uint, steps int) error {
+	for step := 0; step < steps; step++ {
+		downMigration, _, err := sourceDriver.ReadDown(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%w %d", ErrIrreversibleMigration, version)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to read down migration %d: %w", version, err)
+		}
+
+		_ = downMigration.Close()
+
+		version, err = sourceDriver.Prev(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to read migration before %d: %w", version, err)
+		}
+	}
+
+	return nil
+}
+
+// migrationVersions lists the versions of the migrations in ascending order.
+func migrationVersions(sourceDriver source.Driver) ([]uint, error) {
+	version, err := sourceDriver.First()
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read first migration: %w", err)
+	}
+
+	versions := []uint{version}
+
+	for {
+		version, err = sourceDriver.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return versions, nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration after %d: %w", version, err)
+		}
+
+		versions = append(versions, version)
+	}
+}
+
+// withMigrate runs fn with a migrate instance of the database while holding the migration lock.
+func (db *DB) withMigrate(ctx context.Context, fn func(m *migrate.Migrate) error) error {
+	unlock, err := db.lockMigrations(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	return db.runMigrate(ctx, fn)
+}
+
+func (db *DB) runMigrate(ctx context.Context, fn func(m *migrate.Migrate) error) error {
+	sourceDriver, err := iofs.New(db.config.Migrations, ".")
+	if err != nil {
+		return fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	if db.config.DriverName == DriverSQLite {
+		// The sqlite driver closes the connection pool of the database on Close, only the source is closed.
+		defer sourceDriver.Close()
+
+		databaseDriver, err := sqlite.WithInstance(db.conn.DB, &sqlite.Config{})
+		if err != nil {
+			return fmt.Errorf("failed to create migration sqlite driver: %w", err)
+		}
+
+		m, err := migrate.NewWithInstance("iofs", sourceDriver, db.config.Path, databaseDriver)
+		if err != nil {
+			return fmt.Errorf("failed to create migrate instance: %w", err)
+		}
+
+		return fn(m)
+	}
+
+	conn, err := db.conn.Conn(ctx)
+	if err != nil {
+		_ = sourceDriver.Close()
+
+		return fmt.Errorf("failed to get connection for migrations: %w", err)
+	}
+
+	// Created with a connection rather than the pool, the driver closes just the connection.
+	databaseDriver, err := postgres.WithConnection(ctx, conn, &postgres.Config{})
+	if err != nil {
+		_ = sourceDriver.Close()
+		_ = conn.Close()
+
+		return fmt.Errorf("failed to create migration postgres driver: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", sourceDriver, db.config.DBName, databaseDriver)
+	if err != nil {
+		_ = sourceDriver.Close()
+		_ = databaseDriver.Close()
+
+		return fmt.Errorf("failed to create migrate instance: %w", err)
+	}
+
+	defer func() {
+		sourceErr, databaseErr := m.Close()
+		if err := errors.Join(sourceErr, databaseErr); err != nil {
+			slog.Error(fmt.Sprintf("Failed to close migrate instance: %s", err))
+		}
+	}()
+
+	return fn(m)
+}
+
+// lockMigrations waits for other instances migrating the database, SQLite has a single writer and needs no lock.
+func (db *DB) lockMigrations(ctx context.Context) (func(), error) {
+	if db.config.DriverName == DriverSQLite {
+		return func() {}, nil
+	}
+
+	unlock, err := db.advisoryLock(ctx, migrationLockKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to take migration lock: %w", err)
+	}
+
+	return unlock, nil
+}
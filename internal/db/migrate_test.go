@@ -0,0 +1,89 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"myfacebook-dialog/internal/db"
+	"myfacebook-dialog/internal/db/dbtest"
+)
+
+func TestMigrateDownRevertsSQLiteMigrations(t *testing.T) {
+	ctx := context.Background()
+	sqliteDB := dbtest.SQLite(t)
+
+	status, err := sqliteDB.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatalf("failed to fetch migration status: %s", err)
+	}
+
+	if err := sqliteDB.MigrateDown(ctx, 3); err != nil {
+		t.Fatalf("failed to revert migrations: %s", err)
+	}
+
+	var tables int
+
+	if err := sqliteDB.GetConnection(ctx).GetContext(ctx, &tables, `SELECT count(*) FROM sqlite_master WHERE type='table' AND name LIKE 'dialog%'`); err != nil {
+		t.Fatalf("failed to count tables: %s", err)
+	}
+
+	if tables != 0 {
+		t.Errorf("got %d dialog tables after reverting every migration, want none", tables)
+	}
+
+	if err := sqliteDB.Migrate(ctx); err != nil {
+		t.Fatalf("failed to migrate again: %s", err)
+	}
+
+	migratedStatus, err := sqliteDB.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatalf("failed to fetch migration status: %s", err)
+	}
+
+	if migratedStatus.Version != status.Version || migratedStatus.Dirty {
+		t.Errorf("got version %d, dirty %t after migrating again, want version %d", migratedStatus.Version, migratedStatus.Dirty, status.Version)
+	}
+}
+
+func TestMigrateDownRefusesIrreversibleMigrations(t *testing.T) {
+	ctx := context.Background()
+
+	sqliteDB := db.New(db.Config{
+		DriverName: db.DriverSQLite,
+		Path:       filepath.Join(t.TempDir(), "test.sqlite"),
+		Migrations: fstest.MapFS{
+			"1_create_a.up.sql":   {Data: []byte(`create table a (id integer)`)},
+			"1_create_a.down.sql": {Data: []byte(`drop table a`)},
+			"2_create_b.up.sql":   {Data: []byte(`create table b (id integer)`)},
+		},
+	})
+
+	if err := sqliteDB.Connect(ctx); err != nil {
+		t.Fatalf("failed to connect: %s", err)
+	}
+
+	t.Cleanup(func() {
+		_ = sqliteDB.Disconnect()
+	})
+
+	if err := sqliteDB.Migrate(ctx); err != nil {
+		t.Fatalf("failed to migrate: %s", err)
+	}
+
+	err := sqliteDB.MigrateDown(ctx, 1)
+	if !errors.Is(err, db.ErrIrreversibleMigration) || err.Error() != "irreversible migration 2" {
+		t.Fatalf("got error %v, want irreversible migration 2", err)
+	}
+
+	status, err := sqliteDB.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatalf("failed to fetch migration status: %s", err)
+	}
+
+	if status.Version != 2 {
+		t.Errorf("got version %d after a refused down migration, want 2", status.Version)
+	}
+}
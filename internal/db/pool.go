@@ -0,0 +1,208 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const meterName = "myfacebook-dialog/internal/db"
+
+const (
+	poolRolePrimary = "primary"
+	poolRoleReplica = "replica"
+)
+
+// pool is a connection pool of the database watched by the pool monitor.
+type pool struct {
+	conn  *sqlx.DB
+	name  string
+	attrs metric.MeasurementOption
+	// last holds the stats of the previous check, the wait warning looks at waits since then.
+	last sql.DBStats
+}
+
+// configurePool applies the pool settings of the config, SQLite keeps a single connection.
+func (db *DB) configurePool(conn *sqlx.DB) {
+	if db.config.DriverName == DriverSQLite {
+		conn.SetMaxOpenConns(1)
+		conn.SetMaxIdleConns(1)
+
+		return
+	}
+
+	conn.SetMaxOpenConns(db.config.MaxOpenConnections)
+	conn.SetMaxIdleConns(db.config.MaxIdleConnections)
+	conn.SetConnMaxLifetime(db.config.ConnectionMaxLifetime)
+	conn.SetConnMaxIdleTime(db.config.ConnectionMaxIdleTime)
+}
+
+func (db *DB) pools() []*pool {
+	newPool := func(conn *sqlx.DB, role, host string, port int, dbName string) *pool {
+		return &pool{
+			conn: conn,
+			name: fmt.Sprintf("%s %q on %s:%d", role, dbName, host, port),
+			attrs: metric.WithAttributes(
+				attribute.String("db.role", role),
+				attribute.String("db.name", dbName),
+				attribute.String("server.address", host),
+				attribute.Int("server.port", port),
+			),
+		}
+	}
+
+	pools := []*pool{newPool(db.conn, poolRolePrimary, db.config.Host, db.config.Port, db.config.DBName)}
+	if db.config.DriverName == DriverSQLite {
+		pools[0].name = db.name()
+		pools[0].attrs = metric.WithAttributes(attribute.String("db.role", poolRolePrimary),
+			attribute.String("db.name", db.config.Path))
+	}
+
+	for _, r := range db.replicas {
+		pools = append(pools, newPool(r.conn, poolRoleReplica, r.config.Host, r.config.Port, r.config.DBName))
+	}
+
+	return pools
+}
+
+// startPoolMonitor exports the stats of the connection pools as metrics and warns about long waits for a connection
+// until stopPoolMonitor is called.
+func (db *DB) startPoolMonitor() error {
+	pools := db.pools()
+
+	registration, err := registerPoolMetrics(pools)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	db.stopPoolMonitor = func() {
+		cancel()
+		<-done
+
+		if err := registration.Unregister(); err != nil {
+			slog.Error(fmt.Sprintf("Failed to unregister connection pool metrics: %s", err))
+		}
+	}
+
+	go func() {
+		defer close(done)
+
+		db.watchPools(ctx, pools)
+	}()
+
+	return nil
+}
+
+func registerPoolMetrics(pools []*pool) (metric.Registration, error) { //nolint:ireturn
+	meter := otel.Meter(meterName)
+
+	inUse, err := meter.Int64ObservableGauge("db.pool.connections.in_use",
+		metric.WithDescription("Connections of the pool running queries"),
+		metric.WithUnit("{connection}"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pool in use gauge: %w", err)
+	}
+
+	idle, err := meter.Int64ObservableGauge("db.pool.connections.idle",
+		metric.WithDescription("Open connections of the pool waiting for queries"),
+		metric.WithUnit("{connection}"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pool idle gauge: %w", err)
+	}
+
+	maxOpen, err := meter.Int64ObservableGauge("db.pool.connections.max_open",
+		metric.WithDescription("Limit of open connections of the pool"),
+		metric.WithUnit("{connection}"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pool max open gauge: %w", err)
+	}
+
+	waitCount, err := meter.Int64ObservableCounter("db.pool.wait.count",
+		metric.WithDescription("Queries that waited for a free connection of the pool"),
+		metric.WithUnit("{wait}"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pool wait counter: %w", err)
+	}
+
+	waitDuration, err := meter.Float64ObservableCounter("db.pool.wait.duration",
+		metric.WithDescription("Total time queries waited for a free connection of the pool"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pool wait duration counter: %w", err)
+	}
+
+	registration, err := meter.RegisterCallback(func(_ context.Context, observer metric.Observer) error {
+		for _, p := range pools {
+			stats := p.conn.Stats()
+
+			observer.ObserveInt64(inUse, int64(stats.InUse), p.attrs)
+			observer.ObserveInt64(idle, int64(stats.Idle), p.attrs)
+			observer.ObserveInt64(maxOpen, int64(stats.MaxOpenConnections), p.attrs)
+			observer.ObserveInt64(waitCount, stats.WaitCount, p.attrs)
+			observer.ObserveFloat64(waitDuration, stats.WaitDuration.Seconds(), p.attrs)
+		}
+
+		return nil
+	}, inUse, idle, maxOpen, waitCount, waitDuration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register pool metrics callback: %w", err)
+	}
+
+	return registration, nil
+}
+
+// watchPools checks the pools every PoolCheckInterval until the context is done.
+func (db *DB) watchPools(ctx context.Context, pools []*pool) {
+	if db.config.PoolWaitWarningThreshold <= 0 || db.config.PoolCheckInterval <= 0 {
+		return
+	}
+
+	for _, p := range pools {
+		p.last = p.conn.Stats()
+	}
+
+	ticker := time.NewTicker(db.config.PoolCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, p := range pools {
+				db.checkPool(p)
+			}
+		}
+	}
+}
+
+// checkPool warns when queries since the previous check waited for a connection longer than the threshold on average,
+// the pool is too small for the load or queries hold connections for too long.
+func (db *DB) checkPool(p *pool) {
+	stats := p.conn.Stats()
+	waitCount := stats.WaitCount - p.last.WaitCount
+	waitDuration := stats.WaitDuration - p.last.WaitDuration
+	p.last = stats
+
+	if waitDuration <= 0 {
+		return
+	}
+
+	// The wait count grows when a wait starts and the duration when it ends, a wait can span two checks.
+	waitCount = max(waitCount, 1)
+
+	if averageWait := waitDuration / time.Duration(waitCount); averageWait > db.config.PoolWaitWarningThreshold {
+		slog.Warn(fmt.Sprintf("Connection pool of %s is saturated: %d queries waited %s for a connection on average, %d of %d connections in use",
+			p.name, waitCount, averageWait.Round(time.Millisecond), stats.InUse, stats.MaxOpenConnections))
+	}
+}
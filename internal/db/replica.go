@@ -0,0 +1,201 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type ReplicaConfig struct {
+	Host   string
+	Port   int
+	DBName string
+}
+
+type replica struct {
+	config  ReplicaConfig
+	conn    *sqlx.DB
+	healthy atomic.Bool
+}
+
+func (db *DB) connectReplicas() error {
+	for _, replicaConfig := range db.config.Replicas {
+		// Replicas are opened lazily, a replica that is down at startup must not stop the service.
+		conn, err := sqlx.Open(db.config.DriverName, db.dsn(replicaConfig.Host, replicaConfig.Port, replicaConfig.DBName))
+		if err != nil {
+			db.closeReplicas()
+
+			return fmt.Errorf("failed to open replica %q on %s:%d: %w", replicaConfig.DBName, replicaConfig.Host, replicaConfig.Port, err)
+		}
+
+		db.configurePool(conn)
+
+		db.replicas = append(db.replicas, &replica{
+			config: replicaConfig,
+			conn:   conn,
+		})
+	}
+
+	return nil
+}
+
+func (db *DB) closeReplicas() {
+	for _, r := range db.replicas {
+		if err := r.conn.Close(); err != nil {
+			slog.Error(fmt.Sprintf("Failed to close replica connection to %s:%d: %s", r.config.Host, r.config.Port, err))
+		}
+	}
+
+	db.replicas = nil
+}
+
+// GetReadConnection returns the next healthy replica in round-robin order, the primary when there is none.
+// Use it only for reads that tolerate replication lag.
+func (db *DB) GetReadConnection() *sqlx.DB {
+	replicaCount := uint64(len(db.replicas))
+
+	for i := uint64(0); i < replicaCount; i++ {
+		r := db.replicas[(db.nextReplica.Add(1)-1)%replicaCount]
+		if r.healthy.Load() {
+			return r.conn
+		}
+	}
+
+	return db.conn
+}
+
+// GetReadConnectionAfter returns the next healthy replica that replayed the WAL up to lsn, the primary when there is none.
+// An empty lsn means no position has to be observed. Inside a transaction of the database it returns the transaction.
+// Queries are read queries of GetRetryableConnection.
+func (db *DB) GetReadConnectionAfter(ctx context.Context, lsn string) Conn { //nolint:ireturn
+	if tx := db.txFromContext(ctx); tx != nil {
+		return db.readConn(ctx, tx)
+	}
+
+	return db.readConn(ctx, db.replicaConnectionAfter(ctx, lsn))
+}
+
+func (db *DB) replicaConnectionAfter(ctx context.Context, lsn string) *sqlx.DB {
+	if lsn == "" {
+		return db.GetReadConnection()
+	}
+
+	replicaCount := uint64(len(db.replicas))
+
+	for i := uint64(0); i < replicaCount; i++ {
+		r := db.replicas[(db.nextReplica.Add(1)-1)%replicaCount]
+		if !r.healthy.Load() {
+			continue
+		}
+
+		replayed, err := r.replayed(ctx, lsn)
+		if err != nil {
+			slog.Warn(fmt.Sprintf("Failed to check replay position of replica %s:%d: %s", r.config.Host, r.config.Port, err))
+
+			continue
+		}
+
+		if replayed {
+			return r.conn
+		}
+	}
+
+	return db.conn
+}
+
+// HasReplicas reports whether reads can be served by replicas.
+func (db *DB) HasReplicas() bool {
+	return len(db.replicas) > 0
+}
+
+// CurrentLSN returns the current WAL position of the primary,
+// a replica that replayed it observes every transaction committed before the call.
+func (db *DB) CurrentLSN(ctx context.Context) (string, error) {
+	var lsn string
+
+	if err := db.conn.GetContext(ctx, &lsn, `SELECT pg_current_wal_lsn()::text`); err != nil {
+		return "", fmt.Errorf("failed to fetch current wal lsn: %w", err)
+	}
+
+	return lsn, nil
+}
+
+// WatchReplicas checks replica health until the context is done.
+func (db *DB) WatchReplicas(ctx context.Context) {
+	if len(db.replicas) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(db.config.ReplicaCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			db.checkReplicas(ctx)
+		}
+	}
+}
+
+func (db *DB) checkReplicas(ctx context.Context) {
+	for _, r := range db.replicas {
+		healthy := true
+
+		lag, err := r.lag(ctx, db.config.ReplicaCheckInterval)
+		switch {
+		case err != nil:
+			healthy = false
+
+			slog.Warn(fmt.Sprintf("Replica %s:%d is unavailable: %s", r.config.Host, r.config.Port, err))
+		case lag > db.config.ReplicaMaxLag:
+			healthy = false
+
+			slog.Warn(fmt.Sprintf("Replica %s:%d lags %s behind the primary", r.config.Host, r.config.Port, lag))
+		}
+
+		if r.healthy.Swap(healthy) != healthy && healthy {
+			slog.Info(fmt.Sprintf("Replica %s:%d is healthy", r.config.Host, r.config.Port))
+		}
+	}
+}
+
+// lag returns how far the replica is behind the primary. A replica that replayed everything it received has no lag,
+// even if the primary had no writes for a while.
+func (r *replica) lag(ctx context.Context, timeout time.Duration) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var lagSeconds float64
+
+	sqlQuery := `SELECT CASE 
+			WHEN NOT pg_is_in_recovery() THEN -1
+			WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0 
+			ELSE COALESCE(extract(epoch FROM now() - pg_last_xact_replay_timestamp()), 0) 
+		END`
+
+	if err := r.conn.GetContext(ctx, &lagSeconds, sqlQuery); err != nil {
+		return 0, fmt.Errorf("failed to fetch replication lag: %w", err)
+	}
+
+	if lagSeconds < 0 {
+		return 0, errNotReplica
+	}
+
+	return time.Duration(lagSeconds * float64(time.Second)), nil
+}
+
+func (r *replica) replayed(ctx context.Context, lsn string) (bool, error) {
+	var replayed bool
+
+	if err := r.conn.GetContext(ctx, &replayed, `SELECT COALESCE(pg_last_wal_replay_lsn() >= $1::pg_lsn, false)`, lsn); err != nil {
+		return false, fmt.Errorf("failed to compare replay lsn: %w", err)
+	}
+
+	return replayed, nil
+}
@@ -0,0 +1,48 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite" // enable sqlite driver
+)
+
+// sqliteBusyTimeoutMilliseconds is how long a statement waits for a lock held by another process, such as a backup.
+const sqliteBusyTimeoutMilliseconds = 5000
+
+func init() {
+	// Named queries of the sqlite driver are bound to question marks.
+	sqlx.BindDriver(DriverSQLite, sqlx.QUESTION)
+}
+
+// connectSQLite opens the database file with a single connection: SQLite has a single writer anyway,
+// and statements that read before they write must not race another connection of the pool.
+func (db *DB) connectSQLite(ctx context.Context) error {
+	dsn := "file:" + db.config.Path + "?" + url.Values{
+		"_pragma": []string{
+			"foreign_keys(1)",
+			"journal_mode(WAL)",
+			fmt.Sprintf("busy_timeout(%d)", sqliteBusyTimeoutMilliseconds),
+		},
+		"_txlock": []string{"immediate"},
+	}.Encode()
+
+	conn, err := sqlx.ConnectContext(ctx, DriverSQLite, dsn)
+	if err != nil {
+		return fmt.Errorf("failed to open sqlite database %q: %w", db.config.Path, err)
+	}
+
+	db.configurePool(conn)
+
+	db.conn = conn
+
+	if err := db.startPoolMonitor(); err != nil {
+		_ = conn.Close()
+
+		return err
+	}
+
+	return nil
+}
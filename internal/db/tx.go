@@ -0,0 +1,141 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"myfacebook-dialog/internal/repository"
+)
+
+// Conn runs queries of repositories, it is the transaction of the context or the connection pool.
+type Conn interface {
+	sqlx.ExtContext
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error)
+}
+
+// txKey holds the transaction of a database in a context, transactions of several databases can share a context.
+type txKey struct {
+	db *DB
+}
+
+// primaryConn returns the transaction of the database started by TxManager.InTx for the context,
+// the connection pool of the primary outside of transactions.
+func (db *DB) primaryConn(ctx context.Context) Conn { //nolint:ireturn
+	if tx := db.txFromContext(ctx); tx != nil {
+		return tx
+	}
+
+	return db.conn
+}
+
+// InTx reports whether the context carries a transaction of the database.
+func (db *DB) InTx(ctx context.Context) bool {
+	return db.txFromContext(ctx) != nil
+}
+
+func (db *DB) txFromContext(ctx context.Context) *sqlx.Tx {
+	tx, _ := ctx.Value(txKey{db: db}).(*sqlx.Tx)
+
+	return tx
+}
+
+type TxConfig struct {
+	// Isolation is the isolation level of transactions, sql.LevelDefault keeps the one of the database.
+	// SQLite transactions are always serializable and ignore it.
+	Isolation sql.IsolationLevel
+	ReadOnly  bool
+	// MaxAttempts is how many times a transaction runs when it hits a serialization failure or a deadlock, at least once.
+	MaxAttempts int
+	// RetryDelay is the pause before the second attempt, it doubles with every next one and gets random jitter.
+	RetryDelay time.Duration
+}
+
+// TxManager runs functions in transactions of a database.
+type TxManager struct {
+	db     *DB
+	config TxConfig
+}
+
+func NewTxManager(db *DB, config TxConfig) *TxManager {
+	return &TxManager{
+		db:     db,
+		config: config,
+	}
+}
+
+// WithIsolation returns a manager that runs transactions with the isolation level and the other settings of m.
+func (m *TxManager) WithIsolation(isolation sql.IsolationLevel) *TxManager {
+	config := m.config
+	config.Isolation = isolation
+
+	return NewTxManager(m.db, config)
+}
+
+// InTx runs fn in a transaction, repositories of the database called with the context passed to fn take part in it.
+// The transaction commits when fn returns nil and rolls back otherwise. On a serialization failure or a deadlock
+// fn runs again in a new transaction, so it must not have side effects outside of the database.
+// Called inside a transaction of the same database, fn joins it and the outermost InTx commits and retries.
+// Reads of the transaction go to the primary, consistency tokens recorded inside it predate its commit.
+func (m *TxManager) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if m.db.InTx(ctx) {
+		return fn(ctx)
+	}
+
+	maxAttempts := max(m.config.MaxAttempts, 1)
+
+	for attempt := 1; ; attempt++ {
+		err := m.runTx(ctx, fn)
+		if err == nil || attempt >= maxAttempts || !errors.Is(classifyError(err), repository.ErrConflict) {
+			return err
+		}
+
+		delay := backoff(m.config.RetryDelay, attempt)
+
+		slog.Warn(fmt.Sprintf("Transaction attempt %d of %d failed, retrying in %s: %s", attempt, maxAttempts, delay, err))
+
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			return fmt.Errorf("transaction retry canceled: %w", errors.Join(sleepErr, err))
+		}
+	}
+}
+
+func (m *TxManager) runTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	txOptions := &sql.TxOptions{Isolation: m.config.Isolation, ReadOnly: m.config.ReadOnly}
+	if m.db.config.DriverName == DriverSQLite {
+		txOptions.Isolation = sql.LevelDefault
+	}
+
+	tx, err := m.db.conn.BeginTxx(ctx, txOptions)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", classifyError(err))
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{db: m.db}, tx)); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			slog.Error(fmt.Sprintf("Failed to roll back transaction: %s", rollbackErr))
+		}
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", classifyError(err))
+	}
+
+	return nil
+}
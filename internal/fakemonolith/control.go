@@ -0,0 +1,91 @@
+package fakemonolith
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ControlPathPrefix prefixes the endpoints that change the server while it runs, it does not clash with monolith paths.
+const ControlPathPrefix = "/_fake/"
+
+const (
+	controlPathUsers  = ControlPathPrefix + "users"
+	controlPathFaults = ControlPathPrefix + "faults"
+)
+
+type faultRequest struct {
+	// Key limits the fault to requests for the user id or the token, empty applies it to every request.
+	Key                 string `json:"key"`
+	LatencyMilliseconds int    `json:"latency_ms"`
+	StatusCode          int    `json:"status_code"`
+	Drop                bool   `json:"drop"`
+	Times               int    `json:"times"`
+}
+
+// Handler serves both the monolith endpoints and the control endpoints.
+func (s *Server) Handler() http.Handler {
+	controlHandler := s.ControlHandler()
+
+	return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		if strings.HasPrefix(request.URL.Path, ControlPathPrefix) {
+			controlHandler.ServeHTTP(responseWriter, request)
+
+			return
+		}
+
+		s.ServeHTTP(responseWriter, request)
+	})
+}
+
+// ControlHandler serves the control endpoints:
+//
+//	POST   /_fake/users  {"id": "...", "tokens": ["..."]} adds a user
+//	PUT    /_fake/faults {"key": "...", "latency_ms": 0, "status_code": 0, "drop": false, "times": 0} sets a fault
+//	DELETE /_fake/faults clears every fault
+func (s *Server) ControlHandler() http.Handler {
+	return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		switch {
+		case request.URL.Path == controlPathUsers && request.Method == http.MethodPost:
+			var user User
+
+			if err := json.NewDecoder(request.Body).Decode(&user); err != nil || user.ID == "" {
+				responseWriter.WriteHeader(http.StatusBadRequest)
+
+				return
+			}
+
+			s.AddUser(user.ID, user.Tokens...)
+		case request.URL.Path == controlPathFaults && request.Method == http.MethodPut:
+			var faultReq faultRequest
+
+			if err := json.NewDecoder(request.Body).Decode(&faultReq); err != nil {
+				responseWriter.WriteHeader(http.StatusBadRequest)
+
+				return
+			}
+
+			fault := Fault{
+				Latency:    time.Duration(faultReq.LatencyMilliseconds) * time.Millisecond,
+				StatusCode: faultReq.StatusCode,
+				Drop:       faultReq.Drop,
+				Times:      faultReq.Times,
+			}
+
+			if faultReq.Key == "" {
+				s.SetFault(fault)
+			} else {
+				s.SetKeyFault(faultReq.Key, fault)
+			}
+		case request.URL.Path == controlPathFaults && request.Method == http.MethodDelete:
+			s.ClearFaults()
+		default:
+			responseWriter.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		responseWriter.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,103 @@
+package fakemonolith
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"myfacebook-dialog/internal/myfacebookapiclient"
+	"myfacebook-dialog/internal/repository"
+)
+
+// The paths match the legacy dialog endpoints myfacebookapiclient.Client calls.
+const (
+	pathLegacyDialog             = "/int/legacy/dialog/"
+	pathSendLegacyDialogMessage  = pathLegacyDialog + "send"
+	pathListLegacyDialogMessages = pathLegacyDialog + "list"
+)
+
+type sendLegacyDialogMessageRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Text string `json:"text"`
+}
+
+// serveLegacyDialog stores and lists legacy dialog messages, faults for the sender id apply to both.
+func (s *Server) serveLegacyDialog(responseWriter http.ResponseWriter, request *http.Request) {
+	switch {
+	case request.URL.Path == pathSendLegacyDialogMessage && request.Method == http.MethodPost:
+		var sendReq sendLegacyDialogMessageRequest
+
+		if err := json.NewDecoder(request.Body).Decode(&sendReq); err != nil || sendReq.From == "" || sendReq.To == "" || sendReq.Text == "" {
+			responseWriter.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		if s.applyFault(responseWriter, request, sendReq.From) {
+			return
+		}
+
+		s.AddLegacyDialogMessage(sendReq.From, sendReq.To, sendReq.Text)
+
+		responseWriter.WriteHeader(http.StatusOK)
+	case request.URL.Path == pathListLegacyDialogMessages && request.Method == http.MethodGet:
+		userID, peerID := request.URL.Query().Get("from"), request.URL.Query().Get("to")
+		if userID == "" || peerID == "" {
+			responseWriter.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		if s.applyFault(responseWriter, request, userID) {
+			return
+		}
+
+		responseWriter.Header().Set("Content-Type", "application/json; charset=utf-8")
+		responseWriter.WriteHeader(http.StatusOK)
+
+		if err := json.NewEncoder(responseWriter).Encode(s.LegacyDialogMessages(userID, peerID)); err != nil {
+			slog.Error(fmt.Sprintf("Failed to write fake monolith response: %s", err))
+		}
+	default:
+		responseWriter.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// AddLegacyDialogMessage stores a message in the legacy dialog of the users as if it was sent to the monolith.
+func (s *Server) AddLegacyDialogMessage(senderID, receiverID, text string) myfacebookapiclient.DialogMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastDialogMessageID++
+
+	dialogMessage := myfacebookapiclient.DialogMessage{
+		ID:        strconv.Itoa(s.lastDialogMessageID),
+		From:      senderID,
+		To:        receiverID,
+		Text:      text,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	dialogKey := legacyDialogKey(senderID, receiverID)
+	s.dialogs[dialogKey] = append(s.dialogs[dialogKey], dialogMessage)
+
+	return dialogMessage
+}
+
+// LegacyDialogMessages returns the legacy dialog of the users, oldest message first.
+func (s *Server) LegacyDialogMessages(userID, peerID string) []myfacebookapiclient.DialogMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]myfacebookapiclient.DialogMessage{}, s.dialogs[legacyDialogKey(userID, peerID)]...)
+}
+
+func legacyDialogKey(userID, peerID string) string {
+	firstUserID, secondUserID := repository.DialogParticipants(userID, peerID)
+
+	return firstUserID + ":" + secondUserID
+}
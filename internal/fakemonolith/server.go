@@ -0,0 +1,267 @@
+// Package fakemonolith serves the internal user and legacy dialog endpoints of the MyFacebook monolith from memory,
+// so the dialog service runs locally and in tests without the monolith. Faults make it answer slowly, with errors or not at all.
+// In tests serve it with httptest.NewServer and pass the server URL to apiclient.New as the base URL.
+package fakemonolith
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"myfacebook-dialog/internal/myfacebookapiclient"
+)
+
+// The paths match the endpoints myfacebookapiclient.Client calls.
+const (
+	pathFindUserByToken = "/int/user/findByToken/"
+	pathGetUserByID     = "/int/user/"
+)
+
+// Fault changes how requests are answered. The zero Fault answers normally.
+type Fault struct {
+	Latency time.Duration
+	// StatusCode replaces the answer with an empty response, such as 500 for an error or 404 for a known user.
+	StatusCode int
+	// Drop closes the connection without an answer, the client sees a transport error.
+	// http.Transport retries a GET dropped on a reused connection once, so a single dropped request may go unnoticed.
+	Drop bool
+	// Times limits the fault to that many requests, zero keeps it until faults are cleared.
+	Times int
+}
+
+// User is a user of the seed, found by id and by every token.
+type User struct {
+	ID     string   `json:"id"`
+	Tokens []string `json:"tokens"`
+}
+
+// Server is an http.Handler safe for concurrent use, users and faults can be changed while it serves.
+type Server struct {
+	mu     sync.Mutex
+	users  map[string]myfacebookapiclient.User
+	tokens map[string]string
+	// dialogs holds the legacy dialogs by their participants, lastDialogMessageID numbers their messages.
+	dialogs             map[string][]myfacebookapiclient.DialogMessage
+	lastDialogMessageID int
+	// fault applies to every request, keyFaults to requests for a user id or a token.
+	fault     *Fault
+	keyFaults map[string]*Fault
+}
+
+func New() *Server {
+	return &Server{
+		users:     make(map[string]myfacebookapiclient.User),
+		tokens:    make(map[string]string),
+		dialogs:   make(map[string][]myfacebookapiclient.DialogMessage),
+		keyFaults: make(map[string]*Fault),
+	}
+}
+
+// Seed adds every user of the seed.
+func (s *Server) Seed(users []User) {
+	for _, user := range users {
+		s.AddUser(user.ID, user.Tokens...)
+	}
+}
+
+// AddUser stores the user, the user is then found by id and by every given token.
+func (s *Server) AddUser(userID string, tokens ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users[userID] = myfacebookapiclient.User{ID: userID}
+
+	for _, token := range tokens {
+		s.tokens[token] = userID
+	}
+}
+
+// SetFault applies the fault to every request, a key fault of the request takes precedence.
+func (s *Server) SetFault(fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fault = &fault
+}
+
+// SetKeyFault applies the fault to requests for the user id or the token.
+func (s *Server) SetKeyFault(key string, fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keyFaults[key] = &fault
+}
+
+// ClearFaults makes the server answer every request normally.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fault = nil
+	s.keyFaults = make(map[string]*Fault)
+}
+
+func (s *Server) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	if strings.HasPrefix(request.URL.Path, pathLegacyDialog) {
+		s.serveLegacyDialog(responseWriter, request)
+
+		return
+	}
+
+	if request.Method != http.MethodGet {
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	var (
+		key    string
+		lookup func(key string) (myfacebookapiclient.User, bool)
+	)
+
+	switch {
+	case strings.HasPrefix(request.URL.Path, pathFindUserByToken):
+		key, lookup = strings.TrimPrefix(request.URL.Path, pathFindUserByToken), s.userByToken
+	case strings.HasPrefix(request.URL.Path, pathGetUserByID):
+		key, lookup = strings.TrimPrefix(request.URL.Path, pathGetUserByID), s.userByID
+	}
+
+	if key == "" || strings.Contains(key, "/") {
+		responseWriter.WriteHeader(http.StatusNotFound)
+
+		return
+	}
+
+	if s.applyFault(responseWriter, request, key) {
+		return
+	}
+
+	user, ok := lookup(key)
+	if !ok {
+		responseWriter.WriteHeader(http.StatusNotFound)
+
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json; charset=utf-8")
+	responseWriter.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(responseWriter).Encode(user); err != nil {
+		slog.Error(fmt.Sprintf("Failed to write fake monolith response: %s", err))
+	}
+}
+
+// applyFault applies the fault for the request key and reports whether it answered the request.
+func (s *Server) applyFault(responseWriter http.ResponseWriter, request *http.Request, key string) bool {
+	fault, ok := s.takeFault(key)
+	if !ok {
+		return false
+	}
+
+	if !wait(request.Context(), fault.Latency) {
+		return true
+	}
+
+	if fault.Drop {
+		dropConnection(responseWriter)
+
+		return true
+	}
+
+	if fault.StatusCode != 0 {
+		responseWriter.WriteHeader(fault.StatusCode)
+
+		return true
+	}
+
+	return false
+}
+
+// takeFault returns the fault for the request key and counts the request against its Times.
+func (s *Server) takeFault(key string) (Fault, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fault, ok := s.keyFaults[key]
+	if !ok {
+		fault = s.fault
+	}
+
+	if fault == nil {
+		return Fault{}, false
+	}
+
+	if fault.Times > 0 {
+		fault.Times--
+
+		if fault.Times == 0 {
+			if ok {
+				delete(s.keyFaults, key)
+			} else {
+				s.fault = nil
+			}
+		}
+	}
+
+	return *fault, true
+}
+
+func (s *Server) userByToken(token string) (myfacebookapiclient.User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	userID, ok := s.tokens[token]
+	if !ok {
+		return myfacebookapiclient.User{}, false
+	}
+
+	user, ok := s.users[userID]
+
+	return user, ok
+}
+
+func (s *Server) userByID(userID string) (myfacebookapiclient.User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+
+	return user, ok
+}
+
+// wait sleeps for the latency and reports false when the client gave up earlier.
+func wait(ctx context.Context, latency time.Duration) bool {
+	if latency <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(latency)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func dropConnection(responseWriter http.ResponseWriter) {
+	hijacker, ok := responseWriter.(http.Hijacker)
+	if !ok {
+		// The connection cannot be taken over, aborting the handler makes the server close it.
+		panic(http.ErrAbortHandler)
+	}
+
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+
+	_ = conn.Close()
+}
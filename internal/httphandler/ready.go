@@ -0,0 +1,42 @@
+package httphandler
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Dependency is a service the application cannot serve requests without, implemented by db.DB.
+type Dependency interface {
+	Ready() bool
+}
+
+// Ready answers 503 while any of the dependencies is unavailable, so that the instance is taken out of balancing
+// instead of being restarted. Health keeps answering OK meanwhile.
+type Ready struct {
+	Dependencies []Dependency
+}
+
+func (h *Ready) Handle(responseWriter http.ResponseWriter, _ *http.Request) error {
+	status, statusCode := "OK", http.StatusOK
+
+	for _, dependency := range h.Dependencies {
+		if !dependency.Ready() {
+			status, statusCode = "NOT_READY", http.StatusServiceUnavailable
+
+			break
+		}
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	responseWriter.WriteHeader(statusCode)
+
+	err := json.NewEncoder(responseWriter).Encode(healthResponse{
+		Status: status,
+	})
+	if err != nil {
+		http.Error(responseWriter, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+
+	return nil
+}
@@ -41,7 +41,7 @@ func (s *Server) Start() <-chan error {
 	go func() {
 		err := s.httpServer.ListenAndServe()
 		if err != nil && !errors.Is(err, http.ErrServerClosed) {
-			slog.Error("HTTP server ListenAndServe error: %s", err)
+			slog.Error(fmt.Sprintf("HTTP server ListenAndServe error: %s", err))
 
 			errCh <- fmt.Errorf("http listen and server error: %w", err)
 		}
@@ -0,0 +1,125 @@
+// Package idgen generates unique identifiers that sort by creation time.
+package idgen
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrNotUUIDv7 = errors.New("not a UUIDv7")
+
+// counterBits is the size of the rand_a field of a UUIDv7, it holds a counter for ids of the same millisecond.
+const (
+	counterBits = 12
+	maxCounter  = 1<<counterBits - 1
+)
+
+// UUIDv7 generates version 7 UUIDs of RFC 9562: a millisecond unix timestamp followed by random bits.
+// Ids of one generator are strictly increasing, even within a millisecond or when the clock goes back.
+type UUIDv7 struct {
+	mu         sync.Mutex
+	lastMillis int64
+	counter    uint16
+	now        func() time.Time
+}
+
+func NewUUIDv7() *UUIDv7 {
+	return &UUIDv7{
+		now: time.Now,
+	}
+}
+
+func (g *UUIDv7) New() (string, error) {
+	var random [10]byte
+	if _, err := rand.Read(random[:]); err != nil {
+		return "", fmt.Errorf("failed to read random bytes: %w", err)
+	}
+
+	millis, counter := g.next(binary.BigEndian.Uint16(random[:2]))
+
+	return build(millis, counter, random), nil
+}
+
+// NewAt returns an id with the timestamp of t for records created before they got an id, such as imported ones.
+// Unlike New its ids are not ordered within a millisecond.
+func (g *UUIDv7) NewAt(t time.Time) (string, error) {
+	var random [10]byte
+	if _, err := rand.Read(random[:]); err != nil {
+		return "", fmt.Errorf("failed to read random bytes: %w", err)
+	}
+
+	return build(t.UnixMilli(), binary.BigEndian.Uint16(random[:2])&maxCounter, random), nil
+}
+
+func build(millis int64, counter uint16, random [10]byte) string {
+	var id [16]byte
+
+	binary.BigEndian.PutUint64(id[:8], uint64(millis)<<16)
+	binary.BigEndian.PutUint16(id[6:8], 0x7000|counter)
+	copy(id[8:], random[2:])
+	id[8] = 0x80 | id[8]&0x3f
+
+	return format(id)
+}
+
+// next returns the timestamp and the counter of the next id. A new millisecond starts the counter at a random value
+// below the middle of its range, leaving room for ids of the same millisecond. An exhausted counter moves into the next millisecond.
+func (g *UUIDv7) next(random uint16) (int64, uint16) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	millis := g.now().UnixMilli()
+
+	switch {
+	case millis > g.lastMillis:
+		g.lastMillis = millis
+		g.counter = random & (maxCounter >> 1)
+	case g.counter < maxCounter:
+		g.counter++
+	default:
+		g.lastMillis++
+		g.counter = 0
+	}
+
+	return g.lastMillis, g.counter
+}
+
+func format(id [16]byte) string {
+	var buf [36]byte
+
+	hex.Encode(buf[0:8], id[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], id[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], id[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], id[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:36], id[10:16])
+
+	return string(buf[:])
+}
+
+// Time returns the creation time encoded in a UUIDv7 with millisecond precision.
+func Time(id string) (time.Time, error) {
+	if len(id) != 36 || id[14] != '7' {
+		return time.Time{}, fmt.Errorf("%w: %q", ErrNotUUIDv7, id)
+	}
+
+	var millis [8]byte
+
+	if _, err := hex.Decode(millis[2:6], []byte(id[0:8])); err != nil {
+		return time.Time{}, fmt.Errorf("%w: %q", ErrNotUUIDv7, id)
+	}
+
+	if _, err := hex.Decode(millis[6:8], []byte(id[9:13])); err != nil {
+		return time.Time{}, fmt.Errorf("%w: %q", ErrNotUUIDv7, id)
+	}
+
+	return time.UnixMilli(int64(binary.BigEndian.Uint64(millis[:]))), nil
+}
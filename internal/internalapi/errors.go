@@ -1,13 +1,18 @@
 package internalapi
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+
+	"myfacebook-dialog/internal/repository"
 )
 
 const (
 	errorTypeInvalidRequest      = "invalid_request"
 	errorTypeInternalServerError = "server_error"
+	errorTypeServiceUnavailable  = "service_unavailable"
+	errorTypeConflict            = "conflict"
 
 	ErrorLogLevelInfo    = "info"
 	ErrorLogLevelWarning = "warning"
@@ -77,3 +82,39 @@ func NewServerError(err error) *Error {
 		logLevel:    ErrorLogLevelError,
 	}
 }
+
+// NewRepositoryError reports a failed repository call. Repository errors the caller can act on keep their meaning:
+// a record violating storage constraints is an invalid request, and a storage outage asks the caller to retry.
+// Other errors are server errors.
+func NewRepositoryError(err error) *Error {
+	switch {
+	case errors.Is(err, repository.ErrConstraintViolation):
+		return NewInvalidRequestError("request does not fit storage constraints", err)
+	case errors.Is(err, repository.ErrAlreadyExists):
+		return NewConflictError(err)
+	case errors.Is(err, repository.ErrUnavailable), errors.Is(err, repository.ErrTimeout), errors.Is(err, repository.ErrConflict):
+		return NewServiceUnavailableError(err)
+	}
+
+	return NewServerError(err)
+}
+
+func NewServiceUnavailableError(err error) *Error {
+	return &Error{
+		statusCode:  http.StatusServiceUnavailable,
+		description: "service temporarily unavailable",
+		typ:         errorTypeServiceUnavailable,
+		err:         err,
+		logLevel:    ErrorLogLevelWarning,
+	}
+}
+
+func NewConflictError(err error) *Error {
+	return &Error{
+		statusCode:  http.StatusConflict,
+		description: "record already exists",
+		typ:         errorTypeConflict,
+		err:         err,
+		logLevel:    ErrorLogLevelInfo,
+	}
+}
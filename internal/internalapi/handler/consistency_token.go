@@ -0,0 +1,19 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"myfacebook-dialog/internal/consistency"
+	"myfacebook-dialog/internal/internalapi"
+)
+
+// withConsistencyToken maps a malformed consistency token of the request to an invalid request error.
+func withConsistencyToken(ctx context.Context, request *http.Request) (context.Context, error) {
+	ctx, err := consistency.WithRequestToken(ctx, request)
+	if err != nil {
+		return nil, internalapi.NewInvalidRequestErrorInvalidParameter(consistency.TokenHeader, err)
+	}
+
+	return ctx, nil
+}
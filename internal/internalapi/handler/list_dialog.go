@@ -5,51 +5,87 @@ import (
 	"fmt"
 	"net/http"
 	"regexp"
+	"strconv"
+	"time"
 
 	"myfacebook-dialog/internal/internalapi"
 	"myfacebook-dialog/internal/repository"
 )
 
+const maxListDialogLimit = 100
+
 type ListDialog struct {
 	DialogRepository repository.DialogRepository
 }
 
 type dialogMessage struct {
-	ID   string `json:"id"`
-	From string `json:"from"`
-	To   string `json:"to"`
-	Text string `json:"text"`
+	ID            string         `json:"id"`
+	LegacyID      *string        `json:"legacy_id,omitempty"`
+	From          string         `json:"from"`
+	To            string         `json:"to"`
+	Text          string         `json:"text"`
+	Seq           int64          `json:"seq"`
+	CreatedAt     time.Time      `json:"created_at"`
+	ForwardedFrom *forwardedFrom `json:"forwarded_from,omitempty"`
+}
+
+type forwardedFrom struct {
+	UserID    string `json:"user_id"`
+	MessageID string `json:"message_id"`
 }
 
 type listDialogRequest struct {
-	From string
-	To   string
+	From     string
+	To       string
+	AfterSeq int64
+	Limit    int
 }
 
 func (h *ListDialog) Handle(responseWriter http.ResponseWriter, request *http.Request) error {
 	ctx := request.Context()
 
-	listDialogReq := h.getListDialogRequest(request)
+	listDialogReq, err := h.getListDialogRequest(request)
+	if err != nil {
+		return err
+	}
+
+	err = h.validateListDialogRequest(listDialogReq)
+	if err != nil {
+		return err
+	}
 
-	err := h.validateListDialogRequest(listDialogReq)
+	ctx, err = withConsistencyToken(ctx, request)
 	if err != nil {
 		return err
 	}
 
-	dialogMessages, err := h.DialogRepository.GetDialogMessagesBySenderIDAndReceiverID(ctx, listDialogReq.From, listDialogReq.To)
+	dialogMessages, err := h.DialogRepository.GetDialogMessagesAfterSeq(ctx, listDialogReq.From, listDialogReq.To,
+		listDialogReq.AfterSeq, listDialogReq.Limit)
 	if err != nil {
-		return internalapi.NewServerError(fmt.Errorf("list dialog handler, failed to fetch dialoag messages from repository: %w", err))
+		return internalapi.NewRepositoryError(fmt.Errorf("list dialog handler, failed to fetch dialoag messages from repository: %w", err))
 	}
 
 	listDialogResponse := make([]dialogMessage, 0, len(dialogMessages))
 
 	for _, dialogMsg := range dialogMessages {
-		listDialogResponse = append(listDialogResponse, dialogMessage{
-			ID:   dialogMsg.ID,
-			From: dialogMsg.From,
-			To:   dialogMsg.To,
-			Text: dialogMsg.Text,
-		})
+		message := dialogMessage{
+			ID:        dialogMsg.ID,
+			LegacyID:  dialogMsg.LegacyID,
+			From:      dialogMsg.From,
+			To:        dialogMsg.To,
+			Text:      dialogMsg.Text,
+			Seq:       dialogMsg.Seq,
+			CreatedAt: dialogMsg.CreatedAt.UTC(),
+		}
+
+		if dialogMsg.ForwardedFromUserID != nil && dialogMsg.ForwardedFromMessageID != nil {
+			message.ForwardedFrom = &forwardedFrom{
+				UserID:    *dialogMsg.ForwardedFromUserID,
+				MessageID: *dialogMsg.ForwardedFromMessageID,
+			}
+		}
+
+		listDialogResponse = append(listDialogResponse, message)
 	}
 
 	responseWriter.Header().Set("Content-Type", "application/json; utf-8")
@@ -63,11 +99,33 @@ func (h *ListDialog) Handle(responseWriter http.ResponseWriter, request *http.Re
 	return nil
 }
 
-func (h *ListDialog) getListDialogRequest(request *http.Request) listDialogRequest {
-	return listDialogRequest{
-		From: request.URL.Query().Get("from"),
-		To:   request.URL.Query().Get("to"),
+func (h *ListDialog) getListDialogRequest(request *http.Request) (listDialogRequest, error) {
+	query := request.URL.Query()
+
+	listDialogReq := listDialogRequest{
+		From: query.Get("from"),
+		To:   query.Get("to"),
+	}
+
+	if query.Has("after_seq") {
+		afterSeq, err := strconv.ParseInt(query.Get("after_seq"), 10, 64)
+		if err != nil || afterSeq < 0 {
+			return listDialogRequest{}, internalapi.NewInvalidRequestErrorInvalidParameter("after_seq", err)
+		}
+
+		listDialogReq.AfterSeq = afterSeq
 	}
+
+	if query.Has("limit") {
+		limit, err := strconv.Atoi(query.Get("limit"))
+		if err != nil || limit < 1 || limit > maxListDialogLimit {
+			return listDialogRequest{}, internalapi.NewInvalidRequestErrorInvalidParameter("limit", err)
+		}
+
+		listDialogReq.Limit = limit
+	}
+
+	return listDialogReq, nil
 }
 
 func (h *ListDialog) validateListDialogRequest(listDialogReq listDialogRequest) error {
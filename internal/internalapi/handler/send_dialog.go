@@ -5,16 +5,20 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"regexp"
+	"time"
 
+	"myfacebook-dialog/internal/consistency"
 	"myfacebook-dialog/internal/internalapi"
 	"myfacebook-dialog/internal/repository"
 )
 
 type SendDialog struct {
-	DialogRepository repository.DialogRepository
-	UserRepository   repository.UserRepository
+	DialogRepository      repository.DialogRepository
+	DialogDraftRepository repository.DialogDraftRepository
+	UserRepository        repository.UserRepository
 }
 
 type sendDialogRequest struct {
@@ -44,11 +48,22 @@ func (h *SendDialog) Handle(responseWriter http.ResponseWriter, request *http.Re
 		Text: sendDialogReq.Text,
 	}
 
-	err = h.DialogRepository.Add(ctx, dialogMessage)
+	sentAt := time.Now()
+
+	ctx, consistencyRecorder := repository.WithConsistencyRecorder(ctx)
+
+	_, err = h.DialogRepository.Add(ctx, dialogMessage)
+	if err != nil {
+		return internalapi.NewRepositoryError(fmt.Errorf("send dialog handler, failed to add dialog message to repository: %w", err))
+	}
+
+	// The message is stored already, a draft left behind must not turn the request into a failure.
+	err = h.DialogDraftRepository.Delete(ctx, dialogMessage.From, dialogMessage.To, sentAt)
 	if err != nil {
-		return internalapi.NewServerError(fmt.Errorf("send dialog handler, failed to add dialog message to repository: %w", err))
+		slog.Warn(fmt.Sprintf("send dialog handler, failed to delete dialog draft: %s", err))
 	}
 
+	consistency.SetToken(responseWriter, consistencyRecorder)
 	responseWriter.Header().Set("Content-Type", "application/json; utf-8")
 	responseWriter.WriteHeader(http.StatusOK)
 
@@ -75,7 +90,7 @@ func (h *SendDialog) validateSendDialogRequest(ctx context.Context, sendDialogRe
 			return internalapi.NewInvalidRequestErrorInvalidParameter("from", err)
 		}
 
-		return internalapi.NewServerError(err)
+		return internalapi.NewRepositoryError(err)
 	}
 
 	if sendDialogReq.To == "" {
@@ -92,7 +107,7 @@ func (h *SendDialog) validateSendDialogRequest(ctx context.Context, sendDialogRe
 			return internalapi.NewInvalidRequestErrorInvalidParameter("to", err)
 		}
 
-		return internalapi.NewServerError(err)
+		return internalapi.NewRepositoryError(err)
 	}
 
 	return nil
@@ -0,0 +1,93 @@
+package legacyimport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Checkpoint is the progress of an import saved after every committed batch, an interrupted import resumes from it.
+type Checkpoint struct {
+	// Offset is the byte offset of the export after the last row of the committed batches.
+	Offset int64 `json:"offset"`
+	// Rows counts the rows before the offset.
+	Rows     int            `json:"rows"`
+	Imported int            `json:"imported"`
+	Skipped  int            `json:"skipped"`
+	Rejected map[string]int `json:"rejected"`
+	SavedAt  time.Time      `json:"saved_at"`
+}
+
+// RejectedCount is the number of rejected rows of all reasons.
+func (c Checkpoint) RejectedCount() int {
+	var count int
+	for _, reasonCount := range c.Rejected {
+		count += reasonCount
+	}
+
+	return count
+}
+
+// loadCheckpoint returns the saved checkpoint, the start of the export when none was saved.
+func loadCheckpoint(path string) (Checkpoint, bool, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Checkpoint{Rejected: make(map[string]int)}, false, nil
+	}
+
+	if err != nil {
+		return Checkpoint{}, false, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return Checkpoint{}, false, fmt.Errorf("failed to decode checkpoint %s: %w", path, err)
+	}
+
+	if checkpoint.Rejected == nil {
+		checkpoint.Rejected = make(map[string]int)
+	}
+
+	return checkpoint, true, nil
+}
+
+// saveCheckpoint replaces the checkpoint file through a rename, a crash leaves either the old or the new checkpoint.
+func saveCheckpoint(path string, checkpoint Checkpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create checkpoint file: %w", err)
+	}
+
+	defer os.Remove(tmpFile.Name()) //nolint:errcheck
+
+	if _, err := tmpFile.Write(data); err != nil {
+		_ = tmpFile.Close()
+
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+
+	if err := tmpFile.Sync(); err != nil {
+		_ = tmpFile.Close()
+
+		return fmt.Errorf("failed to sync checkpoint: %w", err)
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to close checkpoint file: %w", err)
+	}
+
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace checkpoint: %w", err)
+	}
+
+	return nil
+}
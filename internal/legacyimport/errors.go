@@ -0,0 +1,20 @@
+package legacyimport
+
+import "errors"
+
+var (
+	ErrUnknownFormat = errors.New("unknown export format, expected jsonl or csv")
+	ErrMissingColumn = errors.New("csv header lacks a column")
+	ErrFileChanged   = errors.New("export file is shorter than the checkpoint offset, it changed since the checkpoint")
+)
+
+// Reasons of rejected rows, the summary counts rejections by them.
+var (
+	ErrMalformedRow          = errors.New("malformed row")
+	ErrInvalidLegacyID       = errors.New("invalid legacy_id")
+	ErrInvalidSenderID       = errors.New("invalid sender_id")
+	ErrInvalidReceiverID     = errors.New("invalid receiver_id")
+	ErrSameSenderAndReceiver = errors.New("sender_id equals receiver_id")
+	ErrInvalidText           = errors.New("invalid text")
+	ErrInvalidCreatedAt      = errors.New("invalid created_at")
+)
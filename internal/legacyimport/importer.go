@@ -0,0 +1,283 @@
+// Package legacyimport loads messages exported from the monolith into the dialogs storage.
+package legacyimport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"myfacebook-dialog/internal/idgen"
+	"myfacebook-dialog/internal/repository"
+)
+
+const rejectedFilePerm = 0o644
+
+// rejectionReasons are the reasons the summary counts rejections by, other errors count as themselves.
+var rejectionReasons = []error{
+	ErrMalformedRow,
+	ErrInvalidLegacyID,
+	ErrInvalidSenderID,
+	ErrInvalidReceiverID,
+	ErrSameSenderAndReceiver,
+	ErrInvalidText,
+	ErrInvalidCreatedAt,
+	repository.ErrLegacyIDTaken,
+	repository.ErrDialogHasNewerMessages,
+}
+
+type Config struct {
+	// Path is the export file, Format is FormatJSONL or FormatCSV.
+	Path   string
+	Format string
+	// CheckpointPath is the file the progress is saved to, an import with a checkpoint resumes from it.
+	CheckpointPath string
+	// RejectedPath is the file rejected rows are appended to as JSON lines with the reason, a fixed file
+	// can be imported as a JSONL export. A row may be written twice when the import stops right after its batch.
+	RejectedPath     string
+	BatchSize        int
+	ProgressInterval time.Duration
+}
+
+// Importer validates rows of an export and imports them in batches, every batch commits on its own.
+type Importer struct {
+	importRepository repository.DialogImportRepository
+	idGenerator      *idgen.UUIDv7
+	config           Config
+	now              func() time.Time
+}
+
+func NewImporter(importRepository repository.DialogImportRepository, config Config) *Importer {
+	return &Importer{
+		importRepository: importRepository,
+		idGenerator:      idgen.NewUUIDv7(),
+		config:           config,
+		now:              time.Now,
+	}
+}
+
+// rejectedRow is a line of the rejected file.
+type rejectedRow struct {
+	Row
+	Reason string `json:"reason"`
+	Error  string `json:"error"`
+}
+
+// batch holds the rows read since the last checkpoint.
+type batch struct {
+	rows           int
+	offset         int64
+	dialogMessages []repository.DialogMessage
+	// rowsByID maps ids given to the messages back to their rows.
+	rowsByID map[string]Row
+	rejected []rejectedRow
+}
+
+// Import imports the export from the checkpoint on and returns the totals of the whole import, including the runs
+// before the checkpoint. The checkpoint is kept after the end, running the import again does nothing.
+func (im *Importer) Import(ctx context.Context) (Checkpoint, error) {
+	checkpoint, resumed, err := loadCheckpoint(im.config.CheckpointPath)
+	if err != nil {
+		return Checkpoint{}, err
+	}
+
+	file, err := os.Open(im.config.Path)
+	if err != nil {
+		return checkpoint, fmt.Errorf("failed to open export: %w", err)
+	}
+
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return checkpoint, fmt.Errorf("failed to stat export: %w", err)
+	}
+
+	if checkpoint.Offset > fileInfo.Size() {
+		return checkpoint, fmt.Errorf("%w: offset %d, size %d", ErrFileChanged, checkpoint.Offset, fileInfo.Size())
+	}
+
+	reader, err := NewReader(im.config.Format, file, checkpoint.Offset, checkpoint.Rows)
+	if err != nil {
+		return checkpoint, err
+	}
+
+	rejectedFlags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if !resumed {
+		rejectedFlags |= os.O_TRUNC
+	}
+
+	rejectedFile, err := os.OpenFile(im.config.RejectedPath, rejectedFlags, rejectedFilePerm)
+	if err != nil {
+		return checkpoint, fmt.Errorf("failed to open rejected rows file: %w", err)
+	}
+
+	defer rejectedFile.Close()
+
+	if resumed {
+		slog.Info(fmt.Sprintf("Resuming import of %s from row %d at offset %d", im.config.Path, checkpoint.Rows+1, checkpoint.Offset))
+	}
+
+	progress := newProgress(im.config.Path, fileInfo.Size(), im.config.ProgressInterval)
+	currentBatch := newBatch()
+
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil && !errors.Is(err, ErrMalformedRow) {
+			return checkpoint, fmt.Errorf("failed to read row %d: %w", checkpoint.Rows+currentBatch.rows+1, err)
+		}
+
+		currentBatch.rows++
+		currentBatch.offset = reader.Offset()
+
+		if err == nil {
+			err = im.addRow(currentBatch, row)
+		}
+
+		if err != nil {
+			currentBatch.rejected = append(currentBatch.rejected, newRejectedRow(row, err))
+		}
+
+		if currentBatch.rows >= im.config.BatchSize {
+			if err := im.commit(ctx, currentBatch, &checkpoint, rejectedFile); err != nil {
+				return checkpoint, err
+			}
+
+			currentBatch = newBatch()
+
+			progress.report(checkpoint)
+		}
+	}
+
+	if currentBatch.rows > 0 {
+		if err := im.commit(ctx, currentBatch, &checkpoint, rejectedFile); err != nil {
+			return checkpoint, err
+		}
+	}
+
+	return checkpoint, nil
+}
+
+func newBatch() *batch {
+	return &batch{rowsByID: make(map[string]Row)}
+}
+
+func (im *Importer) addRow(currentBatch *batch, row Row) error {
+	msg, err := validateRow(row, im.now())
+	if err != nil {
+		return err
+	}
+
+	id, err := im.idGenerator.NewAt(msg.createdAt)
+	if err != nil {
+		return fmt.Errorf("failed to generate message id: %w", err)
+	}
+
+	currentBatch.dialogMessages = append(currentBatch.dialogMessages, repository.DialogMessage{
+		ID:        id,
+		LegacyID:  &msg.legacyID,
+		From:      msg.senderID,
+		To:        msg.receiverID,
+		Text:      msg.text,
+		CreatedAt: msg.createdAt,
+	})
+	currentBatch.rowsByID[id] = row
+
+	return nil
+}
+
+// commit imports the messages of the batch, writes its rejected rows and moves the checkpoint past it.
+func (im *Importer) commit(ctx context.Context, currentBatch *batch, checkpoint *Checkpoint, rejectedFile io.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("import interrupted: %w", err)
+	}
+
+	result, err := im.importRepository.ImportDialogMessages(ctx, currentBatch.dialogMessages)
+	if err != nil {
+		return fmt.Errorf("failed to import rows %d to %d: %w", checkpoint.Rows+1, checkpoint.Rows+currentBatch.rows, err)
+	}
+
+	for _, rejection := range result.Rejected {
+		currentBatch.rejected = append(currentBatch.rejected, newRejectedRow(currentBatch.rowsByID[rejection.DialogMessage.ID], rejection.Err))
+	}
+
+	for _, rejected := range currentBatch.rejected {
+		line, err := json.Marshal(rejected)
+		if err != nil {
+			return fmt.Errorf("failed to encode rejected row %d: %w", rejected.Number, err)
+		}
+
+		if _, err := rejectedFile.Write(append(line, '\n')); err != nil {
+			return fmt.Errorf("failed to write rejected row %d: %w", rejected.Number, err)
+		}
+
+		checkpoint.Rejected[rejected.Reason]++
+	}
+
+	checkpoint.Offset = currentBatch.offset
+	checkpoint.Rows += currentBatch.rows
+	checkpoint.Imported += result.Imported
+	checkpoint.Skipped += result.Skipped
+	checkpoint.SavedAt = im.now().UTC()
+
+	return saveCheckpoint(im.config.CheckpointPath, *checkpoint)
+}
+
+func newRejectedRow(row Row, err error) rejectedRow {
+	reason := err.Error()
+
+	for _, rejectionReason := range rejectionReasons {
+		if errors.Is(err, rejectionReason) {
+			reason = rejectionReason.Error()
+
+			break
+		}
+	}
+
+	return rejectedRow{
+		Row:    row,
+		Reason: reason,
+		Error:  err.Error(),
+	}
+}
+
+// progress logs the totals of the import at most once per interval.
+type progress struct {
+	path       string
+	size       int64
+	interval   time.Duration
+	lastReport time.Time
+}
+
+func newProgress(path string, size int64, interval time.Duration) *progress {
+	return &progress{
+		path:       path,
+		size:       size,
+		interval:   interval,
+		lastReport: time.Now(),
+	}
+}
+
+func (p *progress) report(checkpoint Checkpoint) {
+	if time.Since(p.lastReport) < p.interval {
+		return
+	}
+
+	p.lastReport = time.Now()
+
+	var percent float64
+	if p.size > 0 {
+		percent = float64(checkpoint.Offset) * 100 / float64(p.size)
+	}
+
+	slog.Info(fmt.Sprintf("Importing %s: %.1f%% done, %d rows read, %d imported, %d skipped, %d rejected",
+		p.path, percent, checkpoint.Rows, checkpoint.Imported, checkpoint.Skipped, checkpoint.RejectedCount()))
+}
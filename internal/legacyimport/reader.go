@@ -0,0 +1,234 @@
+package legacyimport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+)
+
+// Row is a message of the export as it is written there, fields are validated by the importer.
+type Row struct {
+	// Number is the position of the row in the export starting from 1, the csv header is not counted.
+	Number     int    `json:"row"`
+	LegacyID   string `json:"legacy_id"`
+	SenderID   string `json:"sender_id"`
+	ReceiverID string `json:"receiver_id"`
+	Text       string `json:"text"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// Reader reads rows of an export. A row that cannot be parsed comes with ErrMalformedRow and the reader moves on
+// to the next one, other errors are fatal. The end of the export is io.EOF.
+type Reader interface {
+	Read() (Row, error)
+	// Offset is the byte offset of the export after the last row read, reading resumes from it.
+	Offset() int64
+}
+
+// NewReader returns a reader of the format that starts at the offset, rows counts the rows before it.
+func NewReader(format string, file io.ReadSeeker, offset int64, rows int) (Reader, error) { //nolint:ireturn
+	switch format {
+	case FormatJSONL:
+		return NewJSONLReader(file, offset, rows)
+	case FormatCSV:
+		return NewCSVReader(file, offset, rows)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+// JSONLReader reads an object per line, blank lines are skipped. Ids may be strings or numbers.
+type JSONLReader struct {
+	reader *bufio.Reader
+	offset int64
+	rows   int
+}
+
+func NewJSONLReader(file io.ReadSeeker, offset int64, rows int) (*JSONLReader, error) {
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to seek to offset %d: %w", offset, err)
+	}
+
+	return &JSONLReader{
+		reader: bufio.NewReader(file),
+		offset: offset,
+		rows:   rows,
+	}, nil
+}
+
+// jsonlRow takes ids of the monolith as they were exported, numbers or strings.
+type jsonlRow struct {
+	LegacyID   jsonString `json:"legacy_id"`
+	SenderID   jsonString `json:"sender_id"`
+	ReceiverID jsonString `json:"receiver_id"`
+	Text       string     `json:"text"`
+	CreatedAt  string     `json:"created_at"`
+}
+
+type jsonString string
+
+func (s *jsonString) UnmarshalJSON(data []byte) error {
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err == nil {
+		*s = jsonString(number)
+
+		return nil
+	}
+
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return fmt.Errorf("expected a string or a number, got %s", data)
+	}
+
+	*s = jsonString(str)
+
+	return nil
+}
+
+func (r *JSONLReader) Read() (Row, error) {
+	for {
+		line, err := r.reader.ReadBytes('\n')
+		r.offset += int64(len(line))
+
+		if err != nil && !errors.Is(err, io.EOF) {
+			return Row{}, fmt.Errorf("failed to read line: %w", err)
+		}
+
+		if len(bytes.TrimSpace(line)) == 0 {
+			if err != nil {
+				return Row{}, io.EOF
+			}
+
+			continue
+		}
+
+		r.rows++
+
+		var row jsonlRow
+		if err := json.Unmarshal(line, &row); err != nil {
+			return Row{Number: r.rows}, fmt.Errorf("%w: %w", ErrMalformedRow, err)
+		}
+
+		return Row{
+			Number:     r.rows,
+			LegacyID:   string(row.LegacyID),
+			SenderID:   string(row.SenderID),
+			ReceiverID: string(row.ReceiverID),
+			Text:       row.Text,
+			CreatedAt:  row.CreatedAt,
+		}, nil
+	}
+}
+
+func (r *JSONLReader) Offset() int64 {
+	return r.offset
+}
+
+var csvColumns = []string{"legacy_id", "sender_id", "receiver_id", "text", "created_at"}
+
+// CSVReader reads rows with a header naming the columns, other columns are ignored.
+type CSVReader struct {
+	reader *csv.Reader
+	// base is the offset the csv reader started at, it counts offsets from its start.
+	base    int64
+	rows    int
+	columns map[string]int
+}
+
+// NewCSVReader reads the header and resumes from the offset when it is past the header.
+func NewCSVReader(file io.ReadSeeker, offset int64, rows int) (*CSVReader, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to seek to the header: %w", err)
+	}
+
+	reader := csv.NewReader(file)
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+
+	// Spreadsheets save csv with a byte order mark.
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+
+	for _, name := range csvColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: %q", ErrMissingColumn, name)
+		}
+	}
+
+	r := &CSVReader{
+		reader:  reader,
+		rows:    rows,
+		columns: columns,
+	}
+
+	if offset > reader.InputOffset() {
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to seek to offset %d: %w", offset, err)
+		}
+
+		r.reader = csv.NewReader(file)
+		r.reader.FieldsPerRecord = len(header)
+		r.base = offset
+	}
+
+	return r, nil
+}
+
+func (r *CSVReader) Read() (Row, error) {
+	record, err := r.reader.Read()
+	if errors.Is(err, io.EOF) {
+		return Row{}, io.EOF
+	}
+
+	var parseErr *csv.ParseError
+	if err != nil && !errors.As(err, &parseErr) {
+		return Row{}, fmt.Errorf("failed to read csv row: %w", err)
+	}
+
+	r.rows++
+
+	if err != nil {
+		return Row{Number: r.rows}, fmt.Errorf("%w: %w", ErrMalformedRow, err)
+	}
+
+	return Row{
+		Number:     r.rows,
+		LegacyID:   record[r.columns["legacy_id"]],
+		SenderID:   record[r.columns["sender_id"]],
+		ReceiverID: record[r.columns["receiver_id"]],
+		Text:       record[r.columns["text"]],
+		CreatedAt:  record[r.columns["created_at"]],
+	}, nil
+}
+
+func (r *CSVReader) Offset() int64 {
+	return r.base + r.reader.InputOffset()
+}
+
+// FormatOf guesses the format of the export from the extension of its file.
+func FormatOf(path string) (string, error) {
+	switch strings.ToLower(strings.TrimPrefix(filepath.Ext(path), ".")) {
+	case FormatJSONL, "ndjson":
+		return FormatJSONL, nil
+	case FormatCSV:
+		return FormatCSV, nil
+	default:
+		return "", fmt.Errorf("%w: cannot tell the format of %q, pass it explicitly", ErrUnknownFormat, path)
+	}
+}
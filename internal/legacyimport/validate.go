@@ -0,0 +1,133 @@
+package legacyimport
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxTextLength is the limit of messages sent through the API.
+const maxTextLength = 1000
+
+var userIDRegexp = regexp.MustCompile(`(?i)^[a-f\d]{8}-[a-f\d]{4}-[a-f\d]{4}-[a-f\d]{4}-[a-f\d]{12}$`)
+
+// createdAtLayouts are the layouts the monolith exports timestamps in, timestamps without a zone are UTC.
+var createdAtLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// message is a validated row in the form it is stored in.
+type message struct {
+	legacyID   string
+	senderID   string
+	receiverID string
+	text       string
+	createdAt  time.Time
+}
+
+func validateRow(row Row, now time.Time) (message, error) {
+	legacyID, err := parseLegacyID(row.LegacyID)
+	if err != nil {
+		return message{}, err
+	}
+
+	senderID, err := parseUserID(row.SenderID, ErrInvalidSenderID)
+	if err != nil {
+		return message{}, err
+	}
+
+	receiverID, err := parseUserID(row.ReceiverID, ErrInvalidReceiverID)
+	if err != nil {
+		return message{}, err
+	}
+
+	if senderID == receiverID {
+		return message{}, ErrSameSenderAndReceiver
+	}
+
+	if err := validateText(row.Text); err != nil {
+		return message{}, err
+	}
+
+	createdAt, err := parseCreatedAt(row.CreatedAt, now)
+	if err != nil {
+		return message{}, err
+	}
+
+	return message{
+		legacyID:   legacyID,
+		senderID:   senderID,
+		receiverID: receiverID,
+		text:       row.Text,
+		createdAt:  createdAt,
+	}, nil
+}
+
+// parseLegacyID accepts positive ids of the integer legacy_id column and drops leading zeros, the stored form.
+func parseLegacyID(value string) (string, error) {
+	value = strings.TrimSpace(value)
+
+	legacyID, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || value[0] < '0' || value[0] > '9' || legacyID < 1 || legacyID > math.MaxInt32 {
+		return "", fmt.Errorf("%w: %q", ErrInvalidLegacyID, value)
+	}
+
+	return strconv.FormatInt(legacyID, 10), nil
+}
+
+// parseUserID accepts UUIDs in any case, they are stored and compared in lower case.
+func parseUserID(value string, errInvalid error) (string, error) {
+	value = strings.TrimSpace(value)
+
+	if !userIDRegexp.MatchString(value) {
+		return "", fmt.Errorf("%w: %q", errInvalid, value)
+	}
+
+	return strings.ToLower(value), nil
+}
+
+func validateText(text string) error {
+	switch {
+	case strings.TrimSpace(text) == "":
+		return fmt.Errorf("%w: empty", ErrInvalidText)
+	case !utf8.ValidString(text):
+		return fmt.Errorf("%w: not valid UTF-8", ErrInvalidText)
+	case strings.ContainsRune(text, 0):
+		return fmt.Errorf("%w: contains a NUL character", ErrInvalidText)
+	case utf8.RuneCountInString(text) > maxTextLength:
+		return fmt.Errorf("%w: longer than %d characters", ErrInvalidText, maxTextLength)
+	}
+
+	return nil
+}
+
+// parseCreatedAt keeps microseconds, the precision of the database, so that a stored message compares equal
+// to its row when the import runs again.
+func parseCreatedAt(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	for _, layout := range createdAtLayouts {
+		createdAt, err := time.Parse(layout, value)
+		if err != nil {
+			continue
+		}
+
+		createdAt = createdAt.UTC().Truncate(time.Microsecond)
+
+		if createdAt.Before(time.Unix(0, 0)) || createdAt.After(now) {
+			return time.Time{}, fmt.Errorf("%w: %q is out of range", ErrInvalidCreatedAt, value)
+		}
+
+		return createdAt, nil
+	}
+
+	return time.Time{}, fmt.Errorf("%w: %q", ErrInvalidCreatedAt, value)
+}
@@ -0,0 +1,111 @@
+// Package maintenance holds background jobs that keep dialog storage in shape.
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"myfacebook-dialog/internal/repository"
+)
+
+type DialogPartitionMaintainerConfig struct {
+	// PremakeMonths is how many months after the current one get their partitions in advance.
+	PremakeMonths int
+	// RetentionMonths is how many full months of messages are kept, zero keeps every partition.
+	RetentionMonths int
+	// DropExpired drops expired partitions instead of leaving them detached for an operator.
+	DropExpired bool
+}
+
+// DialogPartitionMaintainer creates upcoming partitions of dialog messages and expires old ones on every dialog database.
+type DialogPartitionMaintainer struct {
+	dialogPartitionRepositories []repository.DialogPartitionRepository
+	config                      DialogPartitionMaintainerConfig
+	now                         func() time.Time
+}
+
+func NewDialogPartitionMaintainer(dialogPartitionRepositories []repository.DialogPartitionRepository,
+	config DialogPartitionMaintainerConfig,
+) *DialogPartitionMaintainer {
+	return &DialogPartitionMaintainer{
+		dialogPartitionRepositories: dialogPartitionRepositories,
+		config:                      config,
+		now:                         time.Now,
+	}
+}
+
+// Run maintains partitions right away and then every interval until the context is done.
+func (m *DialogPartitionMaintainer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Maintain(ctx); err != nil {
+			slog.Error(fmt.Sprintf("Dialog partition maintenance failed: %s", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Maintain goes through every database, a failed database does not stop the others.
+func (m *DialogPartitionMaintainer) Maintain(ctx context.Context) error {
+	var failed int
+
+	for i, dialogPartitionRepository := range m.dialogPartitionRepositories {
+		if err := m.maintain(ctx, dialogPartitionRepository); err != nil {
+			slog.Error(fmt.Sprintf("Dialog partition maintenance of database %d failed: %s", i, err))
+
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of %d databases", errMaintenanceFailed, failed, len(m.dialogPartitionRepositories))
+	}
+
+	return nil
+}
+
+func (m *DialogPartitionMaintainer) maintain(ctx context.Context, dialogPartitionRepository repository.DialogPartitionRepository) error {
+	created, err := dialogPartitionRepository.EnsureDialogPartitions(ctx, m.config.PremakeMonths)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	for _, partition := range created {
+		slog.Info(fmt.Sprintf("Created dialog partition %s", partition))
+	}
+
+	if m.config.RetentionMonths <= 0 {
+		return nil
+	}
+
+	expired, err := dialogPartitionRepository.ExpireDialogPartitions(ctx, m.retentionStart(), m.config.DropExpired)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	for _, partition := range expired {
+		if m.config.DropExpired {
+			slog.Info(fmt.Sprintf("Dropped expired dialog partition %s", partition))
+		} else {
+			slog.Info(fmt.Sprintf("Detached expired dialog partition %s", partition))
+		}
+	}
+
+	return nil
+}
+
+// retentionStart is the start of the oldest month that is kept.
+func (m *DialogPartitionMaintainer) retentionStart() time.Time {
+	now := m.now().UTC()
+
+	return time.Date(now.Year(), now.Month()-time.Month(m.config.RetentionMonths), 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,214 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"myfacebook-dialog/internal/repository"
+)
+
+// dialogPurgeLockKey is the advisory lock that lets a single instance purge a database at a time.
+const dialogPurgeLockKey int64 = 0x6469616c6f67 // "dialog"
+
+const meterName = "myfacebook-dialog/internal/maintenance"
+
+// AdvisoryLocker is implemented by db.DB.
+type AdvisoryLocker interface {
+	TryAdvisoryLock(ctx context.Context, key int64) (func(), bool, error)
+}
+
+// DialogPurgeTarget is a dialog database together with the lock that coordinates purging it.
+type DialogPurgeTarget struct {
+	Locker                AdvisoryLocker
+	DialogPurgeRepository repository.DialogPurgeRepository
+}
+
+type DialogPurgerConfig struct {
+	Policy RetentionPolicy
+	// BatchSize bounds the number of messages deleted by a statement, BatchPause is the pause between statements.
+	BatchSize  int
+	BatchPause time.Duration
+	// DryRun counts messages past their retention without deleting them.
+	DryRun bool
+}
+
+// DialogPurger deletes dialog messages past their retention on every dialog database.
+type DialogPurger struct {
+	targets []DialogPurgeTarget
+	config  DialogPurgerConfig
+	now     func() time.Time
+
+	purgedMessages metric.Int64Counter
+	batches        metric.Int64Counter
+	runs           metric.Int64Counter
+	runDuration    metric.Float64Histogram
+}
+
+func NewDialogPurger(targets []DialogPurgeTarget, config DialogPurgerConfig) (*DialogPurger, error) {
+	meter := otel.Meter(meterName)
+
+	purgedMessages, err := meter.Int64Counter("dialog.purge.messages",
+		metric.WithDescription("Dialog messages deleted by retention, or found past retention in dry run"),
+		metric.WithUnit("{message}"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create purged messages counter: %w", err)
+	}
+
+	batches, err := meter.Int64Counter("dialog.purge.batches",
+		metric.WithDescription("Delete statements run by the dialog purge"),
+		metric.WithUnit("{batch}"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create purge batches counter: %w", err)
+	}
+
+	runs, err := meter.Int64Counter("dialog.purge.runs",
+		metric.WithDescription("Dialog purge runs per database by result"),
+		metric.WithUnit("{run}"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create purge runs counter: %w", err)
+	}
+
+	runDuration, err := meter.Float64Histogram("dialog.purge.duration",
+		metric.WithDescription("Duration of dialog purge runs per database"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create purge duration histogram: %w", err)
+	}
+
+	return &DialogPurger{
+		targets:        targets,
+		config:         config,
+		now:            time.Now,
+		purgedMessages: purgedMessages,
+		batches:        batches,
+		runs:           runs,
+		runDuration:    runDuration,
+	}, nil
+}
+
+// Run purges right away and then every interval until the context is done.
+func (p *DialogPurger) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.Purge(ctx); err != nil {
+			slog.Error(fmt.Sprintf("Dialog purge failed: %s", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge goes through every database, a failed database does not stop the others.
+// A database purged by another instance at the moment is skipped.
+func (p *DialogPurger) Purge(ctx context.Context) error {
+	var failed int
+
+	for i, target := range p.targets {
+		startedAt := time.Now()
+
+		result, err := p.purge(ctx, i, target)
+		if err != nil {
+			slog.Error(fmt.Sprintf("Dialog purge of database %d failed: %s", i, err))
+
+			failed++
+			result = "failed"
+		}
+
+		attrs := metric.WithAttributes(attribute.Int("database", i), attribute.String("result", result),
+			attribute.Bool("dry_run", p.config.DryRun))
+		p.runs.Add(ctx, 1, attrs)
+		p.runDuration.Record(ctx, time.Since(startedAt).Seconds(), attrs)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of %d databases", errMaintenanceFailed, failed, len(p.targets))
+	}
+
+	return nil
+}
+
+func (p *DialogPurger) purge(ctx context.Context, database int, target DialogPurgeTarget) (string, error) {
+	unlock, locked, err := target.Locker.TryAdvisoryLock(ctx, dialogPurgeLockKey)
+	if err != nil {
+		return "", err //nolint:wrapcheck
+	}
+
+	if !locked {
+		slog.Debug(fmt.Sprintf("Dialog purge of database %d is run by another instance", database))
+
+		return "locked", nil
+	}
+
+	defer unlock()
+
+	for _, rule := range p.config.Policy.rules(p.now()) {
+		attrs := metric.WithAttributes(attribute.Int("database", database), attribute.String("policy", rule.name),
+			attribute.Bool("dry_run", p.config.DryRun))
+
+		if p.config.DryRun {
+			count, err := target.DialogPurgeRepository.CountDialogMessages(ctx, rule.filter)
+			if err != nil {
+				return "", err //nolint:wrapcheck
+			}
+
+			p.purgedMessages.Add(ctx, count, attrs)
+
+			slog.Info(fmt.Sprintf("Dry run: dialog purge would delete %d messages of policy %q created before %s on database %d",
+				count, rule.name, rule.filter.CreatedBefore.Format(time.RFC3339), database))
+
+			continue
+		}
+
+		deleted, err := p.deleteInBatches(ctx, target.DialogPurgeRepository, rule.filter, attrs)
+		if deleted > 0 {
+			slog.Info(fmt.Sprintf("Dialog purge deleted %d messages of policy %q created before %s on database %d",
+				deleted, rule.name, rule.filter.CreatedBefore.Format(time.RFC3339), database))
+		}
+
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return "purged", nil
+}
+
+// deleteInBatches deletes until a batch comes up short, pausing between batches to leave room for the regular load.
+func (p *DialogPurger) deleteInBatches(ctx context.Context, dialogPurgeRepository repository.DialogPurgeRepository,
+	filter repository.DialogPurgeFilter, attrs metric.MeasurementOption,
+) (int64, error) {
+	var total int64
+
+	for {
+		deleted, err := dialogPurgeRepository.DeleteDialogMessages(ctx, filter, p.config.BatchSize)
+		if err != nil {
+			return total, err //nolint:wrapcheck
+		}
+
+		total += deleted
+
+		p.batches.Add(ctx, 1, attrs)
+		p.purgedMessages.Add(ctx, deleted, attrs)
+
+		if deleted < int64(p.config.BatchSize) {
+			return total, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return total, ctx.Err() //nolint:wrapcheck
+		case <-time.After(p.config.BatchPause):
+		}
+	}
+}
@@ -0,0 +1,5 @@
+package maintenance
+
+import "errors"
+
+var errMaintenanceFailed = errors.New("maintenance failed")
@@ -0,0 +1,74 @@
+package maintenance
+
+import (
+	"sort"
+	"time"
+
+	"myfacebook-dialog/internal/repository"
+)
+
+// defaultRetentionPolicyName labels the default retention in logs and metrics.
+const defaultRetentionPolicyName = "default"
+
+// RetentionPolicy is how long dialog messages are kept, a zero retention keeps messages forever.
+// ByMessageType overrides Default for messages of the type, including a zero override that keeps them.
+type RetentionPolicy struct {
+	Default       time.Duration
+	ByMessageType map[string]time.Duration
+}
+
+// IsEmpty reports whether the policy keeps every message.
+func (p RetentionPolicy) IsEmpty() bool {
+	if p.Default > 0 {
+		return false
+	}
+
+	for _, retention := range p.ByMessageType {
+		if retention > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+type retentionRule struct {
+	name   string
+	filter repository.DialogPurgeFilter
+}
+
+// rules returns a purge filter for every retention of the policy as of now.
+func (p RetentionPolicy) rules(now time.Time) []retentionRule {
+	messageTypes := make([]string, 0, len(p.ByMessageType))
+	for messageType := range p.ByMessageType {
+		messageTypes = append(messageTypes, messageType)
+	}
+
+	sort.Strings(messageTypes)
+
+	var rules []retentionRule
+
+	for _, messageType := range messageTypes {
+		if retention := p.ByMessageType[messageType]; retention > 0 {
+			rules = append(rules, retentionRule{
+				name: messageType,
+				filter: repository.DialogPurgeFilter{
+					CreatedBefore: now.Add(-retention),
+					MessageType:   messageType,
+				},
+			})
+		}
+	}
+
+	if p.Default > 0 {
+		rules = append(rules, retentionRule{
+			name: defaultRetentionPolicyName,
+			filter: repository.DialogPurgeFilter{
+				CreatedBefore:      now.Add(-p.Default),
+				ExceptMessageTypes: messageTypes,
+			},
+		})
+	}
+
+	return rules
+}
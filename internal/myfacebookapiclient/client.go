@@ -5,11 +5,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 )
 
 const (
 	endpointFindUserByToken = "/int/user/findByToken" //nolint:gosec
 	endpointGetUserByID     = "/int/user/%s"
+
+	// The legacy dialog endpoints store messages in the monolith only, they are not forwarded back to the dialog service.
+	endpointSendLegacyDialogMessage  = "/int/legacy/dialog/send"
+	endpointListLegacyDialogMessages = "/int/legacy/dialog/list?%s"
 )
 
 type HTTPAPIClient interface {
@@ -76,3 +81,47 @@ func (c *Client) GetUserByID(ctx context.Context, userID string) (*User, error)
 
 	return nil, ErrUnexpectedStatusCode
 }
+
+func (c *Client) SendLegacyDialogMessage(ctx context.Context, senderID, receiverID, text string) error {
+	response, err := c.apiClient.Post(ctx, endpointSendLegacyDialogMessage, sendDialogMessageRequest{
+		From: senderID,
+		To:   receiverID,
+		Text: text,
+	})
+	if err != nil {
+		return fmt.Errorf("myfacebookapiclient failed to send legacy dialog message: %w", err)
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return ErrUnexpectedStatusCode
+	}
+
+	return nil
+}
+
+// ListLegacyDialogMessages returns the messages of the dialog between the users stored in the monolith, oldest first.
+func (c *Client) ListLegacyDialogMessages(ctx context.Context, userID, peerID string) ([]DialogMessage, error) {
+	query := url.Values{"from": {userID}, "to": {peerID}}
+
+	response, err := c.apiClient.Get(ctx, fmt.Sprintf(endpointListLegacyDialogMessages, query.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("myfacebookapiclient failed to list legacy dialog messages: %w", err)
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, ErrUnexpectedStatusCode
+	}
+
+	var dialogMessages []DialogMessage
+
+	err = json.NewDecoder(response.Body).Decode(&dialogMessages)
+	if err != nil {
+		return nil, fmt.Errorf("myfacebookapiclient failed to decode api client response: %w", err)
+	}
+
+	return dialogMessages, nil
+}
@@ -1,5 +1,22 @@
 package myfacebookapiclient
 
+import "time"
+
 type User struct {
 	ID string `json:"id"`
 }
+
+type sendDialogMessageRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Text string `json:"text"`
+}
+
+// DialogMessage is a message of the legacy dialogs of the monolith, ID is its integer id there.
+type DialogMessage struct {
+	ID        string    `json:"id"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
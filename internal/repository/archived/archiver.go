@@ -0,0 +1,194 @@
+package archived
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"myfacebook-dialog/internal/blobstore"
+	"myfacebook-dialog/internal/repository"
+)
+
+// restoreBatchSize keeps a restore statement well below the limit of query parameters.
+const restoreBatchSize = 1000
+
+// Shard is a dialog database that messages are archived from and restored to.
+type Shard interface {
+	GetDialogsCreatedBetween(ctx context.Context, from, to time.Time) ([]repository.Dialog, error)
+	GetDialogMessagesCreatedBetween(ctx context.Context, senderID, receiverID string, from, to time.Time) ([]repository.DialogMessage, error)
+	DeleteDialogMessages(ctx context.Context, dialogMessages []repository.DialogMessage) (int64, error)
+	CopyDialogMessages(ctx context.Context, dialogMessages []repository.DialogMessage) error
+}
+
+// Archiver moves messages of a dialog created in a month into an archive file and back.
+// Every step can be repeated, so an interrupted run is finished by running it again.
+type Archiver struct {
+	shards                  []Shard
+	shardIndex              func(userID, peerID string) int
+	dialogArchiveRepository repository.DialogArchiveRepository
+	blobStore               blobstore.BlobStore
+}
+
+func NewArchiver(shards []Shard, shardIndex func(userID, peerID string) int,
+	dialogArchiveRepository repository.DialogArchiveRepository, blobStore blobstore.BlobStore,
+) *Archiver {
+	return &Archiver{
+		shards:                  shards,
+		shardIndex:              shardIndex,
+		dialogArchiveRepository: dialogArchiveRepository,
+		blobStore:               blobStore,
+	}
+}
+
+// Archive moves messages created in the months of [from, to) out of the dialogs table.
+func (a *Archiver) Archive(ctx context.Context, from, to time.Time) error {
+	if err := checkMonthRange(from, to); err != nil {
+		return err
+	}
+
+	for month := from.UTC(); month.Before(to); month = month.AddDate(0, 1, 0) {
+		for shardIndex, shard := range a.shards {
+			dialogs, err := shard.GetDialogsCreatedBetween(ctx, month, month.AddDate(0, 1, 0))
+			if err != nil {
+				return fmt.Errorf("failed to fetch dialogs of %s from shard %d: %w", month.Format("2006-01"), shardIndex, err)
+			}
+
+			var archivedCount int64
+
+			for _, dialog := range dialogs {
+				count, err := a.archiveDialogMonth(ctx, shard, dialog, month)
+				if err != nil {
+					return fmt.Errorf("failed to archive dialog of %s and %s for %s: %w",
+						dialog.FirstUserID, dialog.SecondUserID, month.Format("2006-01"), err)
+				}
+
+				archivedCount += count
+			}
+
+			slog.Info(fmt.Sprintf("Archived %d messages of %d dialogs for %s from shard %d",
+				archivedCount, len(dialogs), month.Format("2006-01"), shardIndex))
+		}
+	}
+
+	return nil
+}
+
+// archiveDialogMonth stores the archive file before recording it, so a recorded archive always has its file.
+// Messages are deleted from the dialogs table last, until then readers find them in both places.
+func (a *Archiver) archiveDialogMonth(ctx context.Context, shard Shard, dialog repository.Dialog, month time.Time) (int64, error) {
+	dialogMessages, err := shard.GetDialogMessagesCreatedBetween(ctx, dialog.FirstUserID, dialog.SecondUserID, month, month.AddDate(0, 1, 0))
+	if err != nil {
+		return 0, err //nolint:wrapcheck
+	}
+
+	if len(dialogMessages) == 0 {
+		return 0, nil
+	}
+
+	data, dataChecksum, err := encodeArchive(dialogMessages)
+	if err != nil {
+		return 0, err
+	}
+
+	firstSeq, lastSeq := dialogMessages[0].Seq, dialogMessages[len(dialogMessages)-1].Seq
+
+	dialogArchive := repository.DialogArchive{
+		FirstUserID:  dialog.FirstUserID,
+		SecondUserID: dialog.SecondUserID,
+		Month:        month,
+		FirstSeq:     firstSeq,
+		LastSeq:      lastSeq,
+		MessageCount: len(dialogMessages),
+		ObjectKey:    objectKey(dialog, month, firstSeq, lastSeq, dataChecksum),
+		Checksum:     dataChecksum,
+	}
+
+	if err := a.blobStore.Put(ctx, dialogArchive.ObjectKey, data); err != nil {
+		return 0, fmt.Errorf("failed to store archive file: %w", err)
+	}
+
+	storedDialogArchive, err := a.dialogArchiveRepository.Add(ctx, dialogArchive, dialogMessages)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record archive: %w", err)
+	}
+
+	if storedDialogArchive.Checksum != dialogArchive.Checksum {
+		return 0, fmt.Errorf("%w: %s", ErrArchiveConflict, dialogArchive.ObjectKey)
+	}
+
+	deleted, err := shard.DeleteDialogMessages(ctx, dialogMessages)
+	if err != nil {
+		return 0, err //nolint:wrapcheck
+	}
+
+	return deleted, nil
+}
+
+// Restore moves messages of the archives of the months in [from, to) back into the dialogs table and removes the archives.
+func (a *Archiver) Restore(ctx context.Context, from, to time.Time) error {
+	if err := checkMonthRange(from, to); err != nil {
+		return err
+	}
+
+	dialogArchives, err := a.dialogArchiveRepository.GetDialogArchivesBetween(ctx, from, to)
+	if err != nil {
+		return fmt.Errorf("failed to fetch dialog archives: %w", err)
+	}
+
+	var restoredCount int
+
+	for _, dialogArchive := range dialogArchives {
+		if err := a.restoreDialogArchive(ctx, dialogArchive); err != nil {
+			return fmt.Errorf("failed to restore dialog archive %d: %w", dialogArchive.ID, err)
+		}
+
+		restoredCount += dialogArchive.MessageCount
+	}
+
+	slog.Info(fmt.Sprintf("Restored %d messages of %d dialog archives", restoredCount, len(dialogArchives)))
+
+	return nil
+}
+
+// restoreDialogArchive copies messages back before forgetting the archive, the file goes last when nothing refers to it.
+func (a *Archiver) restoreDialogArchive(ctx context.Context, dialogArchive repository.DialogArchive) error {
+	dialogMessages, err := readArchive(ctx, a.blobStore, dialogArchive)
+	if err != nil {
+		return err
+	}
+
+	shard := a.shards[a.shardIndex(dialogArchive.FirstUserID, dialogArchive.SecondUserID)]
+
+	for start := 0; start < len(dialogMessages); start += restoreBatchSize {
+		end := min(start+restoreBatchSize, len(dialogMessages))
+
+		if err := shard.CopyDialogMessages(ctx, dialogMessages[start:end]); err != nil {
+			return err //nolint:wrapcheck
+		}
+	}
+
+	if err := a.dialogArchiveRepository.Delete(ctx, dialogArchive.ID); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	if err := a.blobStore.Delete(ctx, dialogArchive.ObjectKey); err != nil {
+		slog.Warn(fmt.Sprintf("Failed to delete file of restored dialog archive %d: %s", dialogArchive.ID, err))
+	}
+
+	return nil
+}
+
+func checkMonthRange(from, to time.Time) error {
+	if !isMonthStart(from) || !isMonthStart(to) || !from.Before(to) {
+		return fmt.Errorf("%w: %s - %s", ErrInvalidMonth, from.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+func isMonthStart(t time.Time) bool {
+	t = t.UTC()
+
+	return t.Equal(time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC))
+}
@@ -0,0 +1,205 @@
+// Package archived serves dialog messages moved out of the dialogs table into archive files
+// and moves them there and back.
+package archived
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"myfacebook-dialog/internal/blobstore"
+	"myfacebook-dialog/internal/repository"
+)
+
+// DialogRepository reads archived messages along with the ones still stored in the dialogs table,
+// so clients page from the hot window into archived history without noticing.
+type DialogRepository struct {
+	dialogRepository        repository.DialogRepository
+	dialogArchiveRepository repository.DialogArchiveRepository
+	blobStore               blobstore.BlobStore
+}
+
+func NewDialogRepository(dialogRepository repository.DialogRepository, dialogArchiveRepository repository.DialogArchiveRepository,
+	blobStore blobstore.BlobStore,
+) *DialogRepository {
+	return &DialogRepository{
+		dialogRepository:        dialogRepository,
+		dialogArchiveRepository: dialogArchiveRepository,
+		blobStore:               blobStore,
+	}
+}
+
+func (r *DialogRepository) Add(ctx context.Context, dialogMessage repository.DialogMessage) (*repository.DialogMessage, error) {
+	return r.dialogRepository.Add(ctx, dialogMessage) //nolint:wrapcheck
+}
+
+func (r *DialogRepository) GetDialogMessagesBySenderIDAndReceiverID(ctx context.Context, senderID, receiverID string) ([]repository.DialogMessage, error) {
+	return r.GetDialogMessagesAfterSeq(ctx, senderID, receiverID, 0, 0)
+}
+
+// GetDialogMessagesAfterSeq merges archived and stored messages by seq. Archives of a dialog cover seq ranges
+// that may interleave at month boundaries, so archives are read until the page cannot change any more.
+func (r *DialogRepository) GetDialogMessagesAfterSeq(ctx context.Context, senderID, receiverID string, afterSeq int64, limit int) ([]repository.DialogMessage, error) {
+	dialogArchives, err := r.dialogArchiveRepository.GetDialogArchivesAfterSeq(ctx, senderID, receiverID, afterSeq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dialog archives: %w", err)
+	}
+
+	storedDialogMessages, err := r.dialogRepository.GetDialogMessagesAfterSeq(ctx, senderID, receiverID, afterSeq, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch stored dialog messages: %w", err)
+	}
+
+	if len(dialogArchives) == 0 {
+		return storedDialogMessages, nil
+	}
+
+	var archivedDialogMessages []repository.DialogMessage
+
+	for _, dialogArchive := range dialogArchives {
+		if limit > 0 && len(archivedDialogMessages) >= limit && dialogArchive.FirstSeq > archivedDialogMessages[limit-1].Seq {
+			break
+		}
+
+		dialogMessages, err := readArchive(ctx, r.blobStore, dialogArchive)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, dialogMsg := range dialogMessages {
+			if dialogMsg.Seq > afterSeq {
+				archivedDialogMessages = append(archivedDialogMessages, dialogMsg)
+			}
+		}
+
+		sortBySeq(archivedDialogMessages)
+	}
+
+	dialogMessages := mergeBySeq(archivedDialogMessages, storedDialogMessages)
+	if limit > 0 && len(dialogMessages) > limit {
+		dialogMessages = dialogMessages[:limit]
+	}
+
+	return dialogMessages, nil
+}
+
+// GetDialogMessagesByIDs looks in archives for the messages that are not stored, pinned and starred messages stay resolvable.
+func (r *DialogRepository) GetDialogMessagesByIDs(ctx context.Context, messageIDs []string) ([]repository.DialogMessage, error) {
+	if len(messageIDs) == 0 {
+		return nil, repository.ErrNotFound
+	}
+
+	dialogMessages, err := r.dialogRepository.GetDialogMessagesByIDs(ctx, messageIDs)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("failed to fetch stored dialog messages by ids: %w", err)
+	}
+
+	foundIDs := make(map[string]struct{}, len(messageIDs))
+
+	for _, dialogMsg := range dialogMessages {
+		for _, id := range dialogMsg.IDs() {
+			foundIDs[id] = struct{}{}
+		}
+	}
+
+	missingIDs := make(map[string]struct{}, len(messageIDs))
+
+	for _, messageID := range messageIDs {
+		if _, ok := foundIDs[messageID]; !ok {
+			missingIDs[messageID] = struct{}{}
+		}
+	}
+
+	if len(missingIDs) == 0 {
+		return dialogMessages, nil
+	}
+
+	missingIDList := make([]string, 0, len(missingIDs))
+	for messageID := range missingIDs {
+		missingIDList = append(missingIDList, messageID)
+	}
+
+	dialogArchives, err := r.dialogArchiveRepository.GetDialogArchivesByMessageIDs(ctx, missingIDList)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dialog archives by message ids: %w", err)
+	}
+
+	for _, dialogArchive := range dialogArchives {
+		archivedDialogMessages, err := readArchive(ctx, r.blobStore, dialogArchive)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, dialogMsg := range archivedDialogMessages {
+			if isAnyOf(dialogMsg.IDs(), missingIDs) {
+				dialogMessages = append(dialogMessages, dialogMsg)
+			}
+		}
+	}
+
+	if len(dialogMessages) == 0 {
+		return nil, repository.ErrNotFound
+	}
+
+	sort.SliceStable(dialogMessages, func(i, j int) bool {
+		return dialogMessages[i].CreatedAt.Before(dialogMessages[j].CreatedAt)
+	})
+
+	return dialogMessages, nil
+}
+
+func readArchive(ctx context.Context, blobStore blobstore.BlobStore, dialogArchive repository.DialogArchive) ([]repository.DialogMessage, error) {
+	data, err := blobStore.Get(ctx, dialogArchive.ObjectKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dialog archive %d: %w", dialogArchive.ID, err)
+	}
+
+	dialogMessages, err := decodeArchive(data, dialogArchive.Checksum)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode dialog archive %d: %w", dialogArchive.ID, err)
+	}
+
+	return dialogMessages, nil
+}
+
+func sortBySeq(dialogMessages []repository.DialogMessage) {
+	sort.SliceStable(dialogMessages, func(i, j int) bool {
+		return dialogMessages[i].Seq < dialogMessages[j].Seq
+	})
+}
+
+// mergeBySeq merges messages sorted by seq. A message is found in both while it is being archived or restored,
+// it is listed once.
+func mergeBySeq(a, b []repository.DialogMessage) []repository.DialogMessage {
+	merged := make([]repository.DialogMessage, 0, len(a)+len(b))
+
+	for len(a) > 0 || len(b) > 0 {
+		var next repository.DialogMessage
+
+		switch {
+		case len(b) == 0 || (len(a) > 0 && a[0].Seq <= b[0].Seq):
+			next, a = a[0], a[1:]
+		default:
+			next, b = b[0], b[1:]
+		}
+
+		if len(merged) > 0 && merged[len(merged)-1].Seq == next.Seq {
+			continue
+		}
+
+		merged = append(merged, next)
+	}
+
+	return merged
+}
+
+func isAnyOf(ids []string, set map[string]struct{}) bool {
+	for _, id := range ids {
+		if _, ok := set[id]; ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,9 @@
+package archived
+
+import "errors"
+
+var (
+	ErrChecksumMismatch = errors.New("archive checksum mismatch")
+	ErrArchiveConflict  = errors.New("archive is stored with different content")
+	ErrInvalidMonth     = errors.New("archive range must start and end at the beginning of a month in UTC")
+)
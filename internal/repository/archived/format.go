@@ -0,0 +1,127 @@
+package archived
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"myfacebook-dialog/internal/repository"
+)
+
+// archivedMessage is a line of an archive file, it keeps every field of a stored message.
+type archivedMessage struct {
+	ID                     string    `json:"id"`
+	LegacyID               *string   `json:"legacy_id,omitempty"`
+	Source                 string    `json:"source,omitempty"`
+	From                   string    `json:"sender_id"`
+	To                     string    `json:"receiver_id"`
+	Text                   string    `json:"text"`
+	Type                   string    `json:"message_type"`
+	ForwardedFromUserID    *string   `json:"forwarded_from_user_id,omitempty"`
+	ForwardedFromMessageID *string   `json:"forwarded_from_message_id,omitempty"`
+	Seq                    int64     `json:"seq"`
+	CreatedAt              time.Time `json:"created_at"`
+}
+
+// encodeArchive writes the messages as gzip compressed JSON lines and returns the file with its checksum.
+// The output depends on the messages only, so archiving the same messages again produces the same file.
+func encodeArchive(dialogMessages []repository.DialogMessage) ([]byte, string, error) {
+	var buf bytes.Buffer
+
+	gzipWriter := gzip.NewWriter(&buf)
+	encoder := json.NewEncoder(gzipWriter)
+
+	for _, dialogMsg := range dialogMessages {
+		err := encoder.Encode(archivedMessage{
+			ID:                     dialogMsg.ID,
+			LegacyID:               dialogMsg.LegacyID,
+			Source:                 dialogMsg.Source,
+			From:                   dialogMsg.From,
+			To:                     dialogMsg.To,
+			Text:                   dialogMsg.Text,
+			Type:                   dialogMsg.Type,
+			ForwardedFromUserID:    dialogMsg.ForwardedFromUserID,
+			ForwardedFromMessageID: dialogMsg.ForwardedFromMessageID,
+			Seq:                    dialogMsg.Seq,
+			CreatedAt:              dialogMsg.CreatedAt.UTC(),
+		})
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to encode archived message %s: %w", dialogMsg.ID, err)
+		}
+	}
+
+	if err := gzipWriter.Close(); err != nil {
+		return nil, "", fmt.Errorf("failed to compress archive: %w", err)
+	}
+
+	return buf.Bytes(), checksum(buf.Bytes()), nil
+}
+
+// decodeArchive verifies the checksum of the file before reading messages out of it.
+func decodeArchive(data []byte, expectedChecksum string) ([]repository.DialogMessage, error) {
+	if actualChecksum := checksum(data); actualChecksum != expectedChecksum {
+		return nil, fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, expectedChecksum, actualChecksum)
+	}
+
+	gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress archive: %w", err)
+	}
+
+	defer gzipReader.Close()
+
+	decoder := json.NewDecoder(gzipReader)
+
+	var dialogMessages []repository.DialogMessage
+
+	for {
+		var archivedMsg archivedMessage
+
+		err := decoder.Decode(&archivedMsg)
+		if errors.Is(err, io.EOF) {
+			return dialogMessages, nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode archived message: %w", err)
+		}
+
+		// Archives written before messages were imported hold messages of this service only.
+		if archivedMsg.Source == "" {
+			archivedMsg.Source = repository.DialogMessageSourceDialog
+		}
+
+		dialogMessages = append(dialogMessages, repository.DialogMessage{
+			ID:                     archivedMsg.ID,
+			LegacyID:               archivedMsg.LegacyID,
+			Source:                 archivedMsg.Source,
+			From:                   archivedMsg.From,
+			To:                     archivedMsg.To,
+			Text:                   archivedMsg.Text,
+			Type:                   archivedMsg.Type,
+			ForwardedFromUserID:    archivedMsg.ForwardedFromUserID,
+			ForwardedFromMessageID: archivedMsg.ForwardedFromMessageID,
+			Seq:                    archivedMsg.Seq,
+			CreatedAt:              archivedMsg.CreatedAt,
+		})
+	}
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// objectKey groups archives by dialog and month. The checksum keeps a different set of messages
+// of the same seq range from overwriting an archive that is already referenced.
+func objectKey(dialog repository.Dialog, month time.Time, firstSeq, lastSeq int64, checksum string) string {
+	return fmt.Sprintf("dialogs/%s_%s/%s/%d-%d-%s.jsonl.gz", dialog.FirstUserID, dialog.SecondUserID,
+		month.Format("2006-01"), firstSeq, lastSeq, checksum[:16])
+}
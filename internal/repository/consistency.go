@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"sync"
+)
+
+type consistencyRecorderKey struct{}
+
+type consistencyTokenKey struct{}
+
+// ConsistencyRecorder collects the consistency token of the writes made with its context.
+type ConsistencyRecorder struct {
+	mu    sync.Mutex
+	token string
+}
+
+// WithConsistencyRecorder asks repositories to record a consistency token for every write made with the returned context.
+func WithConsistencyRecorder(ctx context.Context) (context.Context, *ConsistencyRecorder) {
+	recorder := &ConsistencyRecorder{}
+
+	return context.WithValue(ctx, consistencyRecorderKey{}, recorder), recorder
+}
+
+// ConsistencyRecorderFromContext returns nil when nobody asked for a consistency token.
+func ConsistencyRecorderFromContext(ctx context.Context) *ConsistencyRecorder {
+	recorder, _ := ctx.Value(consistencyRecorderKey{}).(*ConsistencyRecorder)
+
+	return recorder
+}
+
+// Record keeps the token of the latest write.
+func (r *ConsistencyRecorder) Record(token string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.token = token
+}
+
+// Token is empty when no write needed one.
+func (r *ConsistencyRecorder) Token() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.token
+}
+
+// WithConsistencyToken asks repositories to serve reads made with the returned context from a state that includes the write of the token.
+func WithConsistencyToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, consistencyTokenKey{}, token)
+}
+
+func ConsistencyTokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(consistencyTokenKey{}).(string)
+
+	return token
+}
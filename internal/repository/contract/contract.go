@@ -0,0 +1,50 @@
+// Package contract holds the test suites every implementation of the repository interfaces must pass,
+// so handlers can rely on the interface alone whatever storage is configured. It is used by tests only.
+// Checks create their own users with fresh ids, they can run against a database that already holds data.
+package contract
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"myfacebook-dialog/internal/idgen"
+)
+
+type check[T any] struct {
+	name string
+	run  func(ctx context.Context, repository T) error
+}
+
+// runChecks runs every check as a subtest of t.
+func runChecks[T any](t *testing.T, repository T, checks []check[T]) {
+	t.Helper()
+
+	for _, c := range checks {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			if err := c.run(context.Background(), repository); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// userIDs returns n ids unused by previous checks.
+func userIDs(n int) ([]string, error) {
+	idGenerator := idgen.NewUUIDv7()
+
+	ids := make([]string, 0, n)
+
+	for i := 0; i < n; i++ {
+		id, err := idGenerator.New()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate user id: %w", err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
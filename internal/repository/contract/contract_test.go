@@ -0,0 +1,82 @@
+package contract_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"myfacebook-dialog/internal/apiclient"
+	"myfacebook-dialog/internal/db/dbtest"
+	"myfacebook-dialog/internal/fakemonolith"
+	"myfacebook-dialog/internal/httpclient"
+	"myfacebook-dialog/internal/myfacebookapiclient"
+	"myfacebook-dialog/internal/repository"
+	"myfacebook-dialog/internal/repository/contract"
+	"myfacebook-dialog/internal/repository/memory"
+	"myfacebook-dialog/internal/repository/rest"
+	"myfacebook-dialog/internal/repository/sqlite"
+	sqlxrepo "myfacebook-dialog/internal/repository/sqlx"
+)
+
+var userFixture = contract.UserFixture{
+	User:  repository.User{ID: "00000000-0000-4000-8000-00000000c0de"},
+	Token: "contract-token",
+}
+
+func TestMemoryUserRepository(t *testing.T) {
+	userRepository := memory.NewUserRepository()
+	userRepository.Add(userFixture.User, userFixture.Token)
+
+	contract.RunUserRepository(t, userRepository, userFixture)
+}
+
+func TestRESTUserRepository(t *testing.T) {
+	monolith := fakemonolith.New()
+	monolith.AddUser(userFixture.User.ID, userFixture.Token)
+
+	server := httptest.NewServer(monolith)
+	t.Cleanup(server.Close)
+
+	apiClient := apiclient.New(server.URL, httpclient.New(&httpclient.Config{}))
+
+	contract.RunUserRepository(t, rest.NewUserRepository(myfacebookapiclient.New(apiClient)), userFixture)
+}
+
+func TestMemoryDialogRepository(t *testing.T) {
+	contract.RunDialogRepository(t, memory.NewDialogRepository())
+}
+
+func TestSQLiteDialogRepository(t *testing.T) {
+	contract.RunDialogRepository(t, sqlite.NewDialogRepository(dbtest.SQLite(t)))
+}
+
+func TestPostgresDialogRepository(t *testing.T) {
+	postgresDB := dbtest.Postgres(t)
+
+	// Messages go to monthly partitions, the one of the current month must exist.
+	if _, err := sqlxrepo.NewDialogPartitionRepository(postgresDB).EnsureDialogPartitions(context.Background(), 0); err != nil {
+		t.Fatalf("failed to create dialog partitions: %s", err)
+	}
+
+	contract.RunDialogRepository(t, sqlxrepo.NewDialogRepository(postgresDB))
+}
+
+func TestSQLiteDialogInboxRepository(t *testing.T) {
+	sqliteDB := dbtest.SQLite(t)
+
+	contract.RunDialogInboxRepository(t, contract.DialogInboxRepositories{
+		DialogInboxRepository:    sqlite.NewDialogInboxRepository(sqliteDB),
+		DialogSettingsRepository: sqlite.NewDialogSettingsRepository(sqliteDB),
+		DialogDraftRepository:    sqlite.NewDialogDraftRepository(sqliteDB),
+	})
+}
+
+func TestPostgresDialogInboxRepository(t *testing.T) {
+	postgresDB := dbtest.Postgres(t)
+
+	contract.RunDialogInboxRepository(t, contract.DialogInboxRepositories{
+		DialogInboxRepository:    sqlxrepo.NewDialogInboxRepository(postgresDB),
+		DialogSettingsRepository: sqlxrepo.NewDialogSettingsRepository(postgresDB),
+		DialogDraftRepository:    sqlxrepo.NewDialogDraftRepository(postgresDB),
+	})
+}
@@ -0,0 +1,364 @@
+package contract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"myfacebook-dialog/internal/repository"
+)
+
+var errContractViolated = errors.New("contract violated")
+
+// concurrentSends is the number of messages sent to one dialog at once by the concurrency check.
+const concurrentSends = 20
+
+var dialogChecks = []check[repository.DialogRepository]{
+	{"add returns the stored message", checkDialogAdd},
+	{"messages are ordered by seq", checkDialogOrder},
+	{"both directions return the same dialog", checkDialogDirections},
+	{"dialogs do not share messages", checkDialogIsolation},
+	{"after seq pages through the dialog", checkDialogAfterSeq},
+	{"by ids returns found messages in creation order", checkDialogByIDs},
+	{"empty dialogs are not an error", checkDialogEmpty},
+	{"missing message ids map to not found", checkDialogMissing},
+	{"concurrent sends get distinct seq", checkDialogConcurrentAdd},
+}
+
+// RunDialogRepository checks the implementation against the contract of repository.DialogRepository.
+func RunDialogRepository(t *testing.T, dialogRepository repository.DialogRepository) {
+	t.Helper()
+
+	runChecks(t, dialogRepository, dialogChecks)
+}
+
+func checkDialogAdd(ctx context.Context, dialogRepository repository.DialogRepository) error {
+	users, err := userIDs(2)
+	if err != nil {
+		return err
+	}
+
+	forwardedFrom, err := dialogRepository.Add(ctx, repository.DialogMessage{From: users[1], To: users[0], Text: "original"})
+	if err != nil {
+		return fmt.Errorf("failed to add dialog message: %w", err)
+	}
+
+	sent := forwardedFrom.Forward(users[0], users[1])
+
+	stored, err := dialogRepository.Add(ctx, sent)
+	if err != nil {
+		return fmt.Errorf("failed to add forwarded dialog message: %w", err)
+	}
+
+	switch {
+	case stored.ID == "" || stored.ID == forwardedFrom.ID:
+		return fmt.Errorf("%w: stored message id %q is not new", errContractViolated, stored.ID)
+	case stored.From != sent.From || stored.To != sent.To || stored.Text != sent.Text:
+		return fmt.Errorf("%w: stored message %+v differs from sent %+v", errContractViolated, *stored, sent)
+	case stored.Type != repository.DialogMessageTypeText:
+		return fmt.Errorf("%w: stored message type is %q, want %q", errContractViolated, stored.Type, repository.DialogMessageTypeText)
+	case stored.Source != repository.DialogMessageSourceDialog:
+		return fmt.Errorf("%w: stored message source is %q, want %q", errContractViolated, stored.Source, repository.DialogMessageSourceDialog)
+	case stored.ForwardedFromMessageID == nil || *stored.ForwardedFromMessageID != forwardedFrom.ID ||
+		stored.ForwardedFromUserID == nil || *stored.ForwardedFromUserID != forwardedFrom.From:
+		return fmt.Errorf("%w: stored message lost the forwarded from reference", errContractViolated)
+	case stored.LegacyID != nil:
+		return fmt.Errorf("%w: new message has legacy id %q", errContractViolated, *stored.LegacyID)
+	case stored.Seq != 2:
+		return fmt.Errorf("%w: second message of the dialog has seq %d", errContractViolated, stored.Seq)
+	case stored.CreatedAt.IsZero():
+		return fmt.Errorf("%w: stored message has no creation time", errContractViolated)
+	}
+
+	return expectMessages(ctx, dialogRepository, users[0], users[1], []repository.DialogMessage{*forwardedFrom, *stored})
+}
+
+func checkDialogOrder(ctx context.Context, dialogRepository repository.DialogRepository) error {
+	users, err := userIDs(2)
+	if err != nil {
+		return err
+	}
+
+	sent, err := sendMessages(ctx, dialogRepository, users[0], users[1], 5)
+	if err != nil {
+		return err
+	}
+
+	for i, dialogMessage := range sent {
+		if dialogMessage.Seq != int64(i+1) {
+			return fmt.Errorf("%w: message %d of the dialog has seq %d", errContractViolated, i+1, dialogMessage.Seq)
+		}
+
+		if i > 0 && dialogMessage.CreatedAt.Before(sent[i-1].CreatedAt) {
+			return fmt.Errorf("%w: message with seq %d is created before the previous one", errContractViolated, dialogMessage.Seq)
+		}
+	}
+
+	return expectMessages(ctx, dialogRepository, users[0], users[1], sent)
+}
+
+func checkDialogDirections(ctx context.Context, dialogRepository repository.DialogRepository) error {
+	users, err := userIDs(2)
+	if err != nil {
+		return err
+	}
+
+	sent, err := sendMessages(ctx, dialogRepository, users[0], users[1], 4)
+	if err != nil {
+		return err
+	}
+
+	if err := expectMessages(ctx, dialogRepository, users[0], users[1], sent); err != nil {
+		return err
+	}
+
+	return expectMessages(ctx, dialogRepository, users[1], users[0], sent)
+}
+
+func checkDialogIsolation(ctx context.Context, dialogRepository repository.DialogRepository) error {
+	users, err := userIDs(3)
+	if err != nil {
+		return err
+	}
+
+	firstDialog, err := sendMessages(ctx, dialogRepository, users[0], users[1], 2)
+	if err != nil {
+		return err
+	}
+
+	secondDialog, err := sendMessages(ctx, dialogRepository, users[0], users[2], 3)
+	if err != nil {
+		return err
+	}
+
+	if secondDialog[0].Seq != 1 {
+		return fmt.Errorf("%w: first message of another dialog of the sender has seq %d", errContractViolated, secondDialog[0].Seq)
+	}
+
+	if err := expectMessages(ctx, dialogRepository, users[0], users[1], firstDialog); err != nil {
+		return err
+	}
+
+	return expectMessages(ctx, dialogRepository, users[2], users[0], secondDialog)
+}
+
+func checkDialogAfterSeq(ctx context.Context, dialogRepository repository.DialogRepository) error {
+	users, err := userIDs(2)
+	if err != nil {
+		return err
+	}
+
+	sent, err := sendMessages(ctx, dialogRepository, users[0], users[1], 5)
+	if err != nil {
+		return err
+	}
+
+	pages := []struct {
+		afterSeq int64
+		limit    int
+		want     []repository.DialogMessage
+	}{
+		{afterSeq: 0, limit: 2, want: sent[:2]},
+		{afterSeq: 2, limit: 2, want: sent[2:4]},
+		{afterSeq: 4, limit: 2, want: sent[4:]},
+		{afterSeq: 1, limit: 0, want: sent[1:]},
+		{afterSeq: 5, limit: 0, want: nil},
+		{afterSeq: 100, limit: 10, want: nil},
+	}
+
+	for _, page := range pages {
+		got, err := dialogRepository.GetDialogMessagesAfterSeq(ctx, users[1], users[0], page.afterSeq, page.limit)
+		if err != nil {
+			return fmt.Errorf("failed to fetch dialog messages after seq %d: %w", page.afterSeq, err)
+		}
+
+		if err := compareMessages(got, page.want); err != nil {
+			return fmt.Errorf("after seq %d with limit %d: %w", page.afterSeq, page.limit, err)
+		}
+	}
+
+	return nil
+}
+
+func checkDialogByIDs(ctx context.Context, dialogRepository repository.DialogRepository) error {
+	users, err := userIDs(3)
+	if err != nil {
+		return err
+	}
+
+	firstDialog, err := sendMessages(ctx, dialogRepository, users[0], users[1], 2)
+	if err != nil {
+		return err
+	}
+
+	secondDialog, err := sendMessages(ctx, dialogRepository, users[2], users[1], 2)
+	if err != nil {
+		return err
+	}
+
+	missingIDs, err := userIDs(1)
+	if err != nil {
+		return err
+	}
+
+	// Requested out of order, with a duplicate and ids that resolve to nothing.
+	messageIDs := []string{secondDialog[1].ID, missingIDs[0], firstDialog[0].ID, secondDialog[1].ID, "not-an-id", firstDialog[1].ID}
+
+	got, err := dialogRepository.GetDialogMessagesByIDs(ctx, messageIDs)
+	if err != nil {
+		return fmt.Errorf("failed to fetch dialog messages by ids: %w", err)
+	}
+
+	return compareMessages(got, []repository.DialogMessage{firstDialog[0], firstDialog[1], secondDialog[1]})
+}
+
+// checkDialogEmpty pins down that a dialog without messages is empty rather than not found.
+func checkDialogEmpty(ctx context.Context, dialogRepository repository.DialogRepository) error {
+	users, err := userIDs(2)
+	if err != nil {
+		return err
+	}
+
+	got, err := dialogRepository.GetDialogMessagesBySenderIDAndReceiverID(ctx, users[0], users[1])
+	if err != nil {
+		return fmt.Errorf("%w: empty dialog returned %w", errContractViolated, err)
+	}
+
+	if len(got) > 0 {
+		return fmt.Errorf("%w: empty dialog returned %d messages", errContractViolated, len(got))
+	}
+
+	got, err = dialogRepository.GetDialogMessagesAfterSeq(ctx, users[0], users[1], 0, 10)
+	if err != nil {
+		return fmt.Errorf("%w: empty dialog page returned %w", errContractViolated, err)
+	}
+
+	if len(got) > 0 {
+		return fmt.Errorf("%w: empty dialog page returned %d messages", errContractViolated, len(got))
+	}
+
+	return nil
+}
+
+// checkDialogMissing pins down that lookups by ids resolving to no message return repository.ErrNotFound.
+func checkDialogMissing(ctx context.Context, dialogRepository repository.DialogRepository) error {
+	missingIDs, err := userIDs(1)
+	if err != nil {
+		return err
+	}
+
+	lookups := []struct {
+		name       string
+		messageIDs []string
+	}{
+		{name: "unknown message id", messageIDs: missingIDs},
+		{name: "unknown legacy id", messageIDs: []string{"2147483647"}},
+		{name: "malformed message id", messageIDs: []string{"not-an-id"}},
+		{name: "no message ids", messageIDs: nil},
+	}
+
+	for _, lookup := range lookups {
+		got, err := dialogRepository.GetDialogMessagesByIDs(ctx, lookup.messageIDs)
+		if len(got) > 0 {
+			return fmt.Errorf("%w: %s returned %d messages", errContractViolated, lookup.name, len(got))
+		}
+
+		if err := expectNotFound(err); err != nil {
+			return fmt.Errorf("%s: %w", lookup.name, err)
+		}
+	}
+
+	return nil
+}
+
+func checkDialogConcurrentAdd(ctx context.Context, dialogRepository repository.DialogRepository) error {
+	users, err := userIDs(2)
+	if err != nil {
+		return err
+	}
+
+	errs := make([]error, concurrentSends)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < concurrentSends; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			from, to := users[i%2], users[(i+1)%2]
+
+			_, errs[i] = dialogRepository.Add(ctx, repository.DialogMessage{From: from, To: to, Text: fmt.Sprintf("concurrent %d", i)})
+		}(i)
+	}
+
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to add dialog messages concurrently: %w", err)
+	}
+
+	got, err := dialogRepository.GetDialogMessagesBySenderIDAndReceiverID(ctx, users[0], users[1])
+	if err != nil {
+		return fmt.Errorf("failed to fetch dialog messages: %w", err)
+	}
+
+	if len(got) != concurrentSends {
+		return fmt.Errorf("%w: %d of %d concurrently sent messages stored", errContractViolated, len(got), concurrentSends)
+	}
+
+	for i, dialogMessage := range got {
+		if dialogMessage.Seq != int64(i+1) {
+			return fmt.Errorf("%w: concurrently sent message %d has seq %d", errContractViolated, i+1, dialogMessage.Seq)
+		}
+	}
+
+	return nil
+}
+
+// sendMessages sends count messages between the users, alternating the sender.
+func sendMessages(ctx context.Context, dialogRepository repository.DialogRepository, userID, peerID string, count int) ([]repository.DialogMessage, error) {
+	sent := make([]repository.DialogMessage, 0, count)
+
+	for i := 0; i < count; i++ {
+		from, to := userID, peerID
+		if i%2 == 1 {
+			from, to = peerID, userID
+		}
+
+		stored, err := dialogRepository.Add(ctx, repository.DialogMessage{From: from, To: to, Text: fmt.Sprintf("message %d", i+1)})
+		if err != nil {
+			return nil, fmt.Errorf("failed to add dialog message: %w", err)
+		}
+
+		sent = append(sent, *stored)
+	}
+
+	return sent, nil
+}
+
+func expectMessages(ctx context.Context, dialogRepository repository.DialogRepository, senderID, receiverID string, want []repository.DialogMessage) error {
+	got, err := dialogRepository.GetDialogMessagesBySenderIDAndReceiverID(ctx, senderID, receiverID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch dialog messages: %w", err)
+	}
+
+	return compareMessages(got, want)
+}
+
+func compareMessages(got, want []repository.DialogMessage) error {
+	if len(got) != len(want) {
+		return fmt.Errorf("%w: got %d messages, want %d", errContractViolated, len(got), len(want))
+	}
+
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			return fmt.Errorf("%w: message %d is %+v, want %+v", errContractViolated, i, got[i], want[i])
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,325 @@
+package contract
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"myfacebook-dialog/internal/repository"
+)
+
+// DialogInboxRepositories are the repositories of the inbox checks, the inbox reads the settings and the drafts of the user.
+type DialogInboxRepositories struct {
+	DialogInboxRepository    repository.DialogInboxRepository
+	DialogSettingsRepository repository.DialogSettingsRepository
+	DialogDraftRepository    repository.DialogDraftRepository
+}
+
+var dialogInboxChecks = []check[DialogInboxRepositories]{
+	{"messages are unread for the receiver only", checkDialogInboxUnread},
+	{"older messages do not replace the last one", checkDialogInboxLastMessage},
+	{"muted dialogs count as unread", checkDialogInboxMuted},
+	{"filters match dialogs without settings by defaults", checkDialogInboxFilters},
+	{"mark read resets the unread count", checkDialogInboxMarkRead},
+	{"cursor pages through the inbox", checkDialogInboxCursor},
+	{"drafts of the user are listed with their dialogs", checkDialogInboxDrafts},
+}
+
+// RunDialogInboxRepository checks the implementation against the contract of repository.DialogInboxRepository.
+func RunDialogInboxRepository(t *testing.T, repositories DialogInboxRepositories) {
+	t.Helper()
+
+	runChecks(t, repositories, dialogInboxChecks)
+}
+
+func checkDialogInboxUnread(ctx context.Context, repositories DialogInboxRepositories) error {
+	users, err := userIDs(2)
+	if err != nil {
+		return err
+	}
+
+	if _, err := recordMessages(ctx, repositories.DialogInboxRepository, users[0], users[1], 2); err != nil {
+		return err
+	}
+
+	if err := expectInbox(ctx, repositories.DialogInboxRepository, users[1], repository.DialogInboxFilter{}, map[string]int{users[0]: 2}); err != nil {
+		return err
+	}
+
+	if err := expectInbox(ctx, repositories.DialogInboxRepository, users[0], repository.DialogInboxFilter{}, map[string]int{users[1]: 0}); err != nil {
+		return err
+	}
+
+	// Replying reads the dialog.
+	if _, err := recordMessages(ctx, repositories.DialogInboxRepository, users[1], users[0], 1); err != nil {
+		return err
+	}
+
+	return expectInbox(ctx, repositories.DialogInboxRepository, users[1], repository.DialogInboxFilter{}, map[string]int{users[0]: 0})
+}
+
+func checkDialogInboxLastMessage(ctx context.Context, repositories DialogInboxRepositories) error {
+	users, err := userIDs(2)
+	if err != nil {
+		return err
+	}
+
+	recorded, err := recordMessages(ctx, repositories.DialogInboxRepository, users[0], users[1], 2)
+	if err != nil {
+		return err
+	}
+
+	// A message recorded late, after the newer one.
+	if err := repositories.DialogInboxRepository.Record(ctx, recorded[0]); err != nil {
+		return fmt.Errorf("failed to record dialog message: %w", err)
+	}
+
+	dialogInbox, err := repositories.DialogInboxRepository.GetDialogInbox(ctx, users[1], repository.DialogInboxFilter{}, nil, 10)
+	if err != nil {
+		return fmt.Errorf("failed to fetch dialog inbox: %w", err)
+	}
+
+	if len(dialogInbox) != 1 || dialogInbox[0].LastMessage.ID != recorded[1].ID || dialogInbox[0].LastMessage.Seq != recorded[1].Seq {
+		return fmt.Errorf("%w: inbox %+v does not end with message %q", errContractViolated, dialogInbox, recorded[1].ID)
+	}
+
+	return nil
+}
+
+func checkDialogInboxMuted(ctx context.Context, repositories DialogInboxRepositories) error {
+	users, err := userIDs(3)
+	if err != nil {
+		return err
+	}
+
+	if _, err := recordMessages(ctx, repositories.DialogInboxRepository, users[1], users[0], 2); err != nil {
+		return err
+	}
+
+	if _, err := recordMessages(ctx, repositories.DialogInboxRepository, users[2], users[0], 1); err != nil {
+		return err
+	}
+
+	mutedUntil := time.Now().Add(time.Hour)
+
+	err = repositories.DialogSettingsRepository.Save(ctx, repository.DialogSettings{UserID: users[0], PeerID: users[1], MutedUntil: &mutedUntil})
+	if err != nil {
+		return fmt.Errorf("failed to save dialog settings: %w", err)
+	}
+
+	muted, notMuted := true, false
+
+	if err := expectInbox(ctx, repositories.DialogInboxRepository, users[0], repository.DialogInboxFilter{Muted: &muted}, map[string]int{users[1]: 2}); err != nil {
+		return err
+	}
+
+	if err := expectInbox(ctx, repositories.DialogInboxRepository, users[0], repository.DialogInboxFilter{Muted: &notMuted}, map[string]int{users[2]: 1}); err != nil {
+		return err
+	}
+
+	unreadCount, err := repositories.DialogInboxRepository.GetUnreadCount(ctx, users[0])
+	if err != nil {
+		return fmt.Errorf("failed to fetch unread count: %w", err)
+	}
+
+	if unreadCount != 3 {
+		return fmt.Errorf("%w: unread count is %d, want 3 with the muted dialog", errContractViolated, unreadCount)
+	}
+
+	return nil
+}
+
+func checkDialogInboxFilters(ctx context.Context, repositories DialogInboxRepositories) error {
+	users, err := userIDs(3)
+	if err != nil {
+		return err
+	}
+
+	for _, peerID := range users[1:] {
+		if _, err := recordMessages(ctx, repositories.DialogInboxRepository, peerID, users[0], 1); err != nil {
+			return err
+		}
+	}
+
+	err = repositories.DialogSettingsRepository.Save(ctx, repository.DialogSettings{UserID: users[0], PeerID: users[1], Archived: true, Folders: []string{"work"}})
+	if err != nil {
+		return fmt.Errorf("failed to save dialog settings: %w", err)
+	}
+
+	archived, notArchived := true, false
+
+	for _, tc := range []struct {
+		filter repository.DialogInboxFilter
+		want   map[string]int
+	}{
+		{repository.DialogInboxFilter{Archived: &archived}, map[string]int{users[1]: 1}},
+		{repository.DialogInboxFilter{Archived: &notArchived}, map[string]int{users[2]: 1}},
+		{repository.DialogInboxFilter{Folder: "work"}, map[string]int{users[1]: 1}},
+		{repository.DialogInboxFilter{Folder: "home"}, map[string]int{}},
+	} {
+		if err := expectInbox(ctx, repositories.DialogInboxRepository, users[0], tc.filter, tc.want); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func checkDialogInboxMarkRead(ctx context.Context, repositories DialogInboxRepositories) error {
+	users, err := userIDs(2)
+	if err != nil {
+		return err
+	}
+
+	if _, err := recordMessages(ctx, repositories.DialogInboxRepository, users[0], users[1], 3); err != nil {
+		return err
+	}
+
+	if err := repositories.DialogInboxRepository.MarkRead(ctx, users[1], users[0]); err != nil {
+		return fmt.Errorf("failed to mark dialog read: %w", err)
+	}
+
+	if err := expectInbox(ctx, repositories.DialogInboxRepository, users[1], repository.DialogInboxFilter{}, map[string]int{users[0]: 0}); err != nil {
+		return err
+	}
+
+	return expectNotFound(repositories.DialogInboxRepository.MarkRead(ctx, users[0], users[0]))
+}
+
+func checkDialogInboxCursor(ctx context.Context, repositories DialogInboxRepositories) error {
+	users, err := userIDs(4)
+	if err != nil {
+		return err
+	}
+
+	for _, peerID := range users[1:] {
+		if _, err := recordMessages(ctx, repositories.DialogInboxRepository, peerID, users[0], 1); err != nil {
+			return err
+		}
+	}
+
+	var (
+		listed []string
+		after  *repository.DialogInboxCursor
+	)
+
+	for page := 0; page < len(users); page++ {
+		dialogInbox, err := repositories.DialogInboxRepository.GetDialogInbox(ctx, users[0], repository.DialogInboxFilter{}, after, 2)
+		if err != nil {
+			return fmt.Errorf("failed to fetch dialog inbox: %w", err)
+		}
+
+		if len(dialogInbox) == 0 {
+			break
+		}
+
+		for _, entry := range dialogInbox {
+			listed = append(listed, entry.PeerID)
+		}
+
+		last := dialogInbox[len(dialogInbox)-1]
+		after = &repository.DialogInboxCursor{LastMessageAt: last.LastMessage.CreatedAt, PeerID: last.PeerID}
+	}
+
+	// The last peer wrote last, the inbox lists it first.
+	want := []string{users[3], users[2], users[1]}
+
+	if fmt.Sprint(listed) != fmt.Sprint(want) {
+		return fmt.Errorf("%w: inbox pages list %v, want %v", errContractViolated, listed, want)
+	}
+
+	return nil
+}
+
+func checkDialogInboxDrafts(ctx context.Context, repositories DialogInboxRepositories) error {
+	users, err := userIDs(3)
+	if err != nil {
+		return err
+	}
+
+	for _, peerID := range users[1:] {
+		if _, err := recordMessages(ctx, repositories.DialogInboxRepository, peerID, users[0], 1); err != nil {
+			return err
+		}
+	}
+
+	// The draft of the peer is private to the peer.
+	for _, draft := range []repository.DialogDraft{
+		{UserID: users[0], PeerID: users[1], Text: "draft", UpdatedAt: time.Now()},
+		{UserID: users[1], PeerID: users[0], Text: "peer draft", UpdatedAt: time.Now()},
+	} {
+		if _, err := repositories.DialogDraftRepository.Save(ctx, draft); err != nil {
+			return fmt.Errorf("failed to save dialog draft: %w", err)
+		}
+	}
+
+	dialogInbox, err := repositories.DialogInboxRepository.GetDialogInbox(ctx, users[0], repository.DialogInboxFilter{}, nil, 10)
+	if err != nil {
+		return fmt.Errorf("failed to fetch dialog inbox: %w", err)
+	}
+
+	drafts := make(map[string]string, len(dialogInbox))
+
+	for _, entry := range dialogInbox {
+		if entry.Draft != nil {
+			drafts[entry.PeerID] = entry.Draft.Text
+		}
+	}
+
+	if want := map[string]string{users[1]: "draft"}; fmt.Sprint(drafts) != fmt.Sprint(want) {
+		return fmt.Errorf("%w: inbox has drafts %v, want %v", errContractViolated, drafts, want)
+	}
+
+	return nil
+}
+
+// recordMessages records count messages of a new dialog a millisecond apart, as a dialog repository would store them.
+func recordMessages(ctx context.Context, dialogInboxRepository repository.DialogInboxRepository, userID, peerID string, count int) ([]repository.DialogMessage, error) {
+	messageIDs, err := userIDs(count)
+	if err != nil {
+		return nil, err
+	}
+
+	recorded := make([]repository.DialogMessage, 0, count)
+
+	for i, messageID := range messageIDs {
+		dialogMessage := repository.DialogMessage{
+			ID:        messageID,
+			From:      userID,
+			To:        peerID,
+			Text:      fmt.Sprintf("message %d", i+1),
+			Seq:       int64(i + 1),
+			CreatedAt: time.Now().Add(time.Duration(i) * time.Millisecond),
+		}
+
+		if err := dialogInboxRepository.Record(ctx, dialogMessage); err != nil {
+			return nil, fmt.Errorf("failed to record dialog message: %w", err)
+		}
+
+		recorded = append(recorded, dialogMessage)
+	}
+
+	return recorded, nil
+}
+
+// expectInbox compares the unread counts of the dialogs listed in the inbox of the user by peer.
+func expectInbox(ctx context.Context, dialogInboxRepository repository.DialogInboxRepository, userID string,
+	filter repository.DialogInboxFilter, want map[string]int,
+) error {
+	dialogInbox, err := dialogInboxRepository.GetDialogInbox(ctx, userID, filter, nil, 10)
+	if err != nil {
+		return fmt.Errorf("failed to fetch dialog inbox: %w", err)
+	}
+
+	got := make(map[string]int, len(dialogInbox))
+	for _, entry := range dialogInbox {
+		got[entry.PeerID] = entry.UnreadCount
+	}
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		return fmt.Errorf("%w: inbox with filter %+v has unread counts %v, want %v", errContractViolated, filter, got, want)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package contract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"myfacebook-dialog/internal/repository"
+)
+
+// UserFixture is a user the checked repository knows, resolved by Token.
+type UserFixture struct {
+	User  repository.User
+	Token string
+}
+
+type userCheck func(ctx context.Context, userRepository repository.UserRepository, fixture UserFixture) error
+
+var userChecks = []struct {
+	name string
+	run  userCheck
+}{
+	{"known user is found by id", checkUserByID},
+	{"known user is found by token", checkUserByToken},
+	{"unknown user id maps to not found", checkUnknownUserID},
+	{"unknown token maps to not found", checkUnknownUserToken},
+}
+
+// RunUserRepository checks the implementation against the contract of repository.UserRepository,
+// the repository must already know the fixture user.
+func RunUserRepository(t *testing.T, userRepository repository.UserRepository, fixture UserFixture) {
+	t.Helper()
+
+	checks := make([]check[repository.UserRepository], 0, len(userChecks))
+
+	for _, c := range userChecks {
+		run := c.run
+
+		checks = append(checks, check[repository.UserRepository]{
+			name: c.name,
+			run: func(ctx context.Context, userRepository repository.UserRepository) error {
+				return run(ctx, userRepository, fixture)
+			},
+		})
+	}
+
+	runChecks(t, userRepository, checks)
+}
+
+func checkUserByID(ctx context.Context, userRepository repository.UserRepository, fixture UserFixture) error {
+	user, err := userRepository.GetUserByID(ctx, fixture.User.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get user by id: %w", err)
+	}
+
+	if user.ID != fixture.User.ID {
+		return fmt.Errorf("%w: got user %q, want %q", errContractViolated, user.ID, fixture.User.ID)
+	}
+
+	return nil
+}
+
+func checkUserByToken(ctx context.Context, userRepository repository.UserRepository, fixture UserFixture) error {
+	user, err := userRepository.GetUserByToken(ctx, fixture.Token)
+	if err != nil {
+		return fmt.Errorf("failed to get user by token: %w", err)
+	}
+
+	if user.ID != fixture.User.ID {
+		return fmt.Errorf("%w: got user %q, want %q", errContractViolated, user.ID, fixture.User.ID)
+	}
+
+	return nil
+}
+
+func checkUnknownUserID(ctx context.Context, userRepository repository.UserRepository, _ UserFixture) error {
+	unknownIDs, err := userIDs(1)
+	if err != nil {
+		return err
+	}
+
+	_, err = userRepository.GetUserByID(ctx, unknownIDs[0])
+
+	return expectNotFound(err)
+}
+
+func checkUnknownUserToken(ctx context.Context, userRepository repository.UserRepository, fixture UserFixture) error {
+	_, err := userRepository.GetUserByToken(ctx, "unknown-"+fixture.Token)
+
+	return expectNotFound(err)
+}
+
+func expectNotFound(err error) error {
+	if !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("%w: got error %v, want %w", errContractViolated, err, repository.ErrNotFound)
+	}
+
+	return nil
+}
@@ -1,15 +1,122 @@
 package repository
 
-import "context"
+import (
+	"context"
+	"time"
+)
+
+// DialogMessageTypeText is the type of messages sent by users.
+const DialogMessageTypeText = "text"
+
+const (
+	// DialogMessageSourceDialog marks messages stored by this service, their legacy ids are ids from before ids became UUIDs.
+	DialogMessageSourceDialog = "dialog"
+	// DialogMessageSourceMonolith marks messages imported from the monolith, their legacy ids are ids of the monolith.
+	DialogMessageSourceMonolith = "monolith"
+)
 
 type DialogMessage struct {
 	ID   string `db:"id"`
 	From string `db:"sender_id"`
 	To   string `db:"receiver_id"`
 	Text string `db:"text"`
+	// Type is set by the storage, messages are stored as DialogMessageTypeText.
+	Type string `db:"message_type"`
+
+	// LegacyID is the integer id of messages stored before ids became UUIDs or of messages imported from the monolith,
+	// it still resolves to the message. Source tells the two apart, the same legacy id may be taken by one message of each.
+	LegacyID *string `db:"legacy_id"`
+	// Source is set by the storage, messages are stored as DialogMessageSourceDialog unless imported.
+	Source string `db:"source"`
+
+	// ForwardedFromUserID and ForwardedFromMessageID point to the original message
+	// of a forwarded copy, both are nil for regular messages.
+	ForwardedFromUserID    *string `db:"forwarded_from_user_id"`
+	ForwardedFromMessageID *string `db:"forwarded_from_message_id"`
+
+	// Seq numbers messages of a dialog in the order they were stored, starting from 1.
+	Seq       int64     `db:"seq"`
+	CreatedAt time.Time `db:"created_at"`
 }
 
 type DialogRepository interface {
-	Add(ctx context.Context, dialog DialogMessage) error
+	// Add stores the message and returns it as stored, with the id and the creation time set.
+	Add(ctx context.Context, dialog DialogMessage) (*DialogMessage, error)
 	GetDialogMessagesBySenderIDAndReceiverID(ctx context.Context, senderID, receiverID string) ([]DialogMessage, error)
+	// GetDialogMessagesAfterSeq returns up to limit messages of the dialog following afterSeq, a zero limit means all of them.
+	GetDialogMessagesAfterSeq(ctx context.Context, senderID, receiverID string, afterSeq int64, limit int) ([]DialogMessage, error)
+	// GetDialogMessagesByIDs resolves both ids and legacy ids, ids that resolve to nothing are skipped.
+	// A legacy id resolves to messages of both sources.
+	// It returns ErrNotFound when none of the ids resolves to a message.
+	GetDialogMessagesByIDs(ctx context.Context, messageIDs []string) ([]DialogMessage, error)
+}
+
+// Dialog identifies a dialog by its participants in the order of DialogParticipants.
+type Dialog struct {
+	FirstUserID  string `db:"first_user_id"`
+	SecondUserID string `db:"second_user_id"`
+}
+
+// DialogParticipants returns dialog participants in a stable order,
+// so both directions of a conversation resolve to the same pair.
+func DialogParticipants(userID, peerID string) (string, string) {
+	if userID < peerID {
+		return userID, peerID
+	}
+
+	return peerID, userID
+}
+
+// IsBetween reports whether the message belongs to the dialog of the given users.
+func (m DialogMessage) IsBetween(userID, peerID string) bool {
+	return (m.From == userID && m.To == peerID) || (m.From == peerID && m.To == userID)
+}
+
+// HasParticipant reports whether the user is the sender or the receiver of the message.
+func (m DialogMessage) HasParticipant(userID string) bool {
+	return m.From == userID || m.To == userID
+}
+
+// Forward returns a copy of the message sent by the user to the peer,
+// it keeps a reference to the very first message of a forwarding chain.
+func (m DialogMessage) Forward(userID, peerID string) DialogMessage {
+	forwarded := DialogMessage{
+		From:                   userID,
+		To:                     peerID,
+		Text:                   m.Text,
+		ForwardedFromUserID:    &m.From,
+		ForwardedFromMessageID: &m.ID,
+	}
+
+	if m.ForwardedFromMessageID != nil {
+		forwarded.ForwardedFromUserID = m.ForwardedFromUserID
+		forwarded.ForwardedFromMessageID = m.ForwardedFromMessageID
+	}
+
+	return forwarded
+}
+
+// IDs returns every id the message resolves by.
+func (m DialogMessage) IDs() []string {
+	if m.LegacyID == nil {
+		return []string{m.ID}
+	}
+
+	return []string{m.ID, *m.LegacyID}
+}
+
+// Equal reports whether both values describe the same stored message.
+func (m DialogMessage) Equal(other DialogMessage) bool {
+	return m.ID == other.ID && equalOptional(m.LegacyID, other.LegacyID) && m.From == other.From && m.To == other.To && m.Text == other.Text && m.Type == other.Type &&
+		m.Source == other.Source && equalOptional(m.ForwardedFromUserID, other.ForwardedFromUserID) &&
+		equalOptional(m.ForwardedFromMessageID, other.ForwardedFromMessageID) &&
+		m.Seq == other.Seq && m.CreatedAt.Equal(other.CreatedAt)
+}
+
+func equalOptional(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
 }
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// DialogArchive is a compressed file with the messages of a dialog created in a month,
+// the messages are moved out of the dialogs table into it.
+type DialogArchive struct {
+	ID           int64  `db:"id"`
+	FirstUserID  string `db:"first_user_id"`
+	SecondUserID string `db:"second_user_id"`
+	// Month is the first day of the month in UTC.
+	Month        time.Time `db:"month"`
+	FirstSeq     int64     `db:"first_seq"`
+	LastSeq      int64     `db:"last_seq"`
+	MessageCount int       `db:"message_count"`
+	ObjectKey    string    `db:"object_key"`
+	// Checksum is the hex encoded SHA-256 of the archive file.
+	Checksum  string    `db:"checksum"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type DialogArchiveRepository interface {
+	// Add records the archive together with the ids of its messages and returns it as stored,
+	// an archive with the same object key is returned as it was stored before.
+	Add(ctx context.Context, dialogArchive DialogArchive, dialogMessages []DialogMessage) (*DialogArchive, error)
+	// GetDialogArchivesAfterSeq returns archives of the dialog holding messages following afterSeq, in seq order.
+	GetDialogArchivesAfterSeq(ctx context.Context, senderID, receiverID string, afterSeq int64) ([]DialogArchive, error)
+	// GetDialogArchivesByMessageIDs returns archives holding any of the messages, resolving both ids and legacy ids.
+	GetDialogArchivesByMessageIDs(ctx context.Context, messageIDs []string) ([]DialogArchive, error)
+	// GetDialogArchivesBetween returns archives of the months starting in [from, to).
+	GetDialogArchivesBetween(ctx context.Context, from, to time.Time) ([]DialogArchive, error)
+	Delete(ctx context.Context, id int64) error
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// DialogDraft is an unsent message of the user, UpdatedAt decides which of concurrent writes wins.
+type DialogDraft struct {
+	UserID    string    `db:"user_id"`
+	PeerID    string    `db:"peer_id"`
+	Text      string    `db:"text"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+type DialogDraftRepository interface {
+	// Save stores the draft unless a newer one is stored already and returns the draft that won.
+	Save(ctx context.Context, draft DialogDraft) (*DialogDraft, error)
+	// Delete removes the draft if it was not updated after the given time.
+	Delete(ctx context.Context, userID, peerID string, updatedBefore time.Time) error
+	GetDialogDraft(ctx context.Context, userID, peerID string) (*DialogDraft, error)
+	// GetDialogDraftsByUserID lists the drafts of the user newest first. The inbox returns the draft of each dialog
+	// for "Draft:" previews, this listing also has drafts of dialogs without messages.
+	GetDialogDraftsByUserID(ctx context.Context, userID string) ([]DialogDraft, error)
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrLegacyIDTaken means a different message was imported with the legacy id.
+	ErrLegacyIDTaken = errors.New("legacy id is taken by another message")
+	// ErrDialogHasNewerMessages means the message would be numbered after newer messages of its dialog.
+	// Reads rely on seq growing with created_at, so messages are imported only in the order of their creation.
+	ErrDialogHasNewerMessages = errors.New("dialog has messages newer than the message")
+)
+
+// DialogImportRejection is a message the repository refused to import.
+type DialogImportRejection struct {
+	DialogMessage DialogMessage
+	Err           error
+}
+
+type DialogImportResult struct {
+	Imported int
+	// Skipped messages were imported before, an imported message with the legacy id has the same content.
+	Skipped  int
+	Rejected []DialogImportRejection
+}
+
+// DialogImportRepository stores messages of the monolith under their legacy ids, as messages of DialogMessageSourceMonolith.
+type DialogImportRepository interface {
+	// ImportDialogMessages stores the messages keeping their ids, legacy ids and creation time, in the order of
+	// their creation. Importing is idempotent through the legacy id: messages stored already are skipped.
+	// No unique constraint backs the legacy id, a partitioned table can only have unique keys with created_at in them.
+	// Imports have to run one at a time, and a message is checked against the database of its own dialog only.
+	ImportDialogMessages(ctx context.Context, dialogMessages []DialogMessage) (DialogImportResult, error)
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// DialogInboxEntry is a dialog in the inbox of the user, with its last message and the private settings of the user.
+type DialogInboxEntry struct {
+	UserID      string
+	PeerID      string
+	LastMessage DialogMessage
+	// UnreadCount counts messages of the peer received after the user last read the dialog, muted dialogs count too.
+	UnreadCount int
+	// Settings hold the defaults when the user stored no settings for the dialog.
+	Settings DialogSettings
+	// Draft is the unsent message of the user in the dialog, clients show it as a "Draft:" preview. It is nil without a draft.
+	Draft *DialogDraft
+}
+
+// DialogInboxFilter narrows down the inbox of a user, nil and empty fields are ignored.
+// Dialogs without settings have the defaults, they are neither archived nor muted and belong to no folder.
+type DialogInboxFilter struct {
+	Archived *bool
+	Muted    *bool
+	Folder   string
+}
+
+// DialogInboxCursor points to the last entry of a page of the inbox, the next page starts after it.
+type DialogInboxCursor struct {
+	LastMessageAt time.Time
+	PeerID        string
+}
+
+type DialogInboxRepository interface {
+	// Record puts the message at the top of the inboxes of both participants and counts it as unread for the receiver,
+	// the sender has read the dialog up to the message. A message older than the last one recorded leaves the top as is.
+	Record(ctx context.Context, dialogMessage DialogMessage) error
+	// MarkRead resets the unread count of the dialog of the user, it returns ErrNotFound when the inbox has no such dialog.
+	MarkRead(ctx context.Context, userID, peerID string) error
+	// GetDialogInbox lists up to limit dialogs of the user newest first, starting after the cursor when it is not nil.
+	GetDialogInbox(ctx context.Context, userID string, filter DialogInboxFilter, after *DialogInboxCursor, limit int) ([]DialogInboxEntry, error)
+	// GetUnreadCount sums the unread counts of all dialogs of the user, muted and archived ones included.
+	GetUnreadCount(ctx context.Context, userID string) (int, error)
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// DialogPartitionRepository manages the monthly partitions of dialog messages.
+type DialogPartitionRepository interface {
+	// EnsureDialogPartitions creates partitions up to monthsAhead months after the current one and returns the created ones.
+	EnsureDialogPartitions(ctx context.Context, monthsAhead int) ([]string, error)
+	// ExpireDialogPartitions detaches partitions of messages created before olderThan only and returns them,
+	// detached partitions are dropped when drop is set.
+	ExpireDialogPartitions(ctx context.Context, olderThan time.Time, drop bool) ([]string, error)
+}
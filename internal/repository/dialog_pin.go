@@ -0,0 +1,37 @@
+package repository
+
+import "context"
+
+type DialogPin struct {
+	MessageID string `db:"message_id"`
+	PinnedBy  string `db:"pinned_by"`
+}
+
+// LiveDialogPinsFunc returns the pins whose messages still exist, pins of deleted messages are stale.
+type LiveDialogPinsFunc func(ctx context.Context, dialogPins []DialogPin) ([]DialogPin, error)
+
+type DialogPinRepository interface {
+	// Add pins the message unless the dialog has limit live pins already. Stale pins do not count towards the limit,
+	// they are deleted on the way.
+	Add(ctx context.Context, userID, peerID string, pin DialogPin, limit int, livePins LiveDialogPinsFunc) error
+	Delete(ctx context.Context, userID, peerID, messageID string) error
+	GetDialogPins(ctx context.Context, userID, peerID string) ([]DialogPin, error)
+}
+
+// StaleDialogPinMessageIDs returns the message ids of the pins that are not live.
+func StaleDialogPinMessageIDs(dialogPins, liveDialogPins []DialogPin) []string {
+	live := make(map[string]struct{}, len(liveDialogPins))
+	for _, dialogPin := range liveDialogPins {
+		live[dialogPin.MessageID] = struct{}{}
+	}
+
+	var staleMessageIDs []string
+
+	for _, dialogPin := range dialogPins {
+		if _, ok := live[dialogPin.MessageID]; !ok {
+			staleMessageIDs = append(staleMessageIDs, dialogPin.MessageID)
+		}
+	}
+
+	return staleMessageIDs
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// DialogPurgeFilter selects messages created before CreatedBefore. When MessageType is set only messages of that type
+// are selected, otherwise messages of every type except ExceptMessageTypes.
+type DialogPurgeFilter struct {
+	CreatedBefore      time.Time
+	MessageType        string
+	ExceptMessageTypes []string
+}
+
+// DialogPurgeRepository deletes dialog messages that are past their retention.
+type DialogPurgeRepository interface {
+	CountDialogMessages(ctx context.Context, filter DialogPurgeFilter) (int64, error)
+	// DeleteDialogMessages deletes up to limit messages selected by the filter and returns how many were deleted.
+	DeleteDialogMessages(ctx context.Context, filter DialogPurgeFilter, limit int) (int64, error)
+}
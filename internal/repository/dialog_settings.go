@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// DialogSettings are private settings of a dialog, they affect only the user who owns them.
+type DialogSettings struct {
+	UserID     string
+	PeerID     string
+	MutedUntil *time.Time
+	Archived   bool
+	Folders    []string
+}
+
+// IsMuted reports whether notifications of the dialog are suppressed at the given time.
+func (s DialogSettings) IsMuted(now time.Time) bool {
+	return s.MutedUntil != nil && s.MutedUntil.After(now)
+}
+
+// DialogSettingsFilter narrows down dialog settings of a user, nil and empty fields are ignored.
+// Dialogs without settings have defaults that are not stored, so no filter matches them. DialogInboxFilter filters dialogs.
+type DialogSettingsFilter struct {
+	Archived *bool
+	Muted    *bool
+	Folder   string
+}
+
+type DialogSettingsRepository interface {
+	Save(ctx context.Context, settings DialogSettings) error
+	Delete(ctx context.Context, userID, peerID string) error
+	GetDialogSettings(ctx context.Context, userID, peerID string) (*DialogSettings, error)
+	// GetDialogSettingsByUserID lists the stored settings of the user, it is not a listing of the dialogs of the user.
+	GetDialogSettingsByUserID(ctx context.Context, userID string, filter DialogSettingsFilter) ([]DialogSettings, error)
+}
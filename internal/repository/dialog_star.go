@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+type DialogStar struct {
+	MessageID string    `db:"message_id"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// DialogStarCursor points to the last star of a page of starred messages, the next page starts after it.
+type DialogStarCursor struct {
+	CreatedAt time.Time
+	MessageID string
+}
+
+type DialogStarRepository interface {
+	Add(ctx context.Context, userID, messageID string) error
+	Delete(ctx context.Context, userID, messageID string) error
+	// DeleteByMessageIDs removes the stars of the user from the messages, stars of deleted messages are stale.
+	DeleteByMessageIDs(ctx context.Context, userID string, messageIDs []string) error
+	// GetDialogStarsByUserID lists up to limit stars of the user newest first, starting after the cursor when it is not nil.
+	GetDialogStarsByUserID(ctx context.Context, userID string, after *DialogStarCursor, limit int) ([]DialogStar, error)
+}
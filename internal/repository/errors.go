@@ -2,4 +2,18 @@ package repository
 
 import "errors"
 
-var ErrNotFound = errors.New("record not found")
+var (
+	ErrNotFound        = errors.New("record not found")
+	ErrPinLimitReached = errors.New("pinned messages limit reached")
+
+	// ErrUnavailable means the storage could not be reached or dropped the connection, a retry may succeed.
+	ErrUnavailable = errors.New("storage unavailable")
+	// ErrTimeout means the operation ran out of its statement timeout.
+	ErrTimeout = errors.New("storage operation timed out")
+	// ErrConflict means a concurrent operation made this one fail, such as a serialization failure or a deadlock.
+	ErrConflict = errors.New("storage operation conflicts with a concurrent one")
+	// ErrAlreadyExists means the record violates a unique constraint.
+	ErrAlreadyExists = errors.New("record already exists")
+	// ErrConstraintViolation means the record does not fit the storage schema, such as a text over the column limit.
+	ErrConstraintViolation = errors.New("record violates a storage constraint")
+)
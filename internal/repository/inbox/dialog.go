@@ -0,0 +1,50 @@
+// Package inbox keeps the inbox of every user in step with the messages stored in the dialogs.
+package inbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"myfacebook-dialog/internal/repository"
+)
+
+// DialogRepository records every stored message in the inboxes of its participants.
+type DialogRepository struct {
+	dialogRepository      repository.DialogRepository
+	dialogInboxRepository repository.DialogInboxRepository
+}
+
+func NewDialogRepository(dialogRepository repository.DialogRepository, dialogInboxRepository repository.DialogInboxRepository) *DialogRepository {
+	return &DialogRepository{
+		dialogRepository:      dialogRepository,
+		dialogInboxRepository: dialogInboxRepository,
+	}
+}
+
+// Add stores the message first, an inbox that failed to follow must not turn a stored message into a failed send.
+// The inbox catches up with the next message of the dialog.
+func (r *DialogRepository) Add(ctx context.Context, dialogMessage repository.DialogMessage) (*repository.DialogMessage, error) {
+	storedDialogMessage, err := r.dialogRepository.Add(ctx, dialogMessage)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	if err := r.dialogInboxRepository.Record(ctx, *storedDialogMessage); err != nil {
+		slog.Warn(fmt.Sprintf("Failed to record dialog message %s in dialog inbox: %s", storedDialogMessage.ID, err))
+	}
+
+	return storedDialogMessage, nil
+}
+
+func (r *DialogRepository) GetDialogMessagesBySenderIDAndReceiverID(ctx context.Context, senderID, receiverID string) ([]repository.DialogMessage, error) {
+	return r.dialogRepository.GetDialogMessagesBySenderIDAndReceiverID(ctx, senderID, receiverID) //nolint:wrapcheck
+}
+
+func (r *DialogRepository) GetDialogMessagesAfterSeq(ctx context.Context, senderID, receiverID string, afterSeq int64, limit int) ([]repository.DialogMessage, error) {
+	return r.dialogRepository.GetDialogMessagesAfterSeq(ctx, senderID, receiverID, afterSeq, limit) //nolint:wrapcheck
+}
+
+func (r *DialogRepository) GetDialogMessagesByIDs(ctx context.Context, messageIDs []string) ([]repository.DialogMessage, error) {
+	return r.dialogRepository.GetDialogMessagesByIDs(ctx, messageIDs) //nolint:wrapcheck
+}
@@ -0,0 +1,11 @@
+package repository
+
+import "context"
+
+// LegacyDialogRepository stores dialogs in the monolith while clients move from its dialog API to the dialog service.
+type LegacyDialogRepository interface {
+	Add(ctx context.Context, dialogMessage DialogMessage) error
+	// GetDialogMessages returns the messages of the dialog between the users in the order they were sent,
+	// LegacyID holds the id of the monolith.
+	GetDialogMessages(ctx context.Context, userID, peerID string) ([]DialogMessage, error)
+}
@@ -0,0 +1,158 @@
+// Package memory keeps repositories in process memory, for tests and local runs that need no database.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"myfacebook-dialog/internal/idgen"
+	"myfacebook-dialog/internal/repository"
+)
+
+// DialogRepository is safe for concurrent use. Messages are copied in and out, callers never share them with the store.
+type DialogRepository struct {
+	mu          sync.RWMutex
+	idGenerator *idgen.UUIDv7
+	// dialogs holds messages of every dialog in seq order.
+	dialogs map[repository.Dialog][]repository.DialogMessage
+	// messages indexes messages by every id they resolve by.
+	messages map[string]repository.DialogMessage
+}
+
+func NewDialogRepository() *DialogRepository {
+	return &DialogRepository{
+		idGenerator: idgen.NewUUIDv7(),
+		dialogs:     make(map[repository.Dialog][]repository.DialogMessage),
+		messages:    make(map[string]repository.DialogMessage),
+	}
+}
+
+func (r *DialogRepository) Add(_ context.Context, dialogMessage repository.DialogMessage) (*repository.DialogMessage, error) {
+	dialog := dialogOf(dialogMessage.From, dialogMessage.To)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// The id is generated under the lock, so ids of a dialog increase with seq.
+	id, err := r.idGenerator.New()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate dialog message id: %w", err)
+	}
+
+	storedDialogMessage := repository.DialogMessage{
+		ID:                     id,
+		From:                   dialogMessage.From,
+		To:                     dialogMessage.To,
+		Text:                   dialogMessage.Text,
+		Type:                   repository.DialogMessageTypeText,
+		Source:                 repository.DialogMessageSourceDialog,
+		ForwardedFromUserID:    copyOptional(dialogMessage.ForwardedFromUserID),
+		ForwardedFromMessageID: copyOptional(dialogMessage.ForwardedFromMessageID),
+		Seq:                    int64(len(r.dialogs[dialog]) + 1),
+		CreatedAt:              time.Now().UTC(),
+	}
+
+	r.dialogs[dialog] = append(r.dialogs[dialog], storedDialogMessage)
+	r.messages[storedDialogMessage.ID] = storedDialogMessage
+
+	return copyDialogMessage(storedDialogMessage), nil
+}
+
+func (r *DialogRepository) GetDialogMessagesBySenderIDAndReceiverID(ctx context.Context, senderID, receiverID string) ([]repository.DialogMessage, error) {
+	return r.GetDialogMessagesAfterSeq(ctx, senderID, receiverID, 0, 0)
+}
+
+func (r *DialogRepository) GetDialogMessagesAfterSeq(_ context.Context, senderID, receiverID string, afterSeq int64, limit int) ([]repository.DialogMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	dialogMessages := r.dialogs[dialogOf(senderID, receiverID)]
+
+	// Seq starts from 1 and has no gaps, so the message with seq afterSeq+1 is at index afterSeq.
+	if afterSeq < 0 {
+		afterSeq = 0
+	}
+
+	if afterSeq >= int64(len(dialogMessages)) {
+		return nil, nil
+	}
+
+	dialogMessages = dialogMessages[afterSeq:]
+
+	if limit > 0 && limit < len(dialogMessages) {
+		dialogMessages = dialogMessages[:limit]
+	}
+
+	result := make([]repository.DialogMessage, 0, len(dialogMessages))
+	for _, dialogMessage := range dialogMessages {
+		result = append(result, *copyDialogMessage(dialogMessage))
+	}
+
+	return result, nil
+}
+
+func (r *DialogRepository) GetDialogMessagesByIDs(_ context.Context, messageIDs []string) ([]repository.DialogMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	found := make(map[string]struct{}, len(messageIDs))
+
+	var result []repository.DialogMessage
+
+	for _, messageID := range messageIDs {
+		dialogMessage, ok := r.messages[messageID]
+		if !ok {
+			continue
+		}
+
+		if _, ok := found[dialogMessage.ID]; ok {
+			continue
+		}
+
+		found[dialogMessage.ID] = struct{}{}
+
+		result = append(result, *copyDialogMessage(dialogMessage))
+	}
+
+	if len(result) == 0 {
+		return nil, repository.ErrNotFound
+	}
+
+	// Ids of one generator increase with time, they order messages created within the same clock tick.
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.Before(result[j].CreatedAt)
+		}
+
+		return result[i].ID < result[j].ID
+	})
+
+	return result, nil
+}
+
+func dialogOf(userID, peerID string) repository.Dialog {
+	firstUserID, secondUserID := repository.DialogParticipants(userID, peerID)
+
+	return repository.Dialog{FirstUserID: firstUserID, SecondUserID: secondUserID}
+}
+
+func copyDialogMessage(dialogMessage repository.DialogMessage) *repository.DialogMessage {
+	dialogMessage.LegacyID = copyOptional(dialogMessage.LegacyID)
+	dialogMessage.ForwardedFromUserID = copyOptional(dialogMessage.ForwardedFromUserID)
+	dialogMessage.ForwardedFromMessageID = copyOptional(dialogMessage.ForwardedFromMessageID)
+
+	return &dialogMessage
+}
+
+func copyOptional(value *string) *string {
+	if value == nil {
+		return nil
+	}
+
+	copied := *value
+
+	return &copied
+}
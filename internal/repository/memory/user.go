@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"myfacebook-dialog/internal/repository"
+)
+
+// UserRepository is safe for concurrent use, users and their tokens are added with Add.
+type UserRepository struct {
+	mu     sync.RWMutex
+	users  map[string]repository.User
+	tokens map[string]string
+}
+
+func NewUserRepository() *UserRepository {
+	return &UserRepository{
+		users:  make(map[string]repository.User),
+		tokens: make(map[string]string),
+	}
+}
+
+// Add stores the user, the user is then resolved by every given token.
+func (r *UserRepository) Add(user repository.User, tokens ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.users[user.ID] = user
+
+	for _, token := range tokens {
+		r.tokens[token] = user.ID
+	}
+}
+
+func (r *UserRepository) GetUserByToken(_ context.Context, token string) (*repository.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	userID, ok := r.tokens[token]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+
+	return r.getUserByID(userID)
+}
+
+func (r *UserRepository) GetUserByID(_ context.Context, userID string) (*repository.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.getUserByID(userID)
+}
+
+func (r *UserRepository) getUserByID(userID string) (*repository.User, error) {
+	user, ok := r.users[userID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,53 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+
+	"myfacebook-dialog/internal/myfacebookapiclient"
+	"myfacebook-dialog/internal/repository"
+)
+
+type LegacyDialogRepository struct {
+	apiClient *myfacebookapiclient.Client
+}
+
+func NewLegacyDialogRepository(apiClient *myfacebookapiclient.Client) *LegacyDialogRepository {
+	return &LegacyDialogRepository{
+		apiClient: apiClient,
+	}
+}
+
+func (r *LegacyDialogRepository) Add(ctx context.Context, dialogMessage repository.DialogMessage) error {
+	err := r.apiClient.SendLegacyDialogMessage(ctx, dialogMessage.From, dialogMessage.To, dialogMessage.Text)
+	if err != nil {
+		return fmt.Errorf("legacydialogrepository failed to add dialog message: %w", err)
+	}
+
+	return nil
+}
+
+func (r *LegacyDialogRepository) GetDialogMessages(ctx context.Context, userID, peerID string) ([]repository.DialogMessage, error) {
+	legacyDialogMessages, err := r.apiClient.ListLegacyDialogMessages(ctx, userID, peerID)
+	if err != nil {
+		return nil, fmt.Errorf("legacydialogrepository failed to get dialog messages: %w", err)
+	}
+
+	dialogMessages := make([]repository.DialogMessage, 0, len(legacyDialogMessages))
+
+	for _, legacyDialogMsg := range legacyDialogMessages {
+		legacyID := legacyDialogMsg.ID
+
+		dialogMessages = append(dialogMessages, repository.DialogMessage{
+			LegacyID:  &legacyID,
+			From:      legacyDialogMsg.From,
+			To:        legacyDialogMsg.To,
+			Text:      legacyDialogMsg.Text,
+			Type:      repository.DialogMessageTypeText,
+			Source:    repository.DialogMessageSourceMonolith,
+			CreatedAt: legacyDialogMsg.CreatedAt,
+		})
+	}
+
+	return dialogMessages, nil
+}
@@ -0,0 +1,38 @@
+package repository
+
+import "context"
+
+// ShardMigrationPhase is the step a move of dialogs from one shard map version to another is at.
+type ShardMigrationPhase string
+
+const (
+	// ShardMigrationPhaseDualWrite sends writes to both shard maps, reads stay on the old one.
+	ShardMigrationPhaseDualWrite ShardMigrationPhase = "dual_write"
+	// ShardMigrationPhaseBackfill copies existing messages to the new shard map.
+	ShardMigrationPhaseBackfill ShardMigrationPhase = "backfill"
+	// ShardMigrationPhaseVerify compares dialogs on both shard maps.
+	ShardMigrationPhaseVerify ShardMigrationPhase = "verify"
+	// ShardMigrationPhaseReadNew serves reads from the new shard map, writes go to it first and are copied to the old one.
+	ShardMigrationPhaseReadNew ShardMigrationPhase = "read_new"
+)
+
+type ShardMigration struct {
+	FromVersion int                 `db:"from_version"`
+	ToVersion   int                 `db:"to_version"`
+	Phase       ShardMigrationPhase `db:"phase"`
+}
+
+// DialogChecksum summarizes the messages of a dialog, equal checksums mean equal dialogs.
+type DialogChecksum struct {
+	FirstUserID  string `db:"first_user_id"`
+	SecondUserID string `db:"second_user_id"`
+	MessageCount int    `db:"message_count"`
+	Checksum     string `db:"checksum"`
+}
+
+type ShardMigrationRepository interface {
+	Save(ctx context.Context, shardMigration ShardMigration) error
+	GetShardMigration(ctx context.Context, toVersion int) (*ShardMigration, error)
+	SaveCheckpoint(ctx context.Context, toVersion, shardIndex int, lastMessageID string) error
+	GetCheckpoint(ctx context.Context, toVersion, shardIndex int) (string, error)
+}
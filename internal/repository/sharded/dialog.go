@@ -0,0 +1,90 @@
+package sharded
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"myfacebook-dialog/internal/repository"
+)
+
+// DialogRepository stores every dialog on a single shard chosen by its participants.
+type DialogRepository struct {
+	shardMap *ShardMap
+}
+
+func NewDialogRepository(shardMap *ShardMap) *DialogRepository {
+	return &DialogRepository{
+		shardMap: shardMap,
+	}
+}
+
+func (r *DialogRepository) Add(ctx context.Context, dialogMessage repository.DialogMessage) (*repository.DialogMessage, error) {
+	storedDialogMessage, err := r.shardMap.Shard(dialogMessage.From, dialogMessage.To).Add(ctx, dialogMessage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add dialog message to shard: %w", err)
+	}
+
+	return storedDialogMessage, nil
+}
+
+func (r *DialogRepository) GetDialogMessagesBySenderIDAndReceiverID(ctx context.Context, senderID, receiverID string) ([]repository.DialogMessage, error) {
+	dialogMessages, err := r.shardMap.Shard(senderID, receiverID).GetDialogMessagesBySenderIDAndReceiverID(ctx, senderID, receiverID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dialog messages from shard: %w", err)
+	}
+
+	return dialogMessages, nil
+}
+
+func (r *DialogRepository) GetDialogMessagesAfterSeq(ctx context.Context, senderID, receiverID string, afterSeq int64, limit int) ([]repository.DialogMessage, error) {
+	dialogMessages, err := r.shardMap.Shard(senderID, receiverID).GetDialogMessagesAfterSeq(ctx, senderID, receiverID, afterSeq, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dialog messages from shard: %w", err)
+	}
+
+	return dialogMessages, nil
+}
+
+// GetDialogMessagesByIDs asks every shard, since a message id alone does not tell where the message is stored.
+func (r *DialogRepository) GetDialogMessagesByIDs(ctx context.Context, messageIDs []string) ([]repository.DialogMessage, error) {
+	return getDialogMessagesByIDs(ctx, r.shardMap, messageIDs)
+}
+
+func getDialogMessagesByIDs(ctx context.Context, shardMap *ShardMap, messageIDs []string) ([]repository.DialogMessage, error) {
+	shards := shardMap.Shards()
+
+	results := make([][]repository.DialogMessage, len(shards))
+	errs := make([]error, len(shards))
+
+	var wg sync.WaitGroup
+
+	for i, shard := range shards {
+		wg.Add(1)
+
+		go func(i int, shard Shard) {
+			defer wg.Done()
+
+			results[i], errs[i] = shard.GetDialogMessagesByIDs(ctx, messageIDs)
+		}(i, shard)
+	}
+
+	wg.Wait()
+
+	var dialogMessages []repository.DialogMessage
+
+	for i := range shards {
+		if errs[i] != nil && !errors.Is(errs[i], repository.ErrNotFound) {
+			return nil, fmt.Errorf("failed to fetch dialog messages by ids from shard %d: %w", i, errs[i])
+		}
+
+		dialogMessages = append(dialogMessages, results[i]...)
+	}
+
+	if len(dialogMessages) == 0 {
+		return nil, repository.ErrNotFound
+	}
+
+	return dialogMessages, nil
+}
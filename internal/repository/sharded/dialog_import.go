@@ -0,0 +1,59 @@
+package sharded
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"myfacebook-dialog/internal/repository"
+)
+
+var ErrShardCountMismatch = errors.New("import repositories do not match the shards of the shard map")
+
+// DialogImportRepository imports every message into the shard of its dialog. A legacy id is unique within a shard:
+// the same message always goes to the same shard, a different message with its legacy id on another shard is not seen.
+type DialogImportRepository struct {
+	shardMap           *ShardMap
+	importRepositories []repository.DialogImportRepository
+}
+
+// NewDialogImportRepository takes an import repository for every shard of the map, in the order of the shards.
+func NewDialogImportRepository(shardMap *ShardMap, importRepositories []repository.DialogImportRepository) (*DialogImportRepository, error) {
+	if len(importRepositories) != len(shardMap.Shards()) {
+		return nil, fmt.Errorf("%w: %d repositories for %d shards", ErrShardCountMismatch, len(importRepositories), len(shardMap.Shards()))
+	}
+
+	return &DialogImportRepository{
+		shardMap:           shardMap,
+		importRepositories: importRepositories,
+	}, nil
+}
+
+// ImportDialogMessages imports the messages shard by shard, a failed shard leaves the shards before it imported.
+func (r *DialogImportRepository) ImportDialogMessages(ctx context.Context, dialogMessages []repository.DialogMessage) (repository.DialogImportResult, error) {
+	shardDialogMessages := make(map[int][]repository.DialogMessage)
+
+	for _, dialogMsg := range dialogMessages {
+		shardIndex := r.shardMap.ShardIndex(dialogMsg.From, dialogMsg.To)
+		shardDialogMessages[shardIndex] = append(shardDialogMessages[shardIndex], dialogMsg)
+	}
+
+	var result repository.DialogImportResult
+
+	for shardIndex, importRepository := range r.importRepositories {
+		if len(shardDialogMessages[shardIndex]) == 0 {
+			continue
+		}
+
+		shardResult, err := importRepository.ImportDialogMessages(ctx, shardDialogMessages[shardIndex])
+		if err != nil {
+			return result, fmt.Errorf("failed to import dialog messages to shard %d: %w", shardIndex, err)
+		}
+
+		result.Imported += shardResult.Imported
+		result.Skipped += shardResult.Skipped
+		result.Rejected = append(result.Rejected, shardResult.Rejected...)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,339 @@
+package sharded
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"myfacebook-dialog/internal/repository"
+)
+
+type ResharderConfig struct {
+	BatchSize int
+	// DualWriteWait is how long running instances need to pick the dual write phase up.
+	DualWriteWait  time.Duration
+	VerifyAttempts int
+}
+
+// Resharder moves dialogs from the current shard map to the next one without downtime.
+// Every step is persisted, so a crashed run continues where it stopped.
+type Resharder struct {
+	current                  *ShardMap
+	next                     *ShardMap
+	shardMigrationRepository repository.ShardMigrationRepository
+	config                   ResharderConfig
+}
+
+type dialogKey struct {
+	firstUserID  string
+	secondUserID string
+}
+
+func NewResharder(current, next *ShardMap, shardMigrationRepository repository.ShardMigrationRepository, config ResharderConfig) *Resharder {
+	return &Resharder{
+		current:                  current,
+		next:                     next,
+		shardMigrationRepository: shardMigrationRepository,
+		config:                   config,
+	}
+}
+
+func (r *Resharder) Run(ctx context.Context) error {
+	shardMigration, err := r.shardMigrationRepository.GetShardMigration(ctx, r.next.Version())
+	if err != nil {
+		if !errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("failed to fetch shard migration: %w", err)
+		}
+
+		shardMigration = &repository.ShardMigration{
+			FromVersion: r.current.Version(),
+			ToVersion:   r.next.Version(),
+			Phase:       repository.ShardMigrationPhaseDualWrite,
+		}
+
+		if err := r.shardMigrationRepository.Save(ctx, *shardMigration); err != nil {
+			return fmt.Errorf("failed to start shard migration: %w", err)
+		}
+	}
+
+	if shardMigration.FromVersion != r.current.Version() {
+		return fmt.Errorf("%w: migration to version %d starts from version %d, current version is %d", ErrShardMapVersionMismatch,
+			shardMigration.ToVersion, shardMigration.FromVersion, r.current.Version())
+	}
+
+	for {
+		slog.Info(fmt.Sprintf("Shard migration from version %d to %d is in phase %q", r.current.Version(), r.next.Version(), shardMigration.Phase))
+
+		var nextPhase repository.ShardMigrationPhase
+
+		switch shardMigration.Phase {
+		case repository.ShardMigrationPhaseDualWrite:
+			nextPhase = repository.ShardMigrationPhaseBackfill
+			err = r.waitForDualWrite(ctx)
+		case repository.ShardMigrationPhaseBackfill:
+			nextPhase = repository.ShardMigrationPhaseVerify
+			err = r.backfill(ctx)
+		case repository.ShardMigrationPhaseVerify:
+			nextPhase = repository.ShardMigrationPhaseReadNew
+			err = r.verify(ctx)
+		case repository.ShardMigrationPhaseReadNew:
+			slog.Info(fmt.Sprintf("Reads are served by shard map version %d, "+
+				"switch DB_SHARD_HOSTS and DB_SHARD_MAP_VERSION to it and unset DB_NEXT_SHARD_HOSTS", r.next.Version()))
+
+			return nil
+		default:
+			return fmt.Errorf("unknown shard migration phase %q", shardMigration.Phase)
+		}
+
+		if err != nil {
+			return fmt.Errorf("shard migration phase %q failed: %w", shardMigration.Phase, err)
+		}
+
+		shardMigration.Phase = nextPhase
+
+		if err := r.shardMigrationRepository.Save(ctx, *shardMigration); err != nil {
+			return fmt.Errorf("failed to save shard migration phase: %w", err)
+		}
+	}
+}
+
+// waitForDualWrite gives every instance time to start writing to both shard maps,
+// messages written before that are picked up by the backfill.
+func (r *Resharder) waitForDualWrite(ctx context.Context) error {
+	slog.Info(fmt.Sprintf("Waiting %s for instances to enable dual write", r.config.DualWriteWait))
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("dual write wait interrupted: %w", ctx.Err())
+	case <-time.After(r.config.DualWriteWait):
+		return nil
+	}
+}
+
+func (r *Resharder) backfill(ctx context.Context) error {
+	for shardIndex, shard := range r.current.Shards() {
+		lastMessageID, err := r.shardMigrationRepository.GetCheckpoint(ctx, r.next.Version(), shardIndex)
+		if err != nil {
+			return fmt.Errorf("failed to fetch checkpoint of shard %d: %w", shardIndex, err)
+		}
+
+		copied := 0
+
+		for {
+			dialogMessages, err := shard.GetDialogMessagesAfterID(ctx, lastMessageID, r.config.BatchSize)
+			if err != nil {
+				return fmt.Errorf("failed to read shard %d after message %s: %w", shardIndex, lastMessageID, err)
+			}
+
+			if len(dialogMessages) == 0 {
+				break
+			}
+
+			if err := r.copyToNext(ctx, dialogMessages); err != nil {
+				return fmt.Errorf("failed to copy shard %d after message %s: %w", shardIndex, lastMessageID, err)
+			}
+
+			lastMessageID = dialogMessages[len(dialogMessages)-1].ID
+
+			if err := r.shardMigrationRepository.SaveCheckpoint(ctx, r.next.Version(), shardIndex, lastMessageID); err != nil {
+				return fmt.Errorf("failed to save checkpoint of shard %d: %w", shardIndex, err)
+			}
+
+			copied += len(dialogMessages)
+
+			slog.Info(fmt.Sprintf("Backfill of shard %d: copied %d messages, checkpoint %s", shardIndex, copied, lastMessageID))
+		}
+	}
+
+	return nil
+}
+
+func (r *Resharder) copyToNext(ctx context.Context, dialogMessages []repository.DialogMessage) error {
+	dialogMessagesByShard := make(map[int][]repository.DialogMessage)
+
+	for _, dialogMsg := range dialogMessages {
+		shardIndex := r.next.ShardIndex(dialogMsg.From, dialogMsg.To)
+		dialogMessagesByShard[shardIndex] = append(dialogMessagesByShard[shardIndex], dialogMsg)
+	}
+
+	for shardIndex, shardDialogMessages := range dialogMessagesByShard {
+		if err := r.next.Shards()[shardIndex].CopyDialogMessages(ctx, shardDialogMessages); err != nil {
+			return fmt.Errorf("failed to copy messages to shard %d of version %d: %w", shardIndex, r.next.Version(), err)
+		}
+	}
+
+	return nil
+}
+
+// verify compares every dialog on both shard maps by its message count and checksum.
+// Dialogs that differ are copied again, since dual writes keep coming while checksums are taken.
+func (r *Resharder) verify(ctx context.Context) error {
+	mismatched, err := r.findMismatchedDialogs(ctx)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 1; len(mismatched) > 0 && attempt <= r.config.VerifyAttempts; attempt++ {
+		slog.Info(fmt.Sprintf("Verification attempt %d: %d dialogs differ, repairing", attempt, len(mismatched)))
+
+		var stillMismatched []dialogKey
+
+		for _, key := range mismatched {
+			equal, err := r.repairDialog(ctx, key)
+			if err != nil {
+				return err
+			}
+
+			if !equal {
+				stillMismatched = append(stillMismatched, key)
+			}
+		}
+
+		mismatched = stillMismatched
+	}
+
+	if len(mismatched) > 0 {
+		for _, key := range mismatched {
+			slog.Error(fmt.Sprintf("Dialog of %s and %s differs between shard map versions %d and %d",
+				key.firstUserID, key.secondUserID, r.current.Version(), r.next.Version()))
+		}
+
+		return fmt.Errorf("%w: %d dialogs", ErrDialogsMismatch, len(mismatched))
+	}
+
+	slog.Info("All dialogs match")
+
+	return nil
+}
+
+func (r *Resharder) findMismatchedDialogs(ctx context.Context) ([]dialogKey, error) {
+	currentChecksums, err := shardMapChecksums(ctx, r.current)
+	if err != nil {
+		return nil, err
+	}
+
+	nextChecksums, err := shardMapChecksums(ctx, r.next)
+	if err != nil {
+		return nil, err
+	}
+
+	var mismatched []dialogKey
+
+	for key, currentChecksum := range currentChecksums {
+		if nextChecksums[key] != currentChecksum {
+			mismatched = append(mismatched, key)
+		}
+	}
+
+	for key := range nextChecksums {
+		if _, ok := currentChecksums[key]; !ok {
+			mismatched = append(mismatched, key)
+		}
+	}
+
+	slog.Info(fmt.Sprintf("Compared %d dialogs, %d differ", len(currentChecksums), len(mismatched)))
+
+	return mismatched, nil
+}
+
+// shardMapChecksums collects checksums of the dialogs every shard owns in the shard map,
+// leftovers of dialogs that moved away from a shard are skipped.
+func shardMapChecksums(ctx context.Context, shardMap *ShardMap) (map[dialogKey]repository.DialogChecksum, error) {
+	checksums := make(map[dialogKey]repository.DialogChecksum)
+
+	for shardIndex, shard := range shardMap.Shards() {
+		dialogChecksums, err := shard.GetDialogChecksums(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch dialog checksums of shard %d of version %d: %w", shardIndex, shardMap.Version(), err)
+		}
+
+		for _, dialogChecksum := range dialogChecksums {
+			if shardMap.ShardIndex(dialogChecksum.FirstUserID, dialogChecksum.SecondUserID) != shardIndex {
+				continue
+			}
+
+			checksums[dialogKey{firstUserID: dialogChecksum.FirstUserID, secondUserID: dialogChecksum.SecondUserID}] = dialogChecksum
+		}
+	}
+
+	return checksums, nil
+}
+
+// repairDialog makes the dialog on the next shard map equal to the current one and reports whether both are equal now.
+// Messages missing from the current shard map, like the ones purged by the retention, are deleted from the next one.
+// The next shard map is read first: writes reach the current shard map before the next one, so a message found there
+// and not in the current shard map read afterwards is not just being written.
+func (r *Resharder) repairDialog(ctx context.Context, key dialogKey) (bool, error) {
+	nextShard := r.next.Shard(key.firstUserID, key.secondUserID)
+
+	nextDialogMessages, err := nextShard.GetDialogMessagesBySenderIDAndReceiverID(ctx, key.firstUserID, key.secondUserID)
+	if err != nil {
+		return false, fmt.Errorf("failed to read dialog from shard map version %d: %w", r.next.Version(), err)
+	}
+
+	currentDialogMessages, err := r.current.Shard(key.firstUserID, key.secondUserID).
+		GetDialogMessagesBySenderIDAndReceiverID(ctx, key.firstUserID, key.secondUserID)
+	if err != nil {
+		return false, fmt.Errorf("failed to read dialog from shard map version %d: %w", r.current.Version(), err)
+	}
+
+	currentIDs := make(map[string]struct{}, len(currentDialogMessages))
+	for _, dialogMsg := range currentDialogMessages {
+		currentIDs[dialogMsg.ID] = struct{}{}
+	}
+
+	var extraDialogMessages []repository.DialogMessage
+
+	for _, dialogMsg := range nextDialogMessages {
+		if _, ok := currentIDs[dialogMsg.ID]; !ok {
+			extraDialogMessages = append(extraDialogMessages, dialogMsg)
+		}
+	}
+
+	deleted, err := nextShard.DeleteDialogMessages(ctx, extraDialogMessages)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete extra messages of dialog from shard map version %d: %w", r.next.Version(), err)
+	}
+
+	if deleted > 0 {
+		slog.Info(fmt.Sprintf("Deleted %d messages of dialog of %s and %s missing from shard map version %d",
+			deleted, key.firstUserID, key.secondUserID, r.current.Version()))
+	}
+
+	if err := nextShard.CopyDialogMessages(ctx, currentDialogMessages); err != nil {
+		return false, fmt.Errorf("failed to copy dialog to shard map version %d: %w", r.next.Version(), err)
+	}
+
+	nextDialogMessages, err = nextShard.GetDialogMessagesBySenderIDAndReceiverID(ctx, key.firstUserID, key.secondUserID)
+	if err != nil {
+		return false, fmt.Errorf("failed to read dialog from shard map version %d: %w", r.next.Version(), err)
+	}
+
+	return equalDialogMessages(currentDialogMessages, nextDialogMessages), nil
+}
+
+func equalDialogMessages(a, b []repository.DialogMessage) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	sortByID := func(dialogMessages []repository.DialogMessage) {
+		sort.Slice(dialogMessages, func(i, j int) bool {
+			return dialogMessages[i].ID < dialogMessages[j].ID
+		})
+	}
+
+	sortByID(a)
+	sortByID(b)
+
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,145 @@
+package sharded
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"myfacebook-dialog/internal/repository"
+)
+
+// ReshardingDialogRepository serves dialogs while they are moved from the current shard map to the next one.
+// It follows the phase of the shard migration driven by the resharding command.
+type ReshardingDialogRepository struct {
+	current                  *ShardMap
+	next                     *ShardMap
+	shardMigrationRepository repository.ShardMigrationRepository
+
+	mu    sync.RWMutex
+	phase repository.ShardMigrationPhase
+}
+
+func NewReshardingDialogRepository(current, next *ShardMap, shardMigrationRepository repository.ShardMigrationRepository) *ReshardingDialogRepository {
+	return &ReshardingDialogRepository{
+		current:                  current,
+		next:                     next,
+		shardMigrationRepository: shardMigrationRepository,
+	}
+}
+
+// RefreshPhase loads the phase of the shard migration, no migration means the current shard map only.
+func (r *ReshardingDialogRepository) RefreshPhase(ctx context.Context) error {
+	var phase repository.ShardMigrationPhase
+
+	shardMigration, err := r.shardMigrationRepository.GetShardMigration(ctx, r.next.Version())
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("failed to fetch shard migration: %w", err)
+	}
+
+	if shardMigration != nil {
+		if shardMigration.FromVersion != r.current.Version() {
+			return fmt.Errorf("%w: migration to version %d starts from version %d, current version is %d", ErrShardMapVersionMismatch,
+				shardMigration.ToVersion, shardMigration.FromVersion, r.current.Version())
+		}
+
+		phase = shardMigration.Phase
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.phase != phase {
+		slog.Info(fmt.Sprintf("Shard migration from version %d to %d is in phase %q",
+			r.current.Version(), r.next.Version(), phase))
+	}
+
+	r.phase = phase
+
+	return nil
+}
+
+// Watch refreshes the phase of the shard migration until the context is done.
+func (r *ReshardingDialogRepository) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.RefreshPhase(ctx); err != nil {
+				slog.Error(fmt.Sprintf("Failed to refresh shard migration phase: %s", err))
+			}
+		}
+	}
+}
+
+// Add writes the message to the shard map reads are served by and copies it to the other one during a shard migration.
+// A lost copy to the next shard map is found and repaired by the verification of the resharding command, a lost copy
+// to the current one only matters when the migration is rolled back.
+func (r *ReshardingDialogRepository) Add(ctx context.Context, dialogMessage repository.DialogMessage) (*repository.DialogMessage, error) {
+	phase := r.getPhase()
+
+	primary, secondary := r.current, r.next
+	if phase == repository.ShardMigrationPhaseReadNew {
+		primary, secondary = r.next, r.current
+	}
+
+	storedDialogMessage, err := primary.Shard(dialogMessage.From, dialogMessage.To).Add(ctx, dialogMessage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add dialog message to shard: %w", err)
+	}
+
+	if phase == "" {
+		return storedDialogMessage, nil
+	}
+
+	// Copies keep their seq, the sequence of the dialog on the other shard map moves past it.
+	err = secondary.Shard(dialogMessage.From, dialogMessage.To).CopyDialogMessages(ctx, []repository.DialogMessage{*storedDialogMessage})
+	if err != nil {
+		slog.Error(fmt.Sprintf("Failed to copy dialog message %s to shard map version %d: %s", storedDialogMessage.ID, secondary.Version(), err))
+	}
+
+	return storedDialogMessage, nil
+}
+
+func (r *ReshardingDialogRepository) GetDialogMessagesBySenderIDAndReceiverID(ctx context.Context, senderID, receiverID string) ([]repository.DialogMessage, error) {
+	dialogMessages, err := r.readShardMap().Shard(senderID, receiverID).GetDialogMessagesBySenderIDAndReceiverID(ctx, senderID, receiverID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dialog messages from shard: %w", err)
+	}
+
+	return dialogMessages, nil
+}
+
+func (r *ReshardingDialogRepository) GetDialogMessagesAfterSeq(ctx context.Context, senderID, receiverID string, afterSeq int64, limit int) ([]repository.DialogMessage, error) {
+	dialogMessages, err := r.readShardMap().Shard(senderID, receiverID).GetDialogMessagesAfterSeq(ctx, senderID, receiverID, afterSeq, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dialog messages from shard: %w", err)
+	}
+
+	return dialogMessages, nil
+}
+
+func (r *ReshardingDialogRepository) GetDialogMessagesByIDs(ctx context.Context, messageIDs []string) ([]repository.DialogMessage, error) {
+	return getDialogMessagesByIDs(ctx, r.readShardMap(), messageIDs)
+}
+
+func (r *ReshardingDialogRepository) getPhase() repository.ShardMigrationPhase {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.phase
+}
+
+func (r *ReshardingDialogRepository) readShardMap() *ShardMap {
+	if r.getPhase() == repository.ShardMigrationPhaseReadNew {
+		return r.next
+	}
+
+	return r.current
+}
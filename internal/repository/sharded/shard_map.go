@@ -0,0 +1,70 @@
+package sharded
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+
+	"myfacebook-dialog/internal/repository"
+)
+
+var (
+	ErrNoShards                = errors.New("shard map has no shards")
+	ErrShardMapVersionMismatch = errors.New("shard map version mismatch")
+	ErrDialogsMismatch         = errors.New("dialogs differ between shard maps")
+)
+
+// Shard is a dialog repository on a single database that can take part in resharding.
+type Shard interface {
+	repository.DialogRepository
+	GetDialogMessagesAfterID(ctx context.Context, afterID string, limit int) ([]repository.DialogMessage, error)
+	CopyDialogMessages(ctx context.Context, dialogMessages []repository.DialogMessage) error
+	DeleteDialogMessages(ctx context.Context, dialogMessages []repository.DialogMessage) (int64, error)
+	GetDialogChecksums(ctx context.Context) ([]repository.DialogChecksum, error)
+}
+
+// ShardMap routes dialogs to shards by their participants.
+// Routing of a version never changes: a different set of shards needs a new version and resharding.
+type ShardMap struct {
+	version int
+	shards  []Shard
+}
+
+func NewShardMap(version int, shards []Shard) (*ShardMap, error) {
+	if len(shards) == 0 {
+		return nil, ErrNoShards
+	}
+
+	return &ShardMap{
+		version: version,
+		shards:  shards,
+	}, nil
+}
+
+func (m *ShardMap) Version() int {
+	return m.version
+}
+
+func (m *ShardMap) Shards() []Shard {
+	return m.shards
+}
+
+// ShardIndex returns the index of the shard that stores the dialog of the given users.
+func (m *ShardMap) ShardIndex(userID, peerID string) int {
+	return int(ShardKey(userID, peerID) % uint32(len(m.shards)))
+}
+
+func (m *ShardMap) Shard(userID, peerID string) Shard { //nolint:ireturn
+	return m.shards[m.ShardIndex(userID, peerID)]
+}
+
+// ShardKey is a stable hash of the unordered pair of participants,
+// both directions of a dialog get the same key.
+func ShardKey(userID, peerID string) uint32 {
+	firstUserID, secondUserID := repository.DialogParticipants(userID, peerID)
+
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(firstUserID + ":" + secondUserID))
+
+	return hash.Sum32()
+}
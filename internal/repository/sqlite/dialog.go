@@ -0,0 +1,148 @@
+// Package sqlite stores dialogs in an embedded SQLite database, for deployments that run on a single node without Postgres.
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"myfacebook-dialog/internal/db"
+	"myfacebook-dialog/internal/idgen"
+	"myfacebook-dialog/internal/repository"
+)
+
+const dialogMessageColumns = `id, legacy_id, source, sender_id, receiver_id, text, message_type, forwarded_from_user_id, forwarded_from_message_id, seq, created_at`
+
+// noLimit makes SQLite return every row.
+const noLimit = -1
+
+var legacyIDRegexp = regexp.MustCompile(`^[0-9]+$`)
+
+type DialogRepository struct {
+	db          *db.DB
+	idGenerator *idgen.UUIDv7
+}
+
+func NewDialogRepository(db *db.DB) *DialogRepository {
+	return &DialogRepository{
+		db:          db,
+		idGenerator: idgen.NewUUIDv7(),
+	}
+}
+
+// Add numbers the message in the same statement that stores it, SQLite runs one write at a time.
+func (r *DialogRepository) Add(ctx context.Context, dialogMessage repository.DialogMessage) (*repository.DialogMessage, error) {
+	dbConn := r.db.GetConnection(ctx)
+
+	id, err := r.idGenerator.New()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate dialog message id: %w", err)
+	}
+
+	dialogKey := makeDialogKey(dialogMessage.From, dialogMessage.To)
+
+	sqlQuery := `INSERT INTO dialogs (id, sender_id, receiver_id, dialog_key, text, forwarded_from_user_id, forwarded_from_message_id, seq, created_at) 
+		SELECT ?, ?, ?, ?, ?, ?, ?, coalesce(max(seq), 0) + 1, ? FROM dialogs WHERE dialog_key = ? 
+		RETURNING ` + dialogMessageColumns
+
+	var storedDialogMessage repository.DialogMessage
+
+	err = dbConn.GetContext(ctx, &storedDialogMessage, sqlQuery, id, dialogMessage.From, dialogMessage.To, dialogKey, dialogMessage.Text,
+		dialogMessage.ForwardedFromUserID, dialogMessage.ForwardedFromMessageID, time.Now().UTC(), dialogKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add dialog mesage to db: %w", err)
+	}
+
+	return &storedDialogMessage, nil
+}
+
+func (r *DialogRepository) GetDialogMessagesBySenderIDAndReceiverID(ctx context.Context, senderID, receiverID string) ([]repository.DialogMessage, error) {
+	return r.GetDialogMessagesAfterSeq(ctx, senderID, receiverID, 0, 0)
+}
+
+func (r *DialogRepository) GetDialogMessagesAfterSeq(ctx context.Context, senderID, receiverID string, afterSeq int64, limit int) ([]repository.DialogMessage, error) {
+	dbConn := r.db.GetRetryableConnection(ctx)
+
+	if limit == 0 {
+		limit = noLimit
+	}
+
+	var dialogMessages []repository.DialogMessage
+
+	sqlQuery := `SELECT ` + dialogMessageColumns + ` 
+		FROM dialogs WHERE dialog_key = ? AND seq > ? 
+		ORDER BY seq LIMIT ?`
+
+	err := dbConn.SelectContext(ctx, &dialogMessages, sqlQuery, makeDialogKey(senderID, receiverID), afterSeq, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dialog messages after seq: %w", err)
+	}
+
+	return dialogMessages, nil
+}
+
+func (r *DialogRepository) GetDialogMessagesByIDs(ctx context.Context, messageIDs []string) ([]repository.DialogMessage, error) {
+	dbConn := r.db.GetRetryableConnection(ctx)
+
+	var (
+		ids, legacyIDs []string
+		conditions     []string
+		args           []interface{}
+	)
+
+	for _, messageID := range messageIDs {
+		if legacyIDRegexp.MatchString(messageID) {
+			legacyIDs = append(legacyIDs, messageID)
+		} else {
+			ids = append(ids, messageID)
+		}
+	}
+
+	if len(ids) > 0 {
+		conditions = append(conditions, "id IN (?)")
+		args = append(args, ids)
+	}
+
+	if len(legacyIDs) > 0 {
+		conditions = append(conditions, "legacy_id IN (?)")
+		args = append(args, legacyIDs)
+	}
+
+	if len(conditions) == 0 {
+		return nil, repository.ErrNotFound
+	}
+
+	sqlQuery, args, err := sqlx.In(`SELECT `+dialogMessageColumns+` 
+		FROM dialogs WHERE `+strings.Join(conditions, " OR "), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build dialog messages by ids query: %w", err)
+	}
+
+	var dialogMessages []repository.DialogMessage
+
+	err = dbConn.SelectContext(ctx, &dialogMessages, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dialog messages by ids: %w", err)
+	}
+
+	if len(dialogMessages) == 0 {
+		return nil, repository.ErrNotFound
+	}
+
+	sort.Slice(dialogMessages, func(i, j int) bool {
+		return dialogMessages[i].CreatedAt.Before(dialogMessages[j].CreatedAt)
+	})
+
+	return dialogMessages, nil
+}
+
+// makeDialogKey matches make_dialog_key of the Postgres schema.
+func makeDialogKey(userID, peerID string) string {
+	firstUserID, secondUserID := repository.DialogParticipants(userID, peerID)
+
+	return firstUserID + ":" + secondUserID
+}
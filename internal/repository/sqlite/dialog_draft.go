@@ -0,0 +1,89 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"myfacebook-dialog/internal/db"
+	"myfacebook-dialog/internal/repository"
+)
+
+type DialogDraftRepository struct {
+	db *db.DB
+}
+
+func NewDialogDraftRepository(db *db.DB) *DialogDraftRepository {
+	return &DialogDraftRepository{
+		db: db,
+	}
+}
+
+// Save compares update times stored in UTC, they compare as text in the same order as in time.
+func (r *DialogDraftRepository) Save(ctx context.Context, draft repository.DialogDraft) (*repository.DialogDraft, error) {
+	dbConn := r.db.GetConnection(ctx)
+
+	draft.UpdatedAt = draft.UpdatedAt.UTC()
+
+	sqlQuery := `INSERT INTO dialog_drafts (user_id, peer_id, text, updated_at) 
+		VALUES (:user_id, :peer_id, :text, :updated_at)
+		ON CONFLICT (user_id, peer_id) DO UPDATE 
+		SET text=excluded.text, updated_at=excluded.updated_at 
+		WHERE dialog_drafts.updated_at < excluded.updated_at`
+
+	_, err := dbConn.NamedExecContext(ctx, sqlQuery, draft)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save dialog draft to db: %w", err)
+	}
+
+	return r.GetDialogDraft(ctx, draft.UserID, draft.PeerID)
+}
+
+func (r *DialogDraftRepository) Delete(ctx context.Context, userID, peerID string, updatedBefore time.Time) error {
+	dbConn := r.db.GetConnection(ctx)
+
+	sqlQuery := `DELETE FROM dialog_drafts WHERE user_id=? AND peer_id=? AND updated_at <= ?`
+
+	_, err := dbConn.ExecContext(ctx, sqlQuery, userID, peerID, updatedBefore.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to delete dialog draft from db: %w", err)
+	}
+
+	return nil
+}
+
+func (r *DialogDraftRepository) GetDialogDraft(ctx context.Context, userID, peerID string) (*repository.DialogDraft, error) {
+	dbConn := r.db.GetRetryableConnection(ctx)
+
+	var draft repository.DialogDraft
+
+	sqlQuery := `SELECT user_id, peer_id, text, updated_at FROM dialog_drafts WHERE user_id=? AND peer_id=?`
+
+	err := dbConn.GetContext(ctx, &draft, sqlQuery, userID, peerID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotFound
+		}
+
+		return nil, fmt.Errorf("failed to fetch dialog draft by userID and peerID: %w", err)
+	}
+
+	return &draft, nil
+}
+
+func (r *DialogDraftRepository) GetDialogDraftsByUserID(ctx context.Context, userID string) ([]repository.DialogDraft, error) {
+	dbConn := r.db.GetRetryableConnection(ctx)
+
+	var drafts []repository.DialogDraft
+
+	sqlQuery := `SELECT user_id, peer_id, text, updated_at FROM dialog_drafts WHERE user_id=? ORDER BY updated_at DESC`
+
+	err := dbConn.SelectContext(ctx, &drafts, sqlQuery, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dialog drafts by userID: %w", err)
+	}
+
+	return drafts, nil
+}
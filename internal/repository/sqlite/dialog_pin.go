@@ -0,0 +1,144 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"myfacebook-dialog/internal/db"
+	"myfacebook-dialog/internal/repository"
+)
+
+type DialogPinRepository struct {
+	db *db.DB
+}
+
+func NewDialogPinRepository(db *db.DB) *DialogPinRepository {
+	return &DialogPinRepository{
+		db: db,
+	}
+}
+
+// Add deletes the stale pins of the dialog first, so that the limit counts live pins only.
+func (r *DialogPinRepository) Add(ctx context.Context, userID, peerID string, pin repository.DialogPin, limit int,
+	livePins repository.LiveDialogPinsFunc,
+) error {
+	dbConn := r.db.GetConnection(ctx)
+
+	firstUserID, secondUserID := repository.DialogParticipants(userID, peerID)
+
+	if err := r.deleteStaleDialogPins(ctx, firstUserID, secondUserID, livePins); err != nil {
+		return err
+	}
+
+	sqlQuery := `INSERT INTO dialog_pins (message_id, first_user_id, second_user_id, pinned_by, created_at)
+		SELECT ?, ?, ?, ?, ?
+		WHERE (SELECT count(*) FROM dialog_pins WHERE first_user_id=? AND second_user_id=?) < ?
+		ON CONFLICT (message_id) DO NOTHING`
+
+	result, err := dbConn.ExecContext(ctx, sqlQuery, pin.MessageID, firstUserID, secondUserID, pin.PinnedBy, time.Now().UTC(),
+		firstUserID, secondUserID, limit)
+	if err != nil {
+		return fmt.Errorf("failed to add dialog pin to db: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows of dialog pin insert: %w", err)
+	}
+
+	if rowsAffected > 0 {
+		return nil
+	}
+
+	// Nothing was inserted: the message is either pinned already or the limit is reached.
+	var pinned bool
+
+	err = dbConn.GetContext(ctx, &pinned, `SELECT EXISTS(SELECT 1 FROM dialog_pins WHERE message_id=?)`, pin.MessageID)
+	if err != nil {
+		return fmt.Errorf("failed to check dialog pin existence: %w", err)
+	}
+
+	if pinned {
+		return nil
+	}
+
+	return repository.ErrPinLimitReached
+}
+
+func (r *DialogPinRepository) deleteStaleDialogPins(ctx context.Context, firstUserID, secondUserID string,
+	livePins repository.LiveDialogPinsFunc,
+) error {
+	dbConn := r.db.GetConnection(ctx)
+
+	dialogPins, err := r.GetDialogPins(ctx, firstUserID, secondUserID)
+	if err != nil {
+		return err
+	}
+
+	liveDialogPins, err := livePins(ctx, dialogPins)
+	if err != nil {
+		return fmt.Errorf("failed to resolve live dialog pins: %w", err)
+	}
+
+	staleMessageIDs := repository.StaleDialogPinMessageIDs(dialogPins, liveDialogPins)
+	if len(staleMessageIDs) == 0 {
+		return nil
+	}
+
+	sqlQuery, args, err := sqlx.In(`DELETE FROM dialog_pins WHERE first_user_id=? AND second_user_id=? AND message_id IN (?)`,
+		firstUserID, secondUserID, staleMessageIDs)
+	if err != nil {
+		return fmt.Errorf("failed to build stale dialog pins delete: %w", err)
+	}
+
+	if _, err := dbConn.ExecContext(ctx, sqlQuery, args...); err != nil {
+		return fmt.Errorf("failed to delete stale dialog pins: %w", err)
+	}
+
+	return nil
+}
+
+func (r *DialogPinRepository) Delete(ctx context.Context, userID, peerID, messageID string) error {
+	dbConn := r.db.GetConnection(ctx)
+
+	firstUserID, secondUserID := repository.DialogParticipants(userID, peerID)
+
+	sqlQuery := `DELETE FROM dialog_pins WHERE message_id=? AND first_user_id=? AND second_user_id=?`
+
+	result, err := dbConn.ExecContext(ctx, sqlQuery, messageID, firstUserID, secondUserID)
+	if err != nil {
+		return fmt.Errorf("failed to delete dialog pin from db: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows of dialog pin delete: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *DialogPinRepository) GetDialogPins(ctx context.Context, userID, peerID string) ([]repository.DialogPin, error) {
+	dbConn := r.db.GetRetryableConnection(ctx)
+
+	firstUserID, secondUserID := repository.DialogParticipants(userID, peerID)
+
+	var dialogPins []repository.DialogPin
+
+	sqlQuery := `SELECT message_id, pinned_by 
+		FROM dialog_pins WHERE first_user_id=? AND second_user_id=? 
+		ORDER BY created_at`
+
+	err := dbConn.SelectContext(ctx, &dialogPins, sqlQuery, firstUserID, secondUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dialog pins: %w", err)
+	}
+
+	return dialogPins, nil
+}
@@ -0,0 +1,186 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"myfacebook-dialog/internal/db"
+	"myfacebook-dialog/internal/repository"
+)
+
+type DialogSettingsRepository struct {
+	db *db.DB
+}
+
+type dialogSettingsRow struct {
+	UserID     string     `db:"user_id"`
+	PeerID     string     `db:"peer_id"`
+	MutedUntil *time.Time `db:"muted_until"`
+	Archived   bool       `db:"archived"`
+	// Folders is a JSON array, SQLite has no array type.
+	Folders   string    `db:"folders"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+func NewDialogSettingsRepository(db *db.DB) *DialogSettingsRepository {
+	return &DialogSettingsRepository{
+		db: db,
+	}
+}
+
+func (r *DialogSettingsRepository) Save(ctx context.Context, settings repository.DialogSettings) error {
+	dbConn := r.db.GetConnection(ctx)
+
+	folders := settings.Folders
+	if folders == nil {
+		folders = []string{}
+	}
+
+	foldersJSON, err := json.Marshal(folders)
+	if err != nil {
+		return fmt.Errorf("failed to encode dialog settings folders: %w", err)
+	}
+
+	var mutedUntil *time.Time
+
+	if settings.MutedUntil != nil {
+		utcMutedUntil := settings.MutedUntil.UTC()
+		mutedUntil = &utcMutedUntil
+	}
+
+	sqlQuery := `INSERT INTO dialog_settings (user_id, peer_id, muted_until, archived, folders, updated_at)
+		VALUES (:user_id, :peer_id, :muted_until, :archived, :folders, :updated_at)
+		ON CONFLICT (user_id, peer_id) DO UPDATE 
+		SET muted_until=excluded.muted_until, archived=excluded.archived, folders=excluded.folders, updated_at=excluded.updated_at`
+
+	_, err = dbConn.NamedExecContext(ctx, sqlQuery, dialogSettingsRow{
+		UserID:     settings.UserID,
+		PeerID:     settings.PeerID,
+		MutedUntil: mutedUntil,
+		Archived:   settings.Archived,
+		Folders:    string(foldersJSON),
+		UpdatedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save dialog settings to db: %w", err)
+	}
+
+	return nil
+}
+
+func (r *DialogSettingsRepository) Delete(ctx context.Context, userID, peerID string) error {
+	dbConn := r.db.GetConnection(ctx)
+
+	result, err := dbConn.ExecContext(ctx, `DELETE FROM dialog_settings WHERE user_id=? AND peer_id=?`, userID, peerID)
+	if err != nil {
+		return fmt.Errorf("failed to delete dialog settings from db: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows of dialog settings delete: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *DialogSettingsRepository) GetDialogSettings(ctx context.Context, userID, peerID string) (*repository.DialogSettings, error) {
+	dbConn := r.db.GetRetryableConnection(ctx)
+
+	var row dialogSettingsRow
+
+	sqlQuery := `SELECT user_id, peer_id, muted_until, archived, folders, updated_at 
+		FROM dialog_settings WHERE user_id=? AND peer_id=?`
+
+	err := dbConn.GetContext(ctx, &row, sqlQuery, userID, peerID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotFound
+		}
+
+		return nil, fmt.Errorf("failed to fetch dialog settings by userID and peerID: %w", err)
+	}
+
+	dialogSettings, err := row.toDialogSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	return &dialogSettings, nil
+}
+
+func (r *DialogSettingsRepository) GetDialogSettingsByUserID(ctx context.Context, userID string, filter repository.DialogSettingsFilter) ([]repository.DialogSettings, error) {
+	dbConn := r.db.GetRetryableConnection(ctx)
+
+	conditions := []string{"user_id=?"}
+	args := []interface{}{userID}
+
+	if filter.Archived != nil {
+		conditions = append(conditions, "archived=?")
+		args = append(args, *filter.Archived)
+	}
+
+	if filter.Muted != nil {
+		mutedCondition := "coalesce(muted_until > ?, false)"
+		if !*filter.Muted {
+			mutedCondition = "NOT " + mutedCondition
+		}
+
+		conditions = append(conditions, mutedCondition)
+		args = append(args, time.Now().UTC())
+	}
+
+	if filter.Folder != "" {
+		conditions = append(conditions, "EXISTS(SELECT 1 FROM json_each(folders) WHERE value=?)")
+		args = append(args, filter.Folder)
+	}
+
+	sqlQuery := `SELECT user_id, peer_id, muted_until, archived, folders, updated_at 
+		FROM dialog_settings WHERE ` + strings.Join(conditions, " AND ") + ` 
+		ORDER BY updated_at DESC`
+
+	var rows []dialogSettingsRow
+
+	err := dbConn.SelectContext(ctx, &rows, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dialog settings by userID: %w", err)
+	}
+
+	dialogSettings := make([]repository.DialogSettings, 0, len(rows))
+
+	for _, row := range rows {
+		settings, err := row.toDialogSettings()
+		if err != nil {
+			return nil, err
+		}
+
+		dialogSettings = append(dialogSettings, settings)
+	}
+
+	return dialogSettings, nil
+}
+
+func (row dialogSettingsRow) toDialogSettings() (repository.DialogSettings, error) {
+	var folders []string
+
+	if err := json.Unmarshal([]byte(row.Folders), &folders); err != nil {
+		return repository.DialogSettings{}, fmt.Errorf("failed to decode dialog settings folders: %w", err)
+	}
+
+	return repository.DialogSettings{
+		UserID:     row.UserID,
+		PeerID:     row.PeerID,
+		MutedUntil: row.MutedUntil,
+		Archived:   row.Archived,
+		Folders:    folders,
+	}, nil
+}
@@ -0,0 +1,101 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"myfacebook-dialog/internal/db"
+	"myfacebook-dialog/internal/repository"
+)
+
+type DialogStarRepository struct {
+	db *db.DB
+}
+
+func NewDialogStarRepository(db *db.DB) *DialogStarRepository {
+	return &DialogStarRepository{
+		db: db,
+	}
+}
+
+func (r *DialogStarRepository) Add(ctx context.Context, userID, messageID string) error {
+	dbConn := r.db.GetConnection(ctx)
+
+	sqlQuery := `INSERT INTO dialog_stars (user_id, message_id, created_at) VALUES (?, ?, ?) 
+		ON CONFLICT (user_id, message_id) DO NOTHING`
+
+	_, err := dbConn.ExecContext(ctx, sqlQuery, userID, messageID, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to add dialog star to db: %w", err)
+	}
+
+	return nil
+}
+
+func (r *DialogStarRepository) Delete(ctx context.Context, userID, messageID string) error {
+	dbConn := r.db.GetConnection(ctx)
+
+	result, err := dbConn.ExecContext(ctx, `DELETE FROM dialog_stars WHERE user_id=? AND message_id=?`, userID, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to delete dialog star from db: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows of dialog star delete: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *DialogStarRepository) DeleteByMessageIDs(ctx context.Context, userID string, messageIDs []string) error {
+	if len(messageIDs) == 0 {
+		return nil
+	}
+
+	dbConn := r.db.GetConnection(ctx)
+
+	sqlQuery, args, err := sqlx.In(`DELETE FROM dialog_stars WHERE user_id=? AND message_id IN (?)`, userID, messageIDs)
+	if err != nil {
+		return fmt.Errorf("failed to build dialog stars delete: %w", err)
+	}
+
+	if _, err := dbConn.ExecContext(ctx, sqlQuery, args...); err != nil {
+		return fmt.Errorf("failed to delete dialog stars by message ids from db: %w", err)
+	}
+
+	return nil
+}
+
+// GetDialogStarsByUserID compares star times stored in UTC, they compare as text in the same order as in time.
+func (r *DialogStarRepository) GetDialogStarsByUserID(ctx context.Context, userID string, after *repository.DialogStarCursor, limit int) ([]repository.DialogStar, error) {
+	dbConn := r.db.GetRetryableConnection(ctx)
+
+	condition := "user_id=?"
+	args := []interface{}{userID}
+
+	if after != nil {
+		condition += " AND (created_at, message_id) < (?, ?)"
+		args = append(args, after.CreatedAt.UTC(), after.MessageID)
+	}
+
+	var dialogStars []repository.DialogStar
+
+	sqlQuery := `SELECT message_id, created_at 
+		FROM dialog_stars WHERE ` + condition + ` 
+		ORDER BY created_at DESC, message_id DESC 
+		LIMIT ?`
+
+	err := dbConn.SelectContext(ctx, &dialogStars, sqlQuery, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dialog stars by userID: %w", err)
+	}
+
+	return dialogStars, nil
+}
@@ -2,55 +2,389 @@ package sqlx
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"time"
 
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"myfacebook-dialog/internal/db"
+	"myfacebook-dialog/internal/idgen"
 	"myfacebook-dialog/internal/repository"
 )
 
+var ErrMessageIDCollision = errors.New("message id is taken by another message")
+
+const dialogMessageColumns = `id, legacy_id, source, sender_id, receiver_id, text, message_type, forwarded_from_user_id, forwarded_from_message_id, seq, created_at`
+
+var legacyIDRegexp = regexp.MustCompile(`^[0-9]+$`)
+
+// Reads of a dialog are served by dialogs_dialog_key_seq_idx without a sort, dialog_test.go checks their plans.
+const (
+	// dialogMessagesAfterSeqQuery takes the user ids, the seq, the limit and partitionPruningSlack as an interval.
+	dialogMessagesAfterSeqQuery = `WITH dialog_start AS (
+			SELECT COALESCE(
+				(SELECT first_created_at FROM dialog_sequences WHERE dialog_key = make_dialog_key($1, $2)), '-infinity'
+			) - $5::interval AS created_at
+		)
+		SELECT ` + dialogMessageColumns + ` 
+		FROM dialogs WHERE dialog_key = make_dialog_key($1, $2) AND seq > $3 
+			AND created_at >= COALESCE(
+				(SELECT created_at - $5::interval FROM dialogs 
+				WHERE dialog_key = make_dialog_key($1, $2) AND seq = $3 AND created_at >= (SELECT created_at FROM dialog_start)),
+				(SELECT created_at FROM dialog_start)
+			)
+		ORDER BY seq LIMIT NULLIF($4, 0)`
+
+	// dialogMessagesCreatedBetweenQuery takes the user ids and the [from, to) range of created_at.
+	dialogMessagesCreatedBetweenQuery = `SELECT ` + dialogMessageColumns + ` 
+		FROM dialogs WHERE dialog_key = make_dialog_key($1, $2) AND created_at >= $3 AND created_at < $4 
+		ORDER BY seq`
+)
+
+// partitionPruningSlack widens created_at bounds derived from other timestamps, dialogs are partitioned by month.
+const partitionPruningSlack = 24 * time.Hour
+
 type DialogRepository struct {
-	db *db.DB
+	db          *db.DB
+	idGenerator *idgen.UUIDv7
 }
 
 func NewDialogRepository(db *db.DB) *DialogRepository {
 	return &DialogRepository{
-		db: db,
+		db:          db,
+		idGenerator: idgen.NewUUIDv7(),
 	}
 }
 
-func (r *DialogRepository) Add(ctx context.Context, dialogMessage repository.DialogMessage) error {
-	dbConn := r.db.GetConnection()
+func (r *DialogRepository) Add(ctx context.Context, dialogMessage repository.DialogMessage) (*repository.DialogMessage, error) {
+	dbConn := r.db.GetConnection(ctx)
+
+	id, err := r.idGenerator.New()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate dialog message id: %w", err)
+	}
 
-	sqlQuery := `INSERT INTO dialogs (sender_id, receiver_id, text) 
-				VALUES (:sender_id, :receiver_id, :text)`
+	dialogMessage.ID = id
 
-	_, err := dbConn.NamedExecContext(ctx, sqlQuery, dialogMessage)
+	sqlQuery := `INSERT INTO dialogs (id, sender_id, receiver_id, text, forwarded_from_user_id, forwarded_from_message_id) 
+				VALUES (:id, :sender_id, :receiver_id, :text, :forwarded_from_user_id, :forwarded_from_message_id)
+				RETURNING ` + dialogMessageColumns
+
+	sqlQuery, args, err := dbConn.BindNamed(sqlQuery, dialogMessage)
 	if err != nil {
-		return fmt.Errorf("failed to add dialog mesage to db: %w", err)
+		return nil, fmt.Errorf("failed to bind dialog message insert: %w", err)
 	}
 
-	return nil
+	var storedDialogMessage repository.DialogMessage
+
+	err = dbConn.GetContext(ctx, &storedDialogMessage, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add dialog mesage to db: %w", err)
+	}
+
+	r.recordConsistencyToken(ctx)
+
+	return &storedDialogMessage, nil
+}
+
+// recordConsistencyToken hands out the WAL position after a write, reads with it skip replicas that did not replay the write.
+// The write is committed already, so a missing token only costs read-your-writes and is not an error.
+func (r *DialogRepository) recordConsistencyToken(ctx context.Context) {
+	recorder := repository.ConsistencyRecorderFromContext(ctx)
+	if recorder == nil || !r.db.HasReplicas() {
+		return
+	}
+
+	lsn, err := r.db.CurrentLSN(ctx)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Failed to record consistency token: %s", err))
+
+		return
+	}
+
+	recorder.Record(lsn)
 }
 
 func (r *DialogRepository) GetDialogMessagesBySenderIDAndReceiverID(ctx context.Context, senderID, receiverID string) ([]repository.DialogMessage, error) {
-	dbConn := r.db.GetConnection()
+	return r.GetDialogMessagesAfterSeq(ctx, senderID, receiverID, 0, 0)
+}
+
+// GetDialogMessagesAfterSeq bounds created_at by the message at afterSeq or by the start of the dialog,
+// so only partitions that can hold the page are scanned. Concurrent sends may store a later seq with an earlier created_at,
+// the bound is lowered by partitionPruningSlack to keep them.
+func (r *DialogRepository) GetDialogMessagesAfterSeq(ctx context.Context, senderID, receiverID string, afterSeq int64, limit int) ([]repository.DialogMessage, error) {
+	dbConn := r.db.GetReadConnectionAfter(ctx, repository.ConsistencyTokenFromContext(ctx))
 
 	var dialogMessages []repository.DialogMessage
 
-	sqlQuery := `SELECT id, sender_id, receiver_id, text 
-		FROM dialogs WHERE (sender_id=$1 AND receiver_id=$2) OR (sender_id=$2 AND receiver_id=$1) 
-		ORDER BY created_at`
+	err := dbConn.SelectContext(ctx, &dialogMessages, dialogMessagesAfterSeqQuery, senderID, receiverID, afterSeq, limit,
+		fmt.Sprintf("%d seconds", int(partitionPruningSlack.Seconds())))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dialog messages after seq: %w", err)
+	}
+
+	return dialogMessages, nil
+}
+
+// GetDialogMessagesByIDs bounds created_at by the time encoded in the ids, which differs from created_at by clock skew only.
+// Legacy ids carry no time, so they are looked up in every partition.
+func (r *DialogRepository) GetDialogMessagesByIDs(ctx context.Context, messageIDs []string) ([]repository.DialogMessage, error) {
+	dbConn := r.db.GetRetryableConnection(ctx)
+
+	var (
+		ids, legacyIDs   []string
+		minTime, maxTime time.Time
+	)
+
+	for _, messageID := range messageIDs {
+		if legacyIDRegexp.MatchString(messageID) {
+			legacyIDs = append(legacyIDs, messageID)
+
+			continue
+		}
 
-	err := dbConn.SelectContext(ctx, &dialogMessages, sqlQuery, senderID, receiverID)
+		idTime, err := idgen.Time(messageID)
+		if err != nil {
+			continue
+		}
+
+		if len(ids) == 0 || idTime.Before(minTime) {
+			minTime = idTime
+		}
+
+		if len(ids) == 0 || idTime.After(maxTime) {
+			maxTime = idTime
+		}
+
+		ids = append(ids, messageID)
+	}
+
+	var dialogMessages []repository.DialogMessage
+
+	if len(ids) > 0 {
+		sqlQuery := `SELECT ` + dialogMessageColumns + ` 
+			FROM dialogs WHERE id = ANY($1::uuid[]) AND created_at BETWEEN $2 AND $3`
+
+		err := dbConn.SelectContext(ctx, &dialogMessages, sqlQuery, pq.Array(ids),
+			minTime.Add(-partitionPruningSlack), maxTime.Add(partitionPruningSlack))
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch dialog messages by ids: %w", err)
+		}
+	}
+
+	if len(legacyIDs) > 0 {
+		var legacyDialogMessages []repository.DialogMessage
+
+		sqlQuery := `SELECT ` + dialogMessageColumns + ` 
+			FROM dialogs WHERE legacy_id = ANY($1::integer[])`
+
+		err := dbConn.SelectContext(ctx, &legacyDialogMessages, sqlQuery, pq.Array(legacyIDs))
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch dialog messages by legacy ids: %w", err)
+		}
+
+		dialogMessages = append(dialogMessages, legacyDialogMessages...)
+	}
+
+	if len(dialogMessages) == 0 {
+		return nil, repository.ErrNotFound
+	}
+
+	sort.Slice(dialogMessages, func(i, j int) bool {
+		return dialogMessages[i].CreatedAt.Before(dialogMessages[j].CreatedAt)
+	})
+
+	return dialogMessages, nil
+}
+
+// GetDialogsCreatedBetween returns dialogs with messages created in [from, to).
+func (r *DialogRepository) GetDialogsCreatedBetween(ctx context.Context, from, to time.Time) ([]repository.Dialog, error) {
+	dbConn := r.db.GetRetryableConnection(ctx)
+
+	var dialogs []repository.Dialog
+
+	sqlQuery := `SELECT DISTINCT least(sender_id, receiver_id) AS first_user_id, greatest(sender_id, receiver_id) AS second_user_id 
+		FROM dialogs WHERE created_at >= $1 AND created_at < $2 
+		ORDER BY 1, 2`
+
+	err := dbConn.SelectContext(ctx, &dialogs, sqlQuery, from, to)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, repository.ErrNotFound
+		return nil, fmt.Errorf("failed to fetch dialogs created between: %w", err)
+	}
+
+	return dialogs, nil
+}
+
+// GetDialogMessagesCreatedBetween returns messages of the dialog created in [from, to) in seq order.
+func (r *DialogRepository) GetDialogMessagesCreatedBetween(ctx context.Context, senderID, receiverID string, from, to time.Time) ([]repository.DialogMessage, error) {
+	dbConn := r.db.GetRetryableConnection(ctx)
+
+	var dialogMessages []repository.DialogMessage
+
+	err := dbConn.SelectContext(ctx, &dialogMessages, dialogMessagesCreatedBetweenQuery, senderID, receiverID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dialog messages created between: %w", err)
+	}
+
+	return dialogMessages, nil
+}
+
+// DeleteDialogMessages deletes the given messages and returns how many were deleted,
+// their creation time bounds the partitions to look in.
+func (r *DialogRepository) DeleteDialogMessages(ctx context.Context, dialogMessages []repository.DialogMessage) (int64, error) {
+	if len(dialogMessages) == 0 {
+		return 0, nil
+	}
+
+	dbConn := r.db.GetConnection(ctx)
+
+	ids := make([]string, 0, len(dialogMessages))
+	minCreatedAt, maxCreatedAt := dialogMessages[0].CreatedAt, dialogMessages[0].CreatedAt
+
+	for _, dialogMsg := range dialogMessages {
+		ids = append(ids, dialogMsg.ID)
+
+		if dialogMsg.CreatedAt.Before(minCreatedAt) {
+			minCreatedAt = dialogMsg.CreatedAt
 		}
 
-		return nil, fmt.Errorf("failed to fetch dialog messages by senderID and receiverID: %w", err)
+		if dialogMsg.CreatedAt.After(maxCreatedAt) {
+			maxCreatedAt = dialogMsg.CreatedAt
+		}
+	}
+
+	sqlQuery := `DELETE FROM dialogs WHERE id = ANY($1::uuid[]) AND created_at BETWEEN $2 AND $3`
+
+	result, err := dbConn.ExecContext(ctx, sqlQuery, pq.Array(ids), minCreatedAt, maxCreatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete dialog messages: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get number of deleted dialog messages: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// GetDialogMessagesAfterID pages through all messages of the database in id order.
+func (r *DialogRepository) GetDialogMessagesAfterID(ctx context.Context, afterID string, limit int) ([]repository.DialogMessage, error) {
+	dbConn := r.db.GetRetryableConnection(ctx)
+
+	var dialogMessages []repository.DialogMessage
+
+	sqlQuery := `SELECT ` + dialogMessageColumns + ` 
+		FROM dialogs WHERE id > $1 
+		ORDER BY id LIMIT $2`
+
+	err := dbConn.SelectContext(ctx, &dialogMessages, sqlQuery, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dialog messages after id: %w", err)
 	}
 
 	return dialogMessages, nil
 }
+
+// CopyDialogMessages stores messages of another database keeping their ids and creation time.
+// Copying is idempotent, but it fails if an id is taken by a different message.
+func (r *DialogRepository) CopyDialogMessages(ctx context.Context, dialogMessages []repository.DialogMessage) error {
+	if len(dialogMessages) == 0 {
+		return nil
+	}
+
+	dbConn := r.db.GetConnection(ctx)
+
+	sqlQuery := `INSERT INTO dialogs (` + dialogMessageColumns + `) 
+		VALUES (:id, :legacy_id, :source, :sender_id, :receiver_id, :text, :message_type, :forwarded_from_user_id, :forwarded_from_message_id, :seq, :created_at)
+		ON CONFLICT (id, created_at) DO NOTHING 
+		RETURNING id`
+
+	rows, err := sqlx.NamedQueryContext(ctx, dbConn, sqlQuery, dialogMessages)
+	if err != nil {
+		return fmt.Errorf("failed to copy dialog messages to db: %w", err)
+	}
+
+	copiedIDs := make(map[string]struct{}, len(dialogMessages))
+
+	for rows.Next() {
+		var copiedID string
+		if err := rows.Scan(&copiedID); err != nil {
+			rows.Close()
+
+			return fmt.Errorf("failed to scan copied dialog message id: %w", err)
+		}
+
+		copiedIDs[copiedID] = struct{}{}
+	}
+
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to copy dialog messages to db: %w", err)
+	}
+
+	return r.checkSkippedDialogMessages(ctx, dialogMessages, copiedIDs)
+}
+
+// checkSkippedDialogMessages makes sure messages that were not copied are stored already and not shadowed by other ones.
+func (r *DialogRepository) checkSkippedDialogMessages(ctx context.Context, dialogMessages []repository.DialogMessage, copiedIDs map[string]struct{}) error {
+	var skippedIDs []string
+
+	for _, dialogMsg := range dialogMessages {
+		if _, ok := copiedIDs[dialogMsg.ID]; !ok {
+			skippedIDs = append(skippedIDs, dialogMsg.ID)
+		}
+	}
+
+	if len(skippedIDs) == 0 {
+		return nil
+	}
+
+	storedDialogMessages, err := r.GetDialogMessagesByIDs(ctx, skippedIDs)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+
+	storedDialogMessagesByID := make(map[string]repository.DialogMessage, len(storedDialogMessages))
+	for _, storedDialogMsg := range storedDialogMessages {
+		storedDialogMessagesByID[storedDialogMsg.ID] = storedDialogMsg
+	}
+
+	for _, dialogMsg := range dialogMessages {
+		if _, ok := copiedIDs[dialogMsg.ID]; ok {
+			continue
+		}
+
+		if !dialogMsg.Equal(storedDialogMessagesByID[dialogMsg.ID]) {
+			return fmt.Errorf("%w: id %s", ErrMessageIDCollision, dialogMsg.ID)
+		}
+	}
+
+	return nil
+}
+
+// GetDialogChecksums returns the number of messages and their checksum for every dialog of the database.
+func (r *DialogRepository) GetDialogChecksums(ctx context.Context) ([]repository.DialogChecksum, error) {
+	dbConn := r.db.GetRetryableConnection(ctx)
+
+	var dialogChecksums []repository.DialogChecksum
+
+	sqlQuery := `SELECT least(sender_id, receiver_id) AS first_user_id, greatest(sender_id, receiver_id) AS second_user_id,
+			count(*) AS message_count,
+			md5(string_agg(concat_ws(':', id, legacy_id, source, sender_id, receiver_id, text, message_type, forwarded_from_user_id, forwarded_from_message_id, seq,
+				extract(epoch FROM created_at)), ',' ORDER BY id)) AS checksum
+		FROM dialogs 
+		GROUP BY 1, 2`
+
+	err := dbConn.SelectContext(ctx, &dialogChecksums, sqlQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dialog checksums: %w", err)
+	}
+
+	return dialogChecksums, nil
+}
@@ -0,0 +1,157 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"myfacebook-dialog/internal/db"
+	"myfacebook-dialog/internal/repository"
+)
+
+const dialogArchiveColumns = `id, first_user_id, second_user_id, month, first_seq, last_seq, message_count, object_key, checksum, created_at`
+
+type DialogArchiveRepository struct {
+	db *db.DB
+}
+
+func NewDialogArchiveRepository(db *db.DB) *DialogArchiveRepository {
+	return &DialogArchiveRepository{
+		db: db,
+	}
+}
+
+// Add inserts the archive and the ids of its messages in a single statement, so they are stored together or not at all.
+func (r *DialogArchiveRepository) Add(ctx context.Context, dialogArchive repository.DialogArchive,
+	dialogMessages []repository.DialogMessage,
+) (*repository.DialogArchive, error) {
+	dbConn := r.db.GetConnection(ctx)
+
+	messageIDs := make([]string, 0, len(dialogMessages))
+	legacyIDs := make([]sql.NullString, 0, len(dialogMessages))
+
+	for _, dialogMsg := range dialogMessages {
+		messageIDs = append(messageIDs, dialogMsg.ID)
+
+		if dialogMsg.LegacyID != nil {
+			legacyIDs = append(legacyIDs, sql.NullString{String: *dialogMsg.LegacyID, Valid: true})
+		} else {
+			legacyIDs = append(legacyIDs, sql.NullString{})
+		}
+	}
+
+	sqlQuery := `WITH archive AS (
+			INSERT INTO dialog_archives (first_user_id, second_user_id, month, first_seq, last_seq, message_count, object_key, checksum)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (object_key) DO NOTHING 
+			RETURNING id
+		)
+		INSERT INTO dialog_archive_messages (message_id, legacy_id, archive_id)
+		SELECT message_ids.message_id, message_ids.legacy_id, archive.id 
+		FROM archive, unnest($9::uuid[], $10::integer[]) AS message_ids (message_id, legacy_id)`
+
+	_, err := dbConn.ExecContext(ctx, sqlQuery, dialogArchive.FirstUserID, dialogArchive.SecondUserID, dialogArchive.Month,
+		dialogArchive.FirstSeq, dialogArchive.LastSeq, dialogArchive.MessageCount, dialogArchive.ObjectKey, dialogArchive.Checksum,
+		pq.Array(messageIDs), pq.Array(legacyIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to add dialog archive to db: %w", err)
+	}
+
+	var storedDialogArchive repository.DialogArchive
+
+	err = dbConn.GetContext(ctx, &storedDialogArchive, `SELECT `+dialogArchiveColumns+` FROM dialog_archives WHERE object_key = $1`,
+		dialogArchive.ObjectKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch added dialog archive: %w", err)
+	}
+
+	return &storedDialogArchive, nil
+}
+
+func (r *DialogArchiveRepository) GetDialogArchivesAfterSeq(ctx context.Context, senderID, receiverID string, afterSeq int64) ([]repository.DialogArchive, error) {
+	dbConn := r.db.GetRetryableConnection(ctx)
+
+	firstUserID, secondUserID := repository.DialogParticipants(senderID, receiverID)
+
+	var dialogArchives []repository.DialogArchive
+
+	sqlQuery := `SELECT ` + dialogArchiveColumns + ` 
+		FROM dialog_archives WHERE first_user_id = $1 AND second_user_id = $2 AND last_seq > $3 
+		ORDER BY first_seq`
+
+	err := dbConn.SelectContext(ctx, &dialogArchives, sqlQuery, firstUserID, secondUserID, afterSeq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dialog archives after seq: %w", err)
+	}
+
+	return dialogArchives, nil
+}
+
+func (r *DialogArchiveRepository) GetDialogArchivesByMessageIDs(ctx context.Context, messageIDs []string) ([]repository.DialogArchive, error) {
+	dbConn := r.db.GetRetryableConnection(ctx)
+
+	var ids, legacyIDs []string
+
+	for _, messageID := range messageIDs {
+		if legacyIDRegexp.MatchString(messageID) {
+			legacyIDs = append(legacyIDs, messageID)
+		} else {
+			ids = append(ids, messageID)
+		}
+	}
+
+	var dialogArchives []repository.DialogArchive
+
+	sqlQuery := `SELECT ` + dialogArchiveColumns + ` 
+		FROM dialog_archives WHERE id IN (
+			SELECT archive_id FROM dialog_archive_messages 
+			WHERE message_id = ANY($1::uuid[]) OR legacy_id = ANY($2::integer[])
+		)
+		ORDER BY id`
+
+	err := dbConn.SelectContext(ctx, &dialogArchives, sqlQuery, pq.Array(ids), pq.Array(legacyIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dialog archives by message ids: %w", err)
+	}
+
+	return dialogArchives, nil
+}
+
+func (r *DialogArchiveRepository) GetDialogArchivesBetween(ctx context.Context, from, to time.Time) ([]repository.DialogArchive, error) {
+	dbConn := r.db.GetRetryableConnection(ctx)
+
+	var dialogArchives []repository.DialogArchive
+
+	sqlQuery := `SELECT ` + dialogArchiveColumns + ` 
+		FROM dialog_archives WHERE month >= $1::date AND month < $2::date 
+		ORDER BY id`
+
+	err := dbConn.SelectContext(ctx, &dialogArchives, sqlQuery, from.UTC().Format(time.DateOnly), to.UTC().Format(time.DateOnly))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dialog archives between months: %w", err)
+	}
+
+	return dialogArchives, nil
+}
+
+func (r *DialogArchiveRepository) Delete(ctx context.Context, id int64) error {
+	dbConn := r.db.GetConnection(ctx)
+
+	result, err := dbConn.ExecContext(ctx, `DELETE FROM dialog_archives WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete dialog archive: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows of dialog archive delete: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
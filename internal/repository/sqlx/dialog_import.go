@@ -0,0 +1,201 @@
+package sqlx
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+	"myfacebook-dialog/internal/db"
+	"myfacebook-dialog/internal/repository"
+)
+
+var dialogImportColumns = []string{"id", "legacy_id", "source", "sender_id", "receiver_id", "text", "created_at"}
+
+// DialogImportRepository loads messages of the monolith into the dialogs table with COPY.
+type DialogImportRepository struct {
+	db        *db.DB
+	txManager *db.TxManager
+}
+
+func NewDialogImportRepository(db *db.DB, txManager *db.TxManager) *DialogImportRepository {
+	return &DialogImportRepository{
+		db:        db,
+		txManager: txManager,
+	}
+}
+
+// ImportDialogMessages copies the messages in a single transaction. Counters of their dialogs stay locked until
+// it commits, so messages sent meanwhile are numbered after the imported ones. The partitions of the messages
+// must exist: a message older than the oldest partition fails the whole batch.
+func (r *DialogImportRepository) ImportDialogMessages(ctx context.Context, dialogMessages []repository.DialogMessage) (repository.DialogImportResult, error) {
+	var result repository.DialogImportResult
+
+	if len(dialogMessages) == 0 {
+		return result, nil
+	}
+
+	// Messages are numbered in the order they are copied.
+	dialogMessages = append([]repository.DialogMessage(nil), dialogMessages...)
+	sort.SliceStable(dialogMessages, func(i, j int) bool {
+		return dialogMessages[i].CreatedAt.Before(dialogMessages[j].CreatedAt)
+	})
+
+	err := r.txManager.InTx(ctx, func(ctx context.Context) error {
+		result = repository.DialogImportResult{}
+
+		lastCreatedAt, err := r.lockDialogs(ctx, dialogMessages)
+		if err != nil {
+			return err
+		}
+
+		storedDialogMessages, err := r.getDialogMessagesByLegacyIDs(ctx, dialogMessages)
+		if err != nil {
+			return err
+		}
+
+		rows := make([][]interface{}, 0, len(dialogMessages))
+
+		for _, dialogMsg := range dialogMessages {
+			storedDialogMsg, stored := storedDialogMessages[*dialogMsg.LegacyID]
+
+			switch {
+			case stored && sameLegacyDialogMessage(dialogMsg, storedDialogMsg):
+				result.Skipped++
+			case stored:
+				result.Rejected = append(result.Rejected, repository.DialogImportRejection{DialogMessage: dialogMsg, Err: repository.ErrLegacyIDTaken})
+			case dialogMsg.CreatedAt.Before(lastCreatedAt[dialogOf(dialogMsg)]):
+				result.Rejected = append(result.Rejected, repository.DialogImportRejection{DialogMessage: dialogMsg, Err: repository.ErrDialogHasNewerMessages})
+			default:
+				rows = append(rows, []interface{}{dialogMsg.ID, *dialogMsg.LegacyID, repository.DialogMessageSourceMonolith, dialogMsg.From, dialogMsg.To, dialogMsg.Text, dialogMsg.CreatedAt})
+
+				// A repeated legacy id of the batch is checked against the first message with it.
+				storedDialogMessages[*dialogMsg.LegacyID] = dialogMsg
+			}
+		}
+
+		if len(rows) == 0 {
+			return nil
+		}
+
+		if err := r.db.CopyIn(ctx, "dialogs", dialogImportColumns, rows); err != nil {
+			return fmt.Errorf("failed to copy dialog messages to db: %w", err)
+		}
+
+		result.Imported = len(rows)
+
+		return nil
+	})
+	if err != nil {
+		return repository.DialogImportResult{}, fmt.Errorf("failed to import dialog messages: %w", err)
+	}
+
+	return result, nil
+}
+
+// lockDialogs locks the counters of the dialogs of the messages and returns the creation time of the last message
+// of every dialog that has messages. Dialogs without a counter get one first, otherwise a message sent to a new dialog
+// during the import could be numbered before the older imported ones.
+func (r *DialogImportRepository) lockDialogs(ctx context.Context, dialogMessages []repository.DialogMessage) (map[repository.Dialog]time.Time, error) {
+	dbConn := r.db.GetConnection(ctx)
+
+	senderIDs := make([]string, 0, len(dialogMessages))
+	receiverIDs := make([]string, 0, len(dialogMessages))
+	createdAts := make([]string, 0, len(dialogMessages))
+
+	for _, dialogMsg := range dialogMessages {
+		senderIDs = append(senderIDs, dialogMsg.From)
+		receiverIDs = append(receiverIDs, dialogMsg.To)
+		createdAts = append(createdAts, dialogMsg.CreatedAt.Format(time.RFC3339Nano))
+	}
+
+	dialogKeysQuery := `SELECT make_dialog_key(u.sender_id, u.receiver_id) AS dialog_key, min(u.created_at) AS first_created_at
+		FROM unnest($1::uuid[], $2::uuid[], $3::timestamptz[]) AS u(sender_id, receiver_id, created_at)
+		GROUP BY 1`
+
+	// Numbered after the stored messages of the dialog, like dialogs_set_seq numbers the first message of a dialog
+	// without a counter. The maximum rather than the count, messages may have been purged.
+	sqlQuery := `INSERT INTO dialog_sequences (dialog_key, last_seq, first_created_at)
+		SELECT k.dialog_key,
+			coalesce((SELECT max(d.seq) FROM dialogs d WHERE d.dialog_key = k.dialog_key), 0),
+			coalesce((SELECT min(d.created_at) FROM dialogs d WHERE d.dialog_key = k.dialog_key), k.first_created_at)
+		FROM (` + dialogKeysQuery + `) k
+		ORDER BY k.dialog_key
+		ON CONFLICT (dialog_key) DO NOTHING`
+
+	if _, err := dbConn.ExecContext(ctx, sqlQuery, pq.Array(senderIDs), pq.Array(receiverIDs), pq.Array(createdAts)); err != nil {
+		return nil, fmt.Errorf("failed to create dialog sequences: %w", err)
+	}
+
+	// Locked in key order, so that concurrent imports of overlapping dialogs do not deadlock.
+	sqlQuery = `SELECT dialog_key FROM dialog_sequences WHERE dialog_key IN (SELECT dialog_key FROM (` + dialogKeysQuery + `) k)
+		ORDER BY dialog_key FOR UPDATE`
+
+	if _, err := dbConn.ExecContext(ctx, sqlQuery, pq.Array(senderIDs), pq.Array(receiverIDs), pq.Array(createdAts)); err != nil {
+		return nil, fmt.Errorf("failed to lock dialog sequences: %w", err)
+	}
+
+	var lastDialogMessages []struct {
+		repository.Dialog
+		CreatedAt time.Time `db:"created_at"`
+	}
+
+	sqlQuery = `SELECT split_part(k.dialog_key, ':', 1) AS first_user_id, split_part(k.dialog_key, ':', 2) AS second_user_id,
+			last.created_at
+		FROM (` + dialogKeysQuery + `) k
+		CROSS JOIN LATERAL (
+			SELECT created_at FROM dialogs d WHERE d.dialog_key = k.dialog_key ORDER BY seq DESC LIMIT 1
+		) last`
+
+	if err := dbConn.SelectContext(ctx, &lastDialogMessages, sqlQuery, pq.Array(senderIDs), pq.Array(receiverIDs), pq.Array(createdAts)); err != nil {
+		return nil, fmt.Errorf("failed to fetch last dialog messages: %w", err)
+	}
+
+	lastCreatedAt := make(map[repository.Dialog]time.Time, len(lastDialogMessages))
+	for _, lastDialogMsg := range lastDialogMessages {
+		lastCreatedAt[lastDialogMsg.Dialog] = lastDialogMsg.CreatedAt
+	}
+
+	return lastCreatedAt, nil
+}
+
+// getDialogMessagesByLegacyIDs returns imported messages by their legacy ids, messages stored here keep legacy ids of their own.
+func (r *DialogImportRepository) getDialogMessagesByLegacyIDs(ctx context.Context, dialogMessages []repository.DialogMessage) (map[string]repository.DialogMessage, error) {
+	dbConn := r.db.GetConnection(ctx)
+
+	legacyIDs := make([]string, 0, len(dialogMessages))
+	for _, dialogMsg := range dialogMessages {
+		legacyIDs = append(legacyIDs, *dialogMsg.LegacyID)
+	}
+
+	var storedDialogMessages []repository.DialogMessage
+
+	sqlQuery := `SELECT ` + dialogMessageColumns + `
+		FROM dialogs WHERE legacy_id = ANY($1::integer[]) AND source = $2`
+
+	err := dbConn.SelectContext(ctx, &storedDialogMessages, sqlQuery, pq.Array(legacyIDs), repository.DialogMessageSourceMonolith)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dialog messages by legacy ids: %w", err)
+	}
+
+	storedDialogMessagesByLegacyID := make(map[string]repository.DialogMessage, len(storedDialogMessages))
+	for _, storedDialogMsg := range storedDialogMessages {
+		storedDialogMessagesByLegacyID[*storedDialogMsg.LegacyID] = storedDialogMsg
+	}
+
+	return storedDialogMessagesByLegacyID, nil
+}
+
+// dialogOf returns the dialog of the message as make_dialog_key orders it, ids of imported messages are lower case.
+func dialogOf(dialogMsg repository.DialogMessage) repository.Dialog {
+	firstUserID, secondUserID := repository.DialogParticipants(dialogMsg.From, dialogMsg.To)
+
+	return repository.Dialog{FirstUserID: firstUserID, SecondUserID: secondUserID}
+}
+
+// sameLegacyDialogMessage reports whether the stored message is the imported one, ids of stored messages differ.
+func sameLegacyDialogMessage(dialogMsg, storedDialogMsg repository.DialogMessage) bool {
+	return dialogMsg.From == storedDialogMsg.From && dialogMsg.To == storedDialogMsg.To && dialogMsg.Text == storedDialogMsg.Text &&
+		dialogMsg.CreatedAt.Equal(storedDialogMsg.CreatedAt)
+}
@@ -0,0 +1,100 @@
+package sqlx
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"myfacebook-dialog/internal/db"
+	"myfacebook-dialog/internal/db/dbtest"
+	"myfacebook-dialog/internal/idgen"
+	"myfacebook-dialog/internal/repository"
+)
+
+func TestImportDialogMessagesRejectsMessagesOlderThanStoredOnes(t *testing.T) {
+	postgresDB := dbtest.Postgres(t)
+	ctx := context.Background()
+
+	if _, err := NewDialogPartitionRepository(postgresDB).EnsureDialogPartitions(ctx, 0); err != nil {
+		t.Fatalf("failed to create dialog partitions: %s", err)
+	}
+
+	idGenerator := idgen.NewUUIDv7()
+
+	newID := func() string {
+		id, err := idGenerator.New()
+		if err != nil {
+			t.Fatalf("failed to generate id: %s", err)
+		}
+
+		return id
+	}
+
+	// The database keeps rows of earlier runs, fresh users and legacy ids keep them apart.
+	userID, peerID := newID(), newID()
+	legacyID := int(time.Now().Unix() % 1_000_000_000)
+
+	dialogRepository := NewDialogRepository(postgresDB)
+
+	sent, err := dialogRepository.Add(ctx, repository.DialogMessage{From: userID, To: peerID, Text: "sent"})
+	if err != nil {
+		t.Fatalf("failed to add dialog message: %s", err)
+	}
+
+	var imported []repository.DialogMessage
+
+	// The first message is older than the sent one, the second one is newer.
+	for i, offset := range []time.Duration{-time.Minute, time.Minute} {
+		createdAt := sent.CreatedAt.Add(offset)
+
+		id, err := idGenerator.NewAt(createdAt)
+		if err != nil {
+			t.Fatalf("failed to generate id: %s", err)
+		}
+
+		messageLegacyID := strconv.Itoa(legacyID + i)
+
+		imported = append(imported, repository.DialogMessage{
+			ID:        id,
+			LegacyID:  &messageLegacyID,
+			From:      peerID,
+			To:        userID,
+			Text:      "imported " + messageLegacyID,
+			CreatedAt: createdAt,
+		})
+	}
+
+	importRepository := NewDialogImportRepository(postgresDB, db.NewTxManager(postgresDB, db.TxConfig{}))
+
+	result, err := importRepository.ImportDialogMessages(ctx, imported)
+	if err != nil {
+		t.Fatalf("failed to import dialog messages: %s", err)
+	}
+
+	if result.Imported != 1 || len(result.Rejected) != 1 || result.Rejected[0].DialogMessage.ID != imported[0].ID ||
+		!errors.Is(result.Rejected[0].Err, repository.ErrDialogHasNewerMessages) {
+		t.Fatalf("got %+v, want the older message rejected and the newer one imported", result)
+	}
+
+	dialogMessages, err := dialogRepository.GetDialogMessagesAfterSeq(ctx, userID, peerID, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to fetch dialog messages: %s", err)
+	}
+
+	// The sent message keeps its seq, the imported one follows it.
+	if len(dialogMessages) != 2 || dialogMessages[0].ID != sent.ID || dialogMessages[0].Seq != sent.Seq ||
+		dialogMessages[1].ID != imported[1].ID || dialogMessages[1].Seq <= sent.Seq {
+		t.Fatalf("got dialog %+v, want the sent message with seq %d followed by the imported one", dialogMessages, sent.Seq)
+	}
+
+	result, err = importRepository.ImportDialogMessages(ctx, imported[1:])
+	if err != nil {
+		t.Fatalf("failed to import dialog messages again: %s", err)
+	}
+
+	if result.Skipped != 1 || result.Imported != 0 || len(result.Rejected) != 0 {
+		t.Errorf("got %+v on the second import, want the imported message skipped", result)
+	}
+}
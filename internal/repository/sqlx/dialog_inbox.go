@@ -0,0 +1,201 @@
+package sqlx
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"myfacebook-dialog/internal/db"
+	"myfacebook-dialog/internal/repository"
+)
+
+type DialogInboxRepository struct {
+	db *db.DB
+}
+
+type dialogInboxRow struct {
+	UserID         string         `db:"user_id"`
+	PeerID         string         `db:"peer_id"`
+	LastMessageID  string         `db:"last_message_id"`
+	LastSenderID   string         `db:"last_sender_id"`
+	LastText       string         `db:"last_text"`
+	LastSeq        int64          `db:"last_seq"`
+	LastMessageAt  time.Time      `db:"last_message_at"`
+	UnreadCount    int            `db:"unread_count"`
+	MutedUntil     *time.Time     `db:"muted_until"`
+	Archived       bool           `db:"archived"`
+	Folders        pq.StringArray `db:"folders"`
+	DraftText      *string        `db:"draft_text"`
+	DraftUpdatedAt *time.Time     `db:"draft_updated_at"`
+}
+
+func NewDialogInboxRepository(db *db.DB) *DialogInboxRepository {
+	return &DialogInboxRepository{
+		db: db,
+	}
+}
+
+func (r *DialogInboxRepository) Record(ctx context.Context, dialogMessage repository.DialogMessage) error {
+	dbConn := r.db.GetConnection(ctx)
+
+	values := "($1, $2, $3, $1, $4, $5, $6, 0), ($2, $1, $3, $1, $4, $5, $6, 1)"
+
+	// A message to oneself has a single inbox row, it is read by its sender.
+	if dialogMessage.From == dialogMessage.To {
+		values = "($1, $2, $3, $1, $4, $5, $6, 0)"
+	}
+
+	sqlQuery := `INSERT INTO dialog_inbox AS i
+		(user_id, peer_id, last_message_id, last_sender_id, last_text, last_seq, last_message_at, unread_count)
+		VALUES ` + values + `
+		ON CONFLICT (user_id, peer_id) DO UPDATE
+		SET last_message_id = CASE WHEN excluded.last_seq > i.last_seq THEN excluded.last_message_id ELSE i.last_message_id END,
+			last_sender_id = CASE WHEN excluded.last_seq > i.last_seq THEN excluded.last_sender_id ELSE i.last_sender_id END,
+			last_text = CASE WHEN excluded.last_seq > i.last_seq THEN excluded.last_text ELSE i.last_text END,
+			last_message_at = CASE WHEN excluded.last_seq > i.last_seq THEN excluded.last_message_at ELSE i.last_message_at END,
+			last_seq = greatest(excluded.last_seq, i.last_seq),
+			unread_count = CASE WHEN i.user_id = excluded.last_sender_id THEN 0 ELSE i.unread_count + 1 END`
+
+	_, err := dbConn.ExecContext(ctx, sqlQuery, dialogMessage.From, dialogMessage.To, dialogMessage.ID, dialogMessage.Text,
+		dialogMessage.Seq, dialogMessage.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record dialog message in dialog inbox: %w", err)
+	}
+
+	return nil
+}
+
+func (r *DialogInboxRepository) MarkRead(ctx context.Context, userID, peerID string) error {
+	dbConn := r.db.GetConnection(ctx)
+
+	result, err := dbConn.ExecContext(ctx, `UPDATE dialog_inbox SET unread_count=0 WHERE user_id=$1 AND peer_id=$2`, userID, peerID)
+	if err != nil {
+		return fmt.Errorf("failed to mark dialog inbox entry read in db: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows of dialog inbox update: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+// GetDialogInbox joins the settings and the drafts of the user, dialogs without settings match the filters by the defaults.
+func (r *DialogInboxRepository) GetDialogInbox(ctx context.Context, userID string, filter repository.DialogInboxFilter,
+	after *repository.DialogInboxCursor, limit int,
+) ([]repository.DialogInboxEntry, error) {
+	dbConn := r.db.GetRetryableConnection(ctx)
+
+	conditions := []string{"i.user_id=$1"}
+	args := []interface{}{userID}
+
+	if filter.Archived != nil {
+		args = append(args, *filter.Archived)
+		conditions = append(conditions, fmt.Sprintf("coalesce(s.archived, false)=$%d", len(args)))
+	}
+
+	if filter.Muted != nil {
+		mutedCondition := "coalesce(s.muted_until > CURRENT_TIMESTAMP, false)"
+		if !*filter.Muted {
+			mutedCondition = "NOT " + mutedCondition
+		}
+
+		conditions = append(conditions, mutedCondition)
+	}
+
+	if filter.Folder != "" {
+		args = append(args, filter.Folder)
+		conditions = append(conditions, fmt.Sprintf("coalesce($%d = ANY(s.folders), false)", len(args)))
+	}
+
+	if after != nil {
+		args = append(args, after.LastMessageAt, after.PeerID)
+		conditions = append(conditions, fmt.Sprintf("(i.last_message_at, i.peer_id) < ($%d, $%d)", len(args)-1, len(args)))
+	}
+
+	args = append(args, limit)
+
+	sqlQuery := `SELECT i.user_id, i.peer_id, i.last_message_id, i.last_sender_id, i.last_text, i.last_seq, i.last_message_at,
+			i.unread_count, s.muted_until, coalesce(s.archived, false) AS archived, coalesce(s.folders, '{}') AS folders,
+			d.text AS draft_text, d.updated_at AS draft_updated_at
+		FROM dialog_inbox i
+		LEFT JOIN dialog_settings s ON s.user_id = i.user_id AND s.peer_id = i.peer_id
+		LEFT JOIN dialog_drafts d ON d.user_id = i.user_id AND d.peer_id = i.peer_id
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY i.last_message_at DESC, i.peer_id DESC
+		LIMIT ` + fmt.Sprintf("$%d", len(args))
+
+	var rows []dialogInboxRow
+
+	err := dbConn.SelectContext(ctx, &rows, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dialog inbox by userID: %w", err)
+	}
+
+	dialogInbox := make([]repository.DialogInboxEntry, 0, len(rows))
+	for _, row := range rows {
+		dialogInbox = append(dialogInbox, row.toDialogInboxEntry())
+	}
+
+	return dialogInbox, nil
+}
+
+func (r *DialogInboxRepository) GetUnreadCount(ctx context.Context, userID string) (int, error) {
+	dbConn := r.db.GetRetryableConnection(ctx)
+
+	var unreadCount int
+
+	err := dbConn.GetContext(ctx, &unreadCount, `SELECT coalesce(sum(unread_count), 0) FROM dialog_inbox WHERE user_id=$1`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch dialog inbox unread count by userID: %w", err)
+	}
+
+	return unreadCount, nil
+}
+
+func (row dialogInboxRow) toDialogInboxEntry() repository.DialogInboxEntry {
+	receiverID := row.PeerID
+	if row.LastSenderID == row.PeerID {
+		receiverID = row.UserID
+	}
+
+	var draft *repository.DialogDraft
+
+	if row.DraftText != nil && row.DraftUpdatedAt != nil {
+		draft = &repository.DialogDraft{
+			UserID:    row.UserID,
+			PeerID:    row.PeerID,
+			Text:      *row.DraftText,
+			UpdatedAt: *row.DraftUpdatedAt,
+		}
+	}
+
+	return repository.DialogInboxEntry{
+		UserID: row.UserID,
+		PeerID: row.PeerID,
+		LastMessage: repository.DialogMessage{
+			ID:        row.LastMessageID,
+			From:      row.LastSenderID,
+			To:        receiverID,
+			Text:      row.LastText,
+			Seq:       row.LastSeq,
+			CreatedAt: row.LastMessageAt,
+		},
+		UnreadCount: row.UnreadCount,
+		Settings: repository.DialogSettings{
+			UserID:     row.UserID,
+			PeerID:     row.PeerID,
+			MutedUntil: row.MutedUntil,
+			Archived:   row.Archived,
+			Folders:    row.Folders,
+		},
+		Draft: draft,
+	}
+}
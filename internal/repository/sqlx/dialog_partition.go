@@ -0,0 +1,45 @@
+package sqlx
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"myfacebook-dialog/internal/db"
+)
+
+type DialogPartitionRepository struct {
+	db *db.DB
+}
+
+func NewDialogPartitionRepository(db *db.DB) *DialogPartitionRepository {
+	return &DialogPartitionRepository{
+		db: db,
+	}
+}
+
+func (r *DialogPartitionRepository) EnsureDialogPartitions(ctx context.Context, monthsAhead int) ([]string, error) {
+	dbConn := r.db.GetConnection(ctx)
+
+	var partitions []string
+
+	err := dbConn.SelectContext(ctx, &partitions, `SELECT ensure_dialogs_partitions($1)`, monthsAhead)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dialog partitions: %w", err)
+	}
+
+	return partitions, nil
+}
+
+func (r *DialogPartitionRepository) ExpireDialogPartitions(ctx context.Context, olderThan time.Time, drop bool) ([]string, error) {
+	dbConn := r.db.GetConnection(ctx)
+
+	var partitions []string
+
+	err := dbConn.SelectContext(ctx, &partitions, `SELECT expire_dialogs_partitions($1, $2)`, olderThan, drop)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire dialog partitions: %w", err)
+	}
+
+	return partitions, nil
+}
@@ -0,0 +1,148 @@
+package sqlx
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+	"myfacebook-dialog/internal/db"
+	"myfacebook-dialog/internal/repository"
+)
+
+// dialogPinLockClass is the first key of the transaction advisory locks of dialog pins, the second one is
+// the hash of the dialog. Two-key locks never collide with the single-key locks of the database.
+const dialogPinLockClass = 0x70696e
+
+type DialogPinRepository struct {
+	db        *db.DB
+	txManager *db.TxManager
+}
+
+func NewDialogPinRepository(db *db.DB, txManager *db.TxManager) *DialogPinRepository {
+	return &DialogPinRepository{
+		db:        db,
+		txManager: txManager,
+	}
+}
+
+// Add pins the message unless the dialog has limit live pins already. Pins of a dialog are added one at a time
+// under a transaction advisory lock of the dialog, so concurrent pins can not exceed the limit.
+func (r *DialogPinRepository) Add(ctx context.Context, userID, peerID string, pin repository.DialogPin, limit int,
+	livePins repository.LiveDialogPinsFunc,
+) error {
+	firstUserID, secondUserID := repository.DialogParticipants(userID, peerID)
+
+	//nolint:wrapcheck
+	return r.txManager.InTx(ctx, func(ctx context.Context) error {
+		dbConn := r.db.GetConnection(ctx)
+
+		_, err := dbConn.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1, hashtext($2))`,
+			dialogPinLockClass, firstUserID+":"+secondUserID)
+		if err != nil {
+			return fmt.Errorf("failed to lock dialog pins: %w", err)
+		}
+
+		var pinned bool
+
+		err = dbConn.GetContext(ctx, &pinned, `SELECT EXISTS(SELECT 1 FROM dialog_pins WHERE message_id=$1)`, pin.MessageID)
+		if err != nil {
+			return fmt.Errorf("failed to check dialog pin existence: %w", err)
+		}
+
+		if pinned {
+			return nil
+		}
+
+		count, err := r.countLiveDialogPins(ctx, firstUserID, secondUserID, livePins)
+		if err != nil {
+			return err
+		}
+
+		if count >= limit {
+			return repository.ErrPinLimitReached
+		}
+
+		sqlQuery := `INSERT INTO dialog_pins (message_id, first_user_id, second_user_id, pinned_by)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (message_id) DO NOTHING`
+
+		if _, err := dbConn.ExecContext(ctx, sqlQuery, pin.MessageID, firstUserID, secondUserID, pin.PinnedBy); err != nil {
+			return fmt.Errorf("failed to add dialog pin to db: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// countLiveDialogPins deletes the stale pins of the dialog and returns the number of the others.
+func (r *DialogPinRepository) countLiveDialogPins(ctx context.Context, firstUserID, secondUserID string,
+	livePins repository.LiveDialogPinsFunc,
+) (int, error) {
+	dbConn := r.db.GetConnection(ctx)
+
+	dialogPins, err := r.GetDialogPins(ctx, firstUserID, secondUserID)
+	if err != nil {
+		return 0, err
+	}
+
+	liveDialogPins, err := livePins(ctx, dialogPins)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve live dialog pins: %w", err)
+	}
+
+	staleMessageIDs := repository.StaleDialogPinMessageIDs(dialogPins, liveDialogPins)
+	if len(staleMessageIDs) == 0 {
+		return len(liveDialogPins), nil
+	}
+
+	sqlQuery := `DELETE FROM dialog_pins WHERE first_user_id=$1 AND second_user_id=$2 AND message_id = ANY($3::text[])`
+
+	if _, err := dbConn.ExecContext(ctx, sqlQuery, firstUserID, secondUserID, pq.Array(staleMessageIDs)); err != nil {
+		return 0, fmt.Errorf("failed to delete stale dialog pins: %w", err)
+	}
+
+	return len(liveDialogPins), nil
+}
+
+func (r *DialogPinRepository) Delete(ctx context.Context, userID, peerID, messageID string) error {
+	dbConn := r.db.GetConnection(ctx)
+
+	firstUserID, secondUserID := repository.DialogParticipants(userID, peerID)
+
+	sqlQuery := `DELETE FROM dialog_pins WHERE message_id=$1 AND first_user_id=$2 AND second_user_id=$3`
+
+	result, err := dbConn.ExecContext(ctx, sqlQuery, messageID, firstUserID, secondUserID)
+	if err != nil {
+		return fmt.Errorf("failed to delete dialog pin from db: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows of dialog pin delete: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *DialogPinRepository) GetDialogPins(ctx context.Context, userID, peerID string) ([]repository.DialogPin, error) {
+	dbConn := r.db.GetRetryableConnection(ctx)
+
+	firstUserID, secondUserID := repository.DialogParticipants(userID, peerID)
+
+	var dialogPins []repository.DialogPin
+
+	sqlQuery := `SELECT message_id, pinned_by 
+		FROM dialog_pins WHERE first_user_id=$1 AND second_user_id=$2 
+		ORDER BY created_at`
+
+	err := dbConn.SelectContext(ctx, &dialogPins, sqlQuery, firstUserID, secondUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dialog pins: %w", err)
+	}
+
+	return dialogPins, nil
+}
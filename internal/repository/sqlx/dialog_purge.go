@@ -0,0 +1,62 @@
+package sqlx
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+	"myfacebook-dialog/internal/db"
+	"myfacebook-dialog/internal/repository"
+)
+
+const dialogPurgeCondition = `created_at < $1 
+	AND (message_type = $2 OR ($2 = '' AND message_type <> ALL($3::varchar[])))`
+
+type DialogPurgeRepository struct {
+	db *db.DB
+}
+
+func NewDialogPurgeRepository(db *db.DB) *DialogPurgeRepository {
+	return &DialogPurgeRepository{
+		db: db,
+	}
+}
+
+func (r *DialogPurgeRepository) CountDialogMessages(ctx context.Context, filter repository.DialogPurgeFilter) (int64, error) {
+	dbConn := r.db.GetRetryableConnection(ctx)
+
+	var count int64
+
+	sqlQuery := `SELECT count(*) FROM dialogs WHERE ` + dialogPurgeCondition
+
+	err := dbConn.GetContext(ctx, &count, sqlQuery, filter.CreatedBefore, filter.MessageType, pq.Array(filter.ExceptMessageTypes))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count dialog messages to purge: %w", err)
+	}
+
+	return count, nil
+}
+
+// DeleteDialogMessages deletes a batch by the unique (id, created_at) key, the created_at bound repeated outside
+// lets the delete skip partitions that are too recent.
+func (r *DialogPurgeRepository) DeleteDialogMessages(ctx context.Context, filter repository.DialogPurgeFilter, limit int) (int64, error) {
+	dbConn := r.db.GetConnection(ctx)
+
+	sqlQuery := `DELETE FROM dialogs 
+		WHERE created_at < $1 AND (id, created_at) IN (
+			SELECT id, created_at FROM dialogs WHERE ` + dialogPurgeCondition + ` 
+			LIMIT $4
+		)`
+
+	result, err := dbConn.ExecContext(ctx, sqlQuery, filter.CreatedBefore, filter.MessageType, pq.Array(filter.ExceptMessageTypes), limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete dialog messages: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get number of deleted dialog messages: %w", err)
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,150 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"myfacebook-dialog/internal/db"
+	"myfacebook-dialog/internal/repository"
+)
+
+type DialogSettingsRepository struct {
+	db *db.DB
+}
+
+type dialogSettingsRow struct {
+	UserID     string         `db:"user_id"`
+	PeerID     string         `db:"peer_id"`
+	MutedUntil *time.Time     `db:"muted_until"`
+	Archived   bool           `db:"archived"`
+	Folders    pq.StringArray `db:"folders"`
+}
+
+func NewDialogSettingsRepository(db *db.DB) *DialogSettingsRepository {
+	return &DialogSettingsRepository{
+		db: db,
+	}
+}
+
+func (r *DialogSettingsRepository) Save(ctx context.Context, settings repository.DialogSettings) error {
+	dbConn := r.db.GetConnection(ctx)
+
+	sqlQuery := `INSERT INTO dialog_settings (user_id, peer_id, muted_until, archived, folders)
+		VALUES (:user_id, :peer_id, :muted_until, :archived, :folders)
+		ON CONFLICT (user_id, peer_id) DO UPDATE 
+		SET muted_until=excluded.muted_until, archived=excluded.archived, folders=excluded.folders, updated_at=CURRENT_TIMESTAMP`
+
+	_, err := dbConn.NamedExecContext(ctx, sqlQuery, dialogSettingsRow{
+		UserID:     settings.UserID,
+		PeerID:     settings.PeerID,
+		MutedUntil: settings.MutedUntil,
+		Archived:   settings.Archived,
+		Folders:    settings.Folders,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save dialog settings to db: %w", err)
+	}
+
+	return nil
+}
+
+func (r *DialogSettingsRepository) Delete(ctx context.Context, userID, peerID string) error {
+	dbConn := r.db.GetConnection(ctx)
+
+	result, err := dbConn.ExecContext(ctx, `DELETE FROM dialog_settings WHERE user_id=$1 AND peer_id=$2`, userID, peerID)
+	if err != nil {
+		return fmt.Errorf("failed to delete dialog settings from db: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows of dialog settings delete: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *DialogSettingsRepository) GetDialogSettings(ctx context.Context, userID, peerID string) (*repository.DialogSettings, error) {
+	dbConn := r.db.GetRetryableConnection(ctx)
+
+	var row dialogSettingsRow
+
+	sqlQuery := `SELECT user_id, peer_id, muted_until, archived, folders 
+		FROM dialog_settings WHERE user_id=$1 AND peer_id=$2`
+
+	err := dbConn.GetContext(ctx, &row, sqlQuery, userID, peerID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotFound
+		}
+
+		return nil, fmt.Errorf("failed to fetch dialog settings by userID and peerID: %w", err)
+	}
+
+	dialogSettings := row.toDialogSettings()
+
+	return &dialogSettings, nil
+}
+
+func (r *DialogSettingsRepository) GetDialogSettingsByUserID(ctx context.Context, userID string, filter repository.DialogSettingsFilter) ([]repository.DialogSettings, error) {
+	dbConn := r.db.GetRetryableConnection(ctx)
+
+	conditions := []string{"user_id=$1"}
+	args := []interface{}{userID}
+
+	if filter.Archived != nil {
+		args = append(args, *filter.Archived)
+		conditions = append(conditions, fmt.Sprintf("archived=$%d", len(args)))
+	}
+
+	if filter.Muted != nil {
+		mutedCondition := "coalesce(muted_until > CURRENT_TIMESTAMP, false)"
+		if !*filter.Muted {
+			mutedCondition = "NOT " + mutedCondition
+		}
+
+		conditions = append(conditions, mutedCondition)
+	}
+
+	if filter.Folder != "" {
+		args = append(args, filter.Folder)
+		conditions = append(conditions, fmt.Sprintf("$%d = ANY(folders)", len(args)))
+	}
+
+	sqlQuery := `SELECT user_id, peer_id, muted_until, archived, folders 
+		FROM dialog_settings WHERE ` + strings.Join(conditions, " AND ") + ` 
+		ORDER BY updated_at DESC`
+
+	var rows []dialogSettingsRow
+
+	err := dbConn.SelectContext(ctx, &rows, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dialog settings by userID: %w", err)
+	}
+
+	dialogSettings := make([]repository.DialogSettings, 0, len(rows))
+	for _, row := range rows {
+		dialogSettings = append(dialogSettings, row.toDialogSettings())
+	}
+
+	return dialogSettings, nil
+}
+
+func (row dialogSettingsRow) toDialogSettings() repository.DialogSettings {
+	return repository.DialogSettings{
+		UserID:     row.UserID,
+		PeerID:     row.PeerID,
+		MutedUntil: row.MutedUntil,
+		Archived:   row.Archived,
+		Folders:    row.Folders,
+	}
+}
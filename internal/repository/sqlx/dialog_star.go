@@ -0,0 +1,94 @@
+package sqlx
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+	"myfacebook-dialog/internal/db"
+	"myfacebook-dialog/internal/repository"
+)
+
+type DialogStarRepository struct {
+	db *db.DB
+}
+
+func NewDialogStarRepository(db *db.DB) *DialogStarRepository {
+	return &DialogStarRepository{
+		db: db,
+	}
+}
+
+func (r *DialogStarRepository) Add(ctx context.Context, userID, messageID string) error {
+	dbConn := r.db.GetConnection(ctx)
+
+	sqlQuery := `INSERT INTO dialog_stars (user_id, message_id) VALUES ($1, $2) 
+		ON CONFLICT (user_id, message_id) DO NOTHING`
+
+	_, err := dbConn.ExecContext(ctx, sqlQuery, userID, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to add dialog star to db: %w", err)
+	}
+
+	return nil
+}
+
+func (r *DialogStarRepository) Delete(ctx context.Context, userID, messageID string) error {
+	dbConn := r.db.GetConnection(ctx)
+
+	result, err := dbConn.ExecContext(ctx, `DELETE FROM dialog_stars WHERE user_id=$1 AND message_id=$2`, userID, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to delete dialog star from db: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows of dialog star delete: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *DialogStarRepository) DeleteByMessageIDs(ctx context.Context, userID string, messageIDs []string) error {
+	dbConn := r.db.GetConnection(ctx)
+
+	sqlQuery := `DELETE FROM dialog_stars WHERE user_id=$1 AND message_id = ANY($2::text[])`
+
+	if _, err := dbConn.ExecContext(ctx, sqlQuery, userID, pq.Array(messageIDs)); err != nil {
+		return fmt.Errorf("failed to delete dialog stars by message ids from db: %w", err)
+	}
+
+	return nil
+}
+
+func (r *DialogStarRepository) GetDialogStarsByUserID(ctx context.Context, userID string, after *repository.DialogStarCursor, limit int) ([]repository.DialogStar, error) {
+	dbConn := r.db.GetRetryableConnection(ctx)
+
+	condition := "user_id=$1"
+	args := []interface{}{userID}
+
+	if after != nil {
+		condition += " AND (created_at, message_id) < ($2, $3)"
+		args = append(args, after.CreatedAt.UTC(), after.MessageID)
+	}
+
+	args = append(args, limit)
+
+	var dialogStars []repository.DialogStar
+
+	sqlQuery := `SELECT message_id, created_at 
+		FROM dialog_stars WHERE ` + condition + ` 
+		ORDER BY created_at DESC, message_id DESC 
+		LIMIT ` + fmt.Sprintf("$%d", len(args))
+
+	err := dbConn.SelectContext(ctx, &dialogStars, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dialog stars by userID: %w", err)
+	}
+
+	return dialogStars, nil
+}
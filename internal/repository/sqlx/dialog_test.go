@@ -0,0 +1,138 @@
+package sqlx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"myfacebook-dialog/internal/db"
+	"myfacebook-dialog/internal/db/dbtest"
+)
+
+const (
+	explainFirstUserID  = "00000000-0000-4000-8000-000000000001"
+	explainSecondUserID = "00000000-0000-4000-8000-000000000002"
+)
+
+// planNode is a node of EXPLAIN (FORMAT JSON) output.
+type planNode struct {
+	NodeType     string     `json:"Node Type"`
+	RelationName string     `json:"Relation Name"`
+	IndexName    string     `json:"Index Name"`
+	Plans        []planNode `json:"Plans"`
+}
+
+// TestDialogReadsUseDialogKeySeqIndex checks that message reads scan dialogs_dialog_key_seq_idx in order.
+// The reads return the text of the messages, which the index does not hold, so an Index Only Scan is not expected:
+// a covering index with the text would duplicate the table and break on texts over the B-tree row size limit.
+func TestDialogReadsUseDialogKeySeqIndex(t *testing.T) {
+	postgresDB := dbtest.Postgres(t)
+	ctx := context.Background()
+
+	if _, err := NewDialogPartitionRepository(postgresDB).EnsureDialogPartitions(ctx, 0); err != nil {
+		t.Fatalf("failed to create dialog partitions: %s", err)
+	}
+
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		query string
+		args  []interface{}
+	}{
+		{
+			name:  "GetDialogMessagesAfterSeq",
+			query: dialogMessagesAfterSeqQuery,
+			args: []interface{}{explainFirstUserID, explainSecondUserID, 10, 50,
+				fmt.Sprintf("%d seconds", int(partitionPruningSlack.Seconds()))},
+		},
+		{
+			name:  "GetDialogMessagesCreatedBetween",
+			query: dialogMessagesCreatedBetweenQuery,
+			args:  []interface{}{explainFirstUserID, explainSecondUserID, now.AddDate(0, -1, 0), now},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plan := explain(ctx, t, postgresDB, test.query, test.args...)
+
+			var dialogIndexScans int
+
+			walkPlan(plan, func(node planNode) {
+				if node.NodeType == "Sort" {
+					t.Errorf("plan sorts messages, expected them in the order of the index")
+				}
+
+				if !strings.HasPrefix(node.RelationName, "dialogs") {
+					return
+				}
+
+				switch node.NodeType {
+				case "Index Scan", "Index Only Scan":
+					if !strings.HasSuffix(node.IndexName, "dialog_key_seq_idx") {
+						t.Errorf("%s of %s uses index %s, expected dialogs_dialog_key_seq_idx", node.NodeType, node.RelationName, node.IndexName)
+					}
+
+					dialogIndexScans++
+				default:
+					t.Errorf("%s reads %s, expected an index scan on dialogs_dialog_key_seq_idx", node.NodeType, node.RelationName)
+				}
+			})
+
+			if dialogIndexScans == 0 {
+				t.Errorf("plan does not scan dialogs_dialog_key_seq_idx")
+			}
+		})
+	}
+}
+
+// explain returns the plan of the query. Tiny test tables are cheaper to scan sequentially,
+// so sequential scans are turned off to get the plan of a table with data.
+func explain(ctx context.Context, t *testing.T, postgresDB *db.DB, query string, args ...interface{}) planNode {
+	t.Helper()
+
+	var plans []struct {
+		Plan planNode `json:"Plan"`
+	}
+
+	err := db.NewTxManager(postgresDB, db.TxConfig{}).InTx(ctx, func(ctx context.Context) error {
+		dbConn := postgresDB.GetConnection(ctx)
+
+		if _, err := dbConn.ExecContext(ctx, `SET LOCAL enable_seqscan = off`); err != nil {
+			return fmt.Errorf("failed to turn off sequential scans: %w", err)
+		}
+
+		var planJSON []byte
+
+		if err := dbConn.GetContext(ctx, &planJSON, `EXPLAIN (FORMAT JSON) `+query, args...); err != nil {
+			return fmt.Errorf("failed to explain query: %w", err)
+		}
+
+		if err := json.Unmarshal(planJSON, &plans); err != nil {
+			return fmt.Errorf("failed to decode plan: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plans) != 1 {
+		t.Fatalf("expected one plan, got %d", len(plans))
+	}
+
+	return plans[0].Plan
+}
+
+func walkPlan(node planNode, visit func(node planNode)) {
+	visit(node)
+
+	for _, child := range node.Plans {
+		walkPlan(child, visit)
+	}
+}
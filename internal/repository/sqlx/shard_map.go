@@ -0,0 +1,53 @@
+package sqlx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"myfacebook-dialog/internal/db"
+)
+
+var ErrShardMapMismatch = errors.New("shard map mismatch")
+
+type ShardMapRepository struct {
+	db *db.DB
+}
+
+func NewShardMapRepository(db *db.DB) *ShardMapRepository {
+	return &ShardMapRepository{
+		db: db,
+	}
+}
+
+// Register records the place of the database in the given shard map version.
+// It fails if the database was registered at another place of the same version,
+// so a reordered or resized shard list cannot silently route dialogs to wrong shards.
+func (r *ShardMapRepository) Register(ctx context.Context, version, shardIndex, shardCount int) error {
+	dbConn := r.db.GetConnection(ctx)
+
+	sqlQuery := `INSERT INTO shard_map (version, shard_index, shard_count) VALUES ($1, $2, $3) 
+		ON CONFLICT (version) DO NOTHING`
+
+	_, err := dbConn.ExecContext(ctx, sqlQuery, version, shardIndex, shardCount)
+	if err != nil {
+		return fmt.Errorf("failed to register shard in shard map: %w", err)
+	}
+
+	var registered struct {
+		ShardIndex int `db:"shard_index"`
+		ShardCount int `db:"shard_count"`
+	}
+
+	err = dbConn.GetContext(ctx, &registered, `SELECT shard_index, shard_count FROM shard_map WHERE version=$1`, version)
+	if err != nil {
+		return fmt.Errorf("failed to fetch shard map registration: %w", err)
+	}
+
+	if registered.ShardIndex != shardIndex || registered.ShardCount != shardCount {
+		return fmt.Errorf("%w: version %d is registered as shard %d of %d, configured as shard %d of %d", ErrShardMapMismatch,
+			version, registered.ShardIndex, registered.ShardCount, shardIndex, shardCount)
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"myfacebook-dialog/internal/db"
+	"myfacebook-dialog/internal/repository"
+)
+
+// initialCheckpoint sorts before every message id.
+const initialCheckpoint = "00000000-0000-0000-0000-000000000000"
+
+type ShardMigrationRepository struct {
+	db *db.DB
+}
+
+func NewShardMigrationRepository(db *db.DB) *ShardMigrationRepository {
+	return &ShardMigrationRepository{
+		db: db,
+	}
+}
+
+func (r *ShardMigrationRepository) Save(ctx context.Context, shardMigration repository.ShardMigration) error {
+	dbConn := r.db.GetConnection(ctx)
+
+	sqlQuery := `INSERT INTO shard_migrations (to_version, from_version, phase) 
+		VALUES (:to_version, :from_version, :phase)
+		ON CONFLICT (to_version) DO UPDATE 
+		SET from_version=excluded.from_version, phase=excluded.phase, updated_at=CURRENT_TIMESTAMP`
+
+	_, err := dbConn.NamedExecContext(ctx, sqlQuery, shardMigration)
+	if err != nil {
+		return fmt.Errorf("failed to save shard migration to db: %w", err)
+	}
+
+	return nil
+}
+
+func (r *ShardMigrationRepository) GetShardMigration(ctx context.Context, toVersion int) (*repository.ShardMigration, error) {
+	dbConn := r.db.GetRetryableConnection(ctx)
+
+	var shardMigration repository.ShardMigration
+
+	sqlQuery := `SELECT to_version, from_version, phase FROM shard_migrations WHERE to_version=$1`
+
+	err := dbConn.GetContext(ctx, &shardMigration, sqlQuery, toVersion)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotFound
+		}
+
+		return nil, fmt.Errorf("failed to fetch shard migration: %w", err)
+	}
+
+	return &shardMigration, nil
+}
+
+func (r *ShardMigrationRepository) SaveCheckpoint(ctx context.Context, toVersion, shardIndex int, lastMessageID string) error {
+	dbConn := r.db.GetConnection(ctx)
+
+	sqlQuery := `INSERT INTO shard_migration_checkpoints (to_version, shard_index, last_message_id) 
+		VALUES ($1, $2, $3)
+		ON CONFLICT (to_version, shard_index) DO UPDATE 
+		SET last_message_id=excluded.last_message_id, updated_at=CURRENT_TIMESTAMP`
+
+	_, err := dbConn.ExecContext(ctx, sqlQuery, toVersion, shardIndex, lastMessageID)
+	if err != nil {
+		return fmt.Errorf("failed to save shard migration checkpoint to db: %w", err)
+	}
+
+	return nil
+}
+
+// GetCheckpoint returns the id of the last copied message of the shard, or the zero UUID when nothing is copied yet.
+func (r *ShardMigrationRepository) GetCheckpoint(ctx context.Context, toVersion, shardIndex int) (string, error) {
+	dbConn := r.db.GetRetryableConnection(ctx)
+
+	var lastMessageID string
+
+	sqlQuery := `SELECT last_message_id FROM shard_migration_checkpoints WHERE to_version=$1 AND shard_index=$2`
+
+	err := dbConn.GetContext(ctx, &lastMessageID, sqlQuery, toVersion, shardIndex)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return initialCheckpoint, nil
+		}
+
+		return "", fmt.Errorf("failed to fetch shard migration checkpoint: %w", err)
+	}
+
+	return lastMessageID, nil
+}
@@ -0,0 +1,94 @@
+package strangler
+
+import (
+	"time"
+
+	"myfacebook-dialog/internal/repository"
+)
+
+// Kinds of messages that differ between the service and the monolith.
+const (
+	mismatchMissingInService  = "missing_in_service"
+	mismatchMissingInMonolith = "missing_in_monolith"
+	mismatchDifferent         = "different"
+)
+
+// shadowPage is a page of a dialog read from the service to be compared with the monolith.
+type shadowPage struct {
+	senderID       string
+	receiverID     string
+	afterSeq       int64
+	limit          int
+	readAt         time.Time
+	dialogMessages []repository.DialogMessage
+}
+
+type pageDiff struct {
+	counts map[string]int
+	// firstSeq is the seq of the first message that differs.
+	firstSeq int64
+}
+
+func (d *pageDiff) add(kind string, seq int64) {
+	d.counts[kind]++
+
+	if d.firstSeq == 0 || seq < d.firstSeq {
+		d.firstSeq = seq
+	}
+}
+
+func (d *pageDiff) empty() bool {
+	return len(d.counts) == 0
+}
+
+// compare matches the message with seq N to the N-th message of the monolith, both number messages of a dialog
+// in the order they were sent. Messages purged by retention shift the numbering and show up as differences.
+// Messages the monolith got after the page was read belong to later pages and are not compared.
+func (p shadowPage) compare(legacyDialogMessages []repository.DialogMessage) pageDiff {
+	diff := pageDiff{counts: make(map[string]int)}
+
+	for len(legacyDialogMessages) > 0 && legacyDialogMessages[len(legacyDialogMessages)-1].CreatedAt.After(p.readAt) {
+		legacyDialogMessages = legacyDialogMessages[:len(legacyDialogMessages)-1]
+	}
+
+	seqs := make(map[int64]struct{}, len(p.dialogMessages))
+
+	for _, dialogMsg := range p.dialogMessages {
+		seqs[dialogMsg.Seq] = struct{}{}
+
+		if dialogMsg.Seq < 1 || dialogMsg.Seq > int64(len(legacyDialogMessages)) {
+			diff.add(mismatchMissingInMonolith, dialogMsg.Seq)
+
+			continue
+		}
+
+		if !sameDialogMessage(dialogMsg, legacyDialogMessages[dialogMsg.Seq-1]) {
+			diff.add(mismatchDifferent, dialogMsg.Seq)
+		}
+	}
+
+	// The page covers the monolith messages after afterSeq up to the limit.
+	lastSeq := int64(len(legacyDialogMessages))
+	if p.limit > 0 {
+		lastSeq = min(lastSeq, p.afterSeq+int64(p.limit))
+	}
+
+	for seq := p.afterSeq + 1; seq <= lastSeq; seq++ {
+		if _, ok := seqs[seq]; !ok {
+			diff.add(mismatchMissingInService, seq)
+		}
+	}
+
+	return diff
+}
+
+// sameDialogMessage compares what both stores keep of a message. Creation times differ by the time the message took
+// to reach the monolith, ids differ unless the message was imported from the monolith.
+func sameDialogMessage(dialogMsg, legacyDialogMsg repository.DialogMessage) bool {
+	if dialogMsg.Source == repository.DialogMessageSourceMonolith && dialogMsg.LegacyID != nil && legacyDialogMsg.LegacyID != nil &&
+		*dialogMsg.LegacyID != *legacyDialogMsg.LegacyID {
+		return false
+	}
+
+	return dialogMsg.From == legacyDialogMsg.From && dialogMsg.To == legacyDialogMsg.To && dialogMsg.Text == legacyDialogMsg.Text
+}
@@ -0,0 +1,194 @@
+// Package strangler keeps the legacy dialogs of the monolith in step with the dialog service while clients move
+// from the dialog API of the monolith to the service, and compares both to prove parity before the cutover.
+package strangler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"myfacebook-dialog/internal/repository"
+)
+
+const meterName = "myfacebook-dialog/internal/repository/strangler"
+
+type DialogRepositoryConfig struct {
+	// DualWrite sends every stored message to the monolith as well.
+	DualWrite bool
+	// ShadowReadPercent is the share of dialog reads compared with the dialog of the monolith, from 0 to 100.
+	ShadowReadPercent int
+	// ShadowReadConcurrency bounds shadow reads in flight, reads over it are not compared.
+	ShadowReadConcurrency int
+	// Timeout bounds every request to the monolith.
+	Timeout time.Duration
+}
+
+// DialogRepository stores and reads dialogs in the dialog service, which stays the source of truth: a failed write
+// to the monolith or a shadow read does not fail the request, it is logged and counted.
+type DialogRepository struct {
+	dialogRepository       repository.DialogRepository
+	legacyDialogRepository repository.LegacyDialogRepository
+	config                 DialogRepositoryConfig
+	shadowReadSlots        chan struct{}
+
+	legacyWrites         metric.Int64Counter
+	shadowReads          metric.Int64Counter
+	shadowReadMismatches metric.Int64Counter
+}
+
+func NewDialogRepository(dialogRepository repository.DialogRepository, legacyDialogRepository repository.LegacyDialogRepository,
+	config DialogRepositoryConfig,
+) (*DialogRepository, error) {
+	meter := otel.Meter(meterName)
+
+	legacyWrites, err := meter.Int64Counter("dialog.legacy.writes",
+		metric.WithDescription("Dialog messages sent to the monolith by result"),
+		metric.WithUnit("{message}"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create legacy writes counter: %w", err)
+	}
+
+	shadowReads, err := meter.Int64Counter("dialog.legacy.shadow_reads",
+		metric.WithDescription("Dialog reads compared with the monolith by result"),
+		metric.WithUnit("{read}"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create shadow reads counter: %w", err)
+	}
+
+	shadowReadMismatches, err := meter.Int64Counter("dialog.legacy.shadow_read.mismatches",
+		metric.WithDescription("Messages of shadow reads that differ from the monolith by kind"),
+		metric.WithUnit("{message}"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create shadow read mismatches counter: %w", err)
+	}
+
+	return &DialogRepository{
+		dialogRepository:       dialogRepository,
+		legacyDialogRepository: legacyDialogRepository,
+		config:                 config,
+		shadowReadSlots:        make(chan struct{}, max(config.ShadowReadConcurrency, 1)),
+		legacyWrites:           legacyWrites,
+		shadowReads:            shadowReads,
+		shadowReadMismatches:   shadowReadMismatches,
+	}, nil
+}
+
+// Add stores the message and then sends it to the monolith, even when the client has gone meanwhile.
+func (r *DialogRepository) Add(ctx context.Context, dialogMessage repository.DialogMessage) (*repository.DialogMessage, error) {
+	storedDialogMessage, err := r.dialogRepository.Add(ctx, dialogMessage)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	if !r.config.DualWrite {
+		return storedDialogMessage, nil
+	}
+
+	ctx, cancel := r.legacyContext(ctx)
+	defer cancel()
+
+	result := "ok"
+
+	if err := r.legacyDialogRepository.Add(ctx, *storedDialogMessage); err != nil {
+		slog.Error(fmt.Sprintf("Failed to send dialog message %s to the monolith: %s", storedDialogMessage.ID, err))
+
+		result = "failed"
+	}
+
+	r.legacyWrites.Add(ctx, 1, metric.WithAttributes(attribute.String("result", result)))
+
+	return storedDialogMessage, nil
+}
+
+func (r *DialogRepository) GetDialogMessagesBySenderIDAndReceiverID(ctx context.Context, senderID, receiverID string) ([]repository.DialogMessage, error) {
+	return r.GetDialogMessagesAfterSeq(ctx, senderID, receiverID, 0, 0)
+}
+
+// GetDialogMessagesAfterSeq returns the page of the service and compares a share of pages with the monolith
+// in the background.
+func (r *DialogRepository) GetDialogMessagesAfterSeq(ctx context.Context, senderID, receiverID string, afterSeq int64, limit int) ([]repository.DialogMessage, error) {
+	readAt := time.Now()
+
+	dialogMessages, err := r.dialogRepository.GetDialogMessagesAfterSeq(ctx, senderID, receiverID, afterSeq, limit)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	if r.config.ShadowReadPercent <= 0 || rand.Intn(100) >= r.config.ShadowReadPercent { //nolint:gosec
+		return dialogMessages, nil
+	}
+
+	select {
+	case r.shadowReadSlots <- struct{}{}:
+	default:
+		r.shadowReads.Add(ctx, 1, metric.WithAttributes(attribute.String("result", "skipped")))
+
+		return dialogMessages, nil
+	}
+
+	go func() {
+		defer func() { <-r.shadowReadSlots }()
+
+		ctx, cancel := r.legacyContext(ctx)
+		defer cancel()
+
+		r.shadowRead(ctx, shadowPage{
+			senderID:       senderID,
+			receiverID:     receiverID,
+			afterSeq:       afterSeq,
+			limit:          limit,
+			readAt:         readAt,
+			dialogMessages: dialogMessages,
+		})
+	}()
+
+	return dialogMessages, nil
+}
+
+func (r *DialogRepository) GetDialogMessagesByIDs(ctx context.Context, messageIDs []string) ([]repository.DialogMessage, error) {
+	return r.dialogRepository.GetDialogMessagesByIDs(ctx, messageIDs) //nolint:wrapcheck
+}
+
+// legacyContext keeps the values of the request context, such as the trace, but not its cancellation.
+func (r *DialogRepository) legacyContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx = context.WithoutCancel(ctx)
+
+	if r.config.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, r.config.Timeout)
+}
+
+func (r *DialogRepository) shadowRead(ctx context.Context, page shadowPage) {
+	legacyDialogMessages, err := r.legacyDialogRepository.GetDialogMessages(ctx, page.senderID, page.receiverID)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Shadow read of the dialog of %s and %s from the monolith failed: %s", page.senderID, page.receiverID, err))
+		r.shadowReads.Add(ctx, 1, metric.WithAttributes(attribute.String("result", "failed")))
+
+		return
+	}
+
+	diff := page.compare(legacyDialogMessages)
+	if diff.empty() {
+		r.shadowReads.Add(ctx, 1, metric.WithAttributes(attribute.String("result", "match")))
+
+		return
+	}
+
+	r.shadowReads.Add(ctx, 1, metric.WithAttributes(attribute.String("result", "mismatch")))
+
+	for kind, count := range diff.counts {
+		r.shadowReadMismatches.Add(ctx, int64(count), metric.WithAttributes(attribute.String("kind", kind)))
+	}
+
+	slog.Warn(fmt.Sprintf("Dialog of %s and %s after seq %d differs from the monolith: %d missing in the service, "+
+		"%d missing in the monolith, %d different, first difference at seq %d",
+		page.senderID, page.receiverID, page.afterSeq, diff.counts[mismatchMissingInService], diff.counts[mismatchMissingInMonolith],
+		diff.counts[mismatchDifferent], diff.firstSeq))
+}
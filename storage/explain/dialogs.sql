@@ -0,0 +1,38 @@
+-- Query plans of the dialog reads, run with `make explain` against a database with data.
+-- Expected: no plan has a Sort node, the listing uses dialogs_dialog_key_seq_idx on the partitions it scans
+-- and reports "Subplans Removed" for the partitions pruned by the created_at bound,
+-- the key-only read is an Index Only Scan once autovacuum has set the visibility map.
+
+\set first_user_id '''00000000-0000-4000-8000-000000000001'''
+\set second_user_id '''00000000-0000-4000-8000-000000000002'''
+
+-- Tiny development tables are cheaper to scan sequentially, hide that from the planner.
+set enable_seqscan = off;
+
+-- DialogRepository.GetDialogMessagesAfterSeq, the created_at bound is only known at run time,
+-- so pruning shows up in the analyzed plan.
+explain (analyze, costs off, timing off)
+with dialog_start as (
+    select coalesce(
+        (select first_created_at from dialog_sequences where dialog_key = make_dialog_key(:first_user_id, :second_user_id)), '-infinity'
+    ) - interval '1 day' as created_at
+)
+select id, legacy_id, sender_id, receiver_id, text, message_type, forwarded_from_user_id, forwarded_from_message_id, seq, created_at
+from dialogs
+where dialog_key = make_dialog_key(:first_user_id, :second_user_id)
+  and seq > 0
+  and created_at >= coalesce(
+    (select created_at - interval '1 day' from dialogs
+     where dialog_key = make_dialog_key(:first_user_id, :second_user_id) and seq = 0
+       and created_at >= (select created_at from dialog_start)),
+    (select created_at from dialog_start)
+  )
+order by seq
+limit 50;
+
+-- Key-only reads of a dialog are answered by the index alone.
+explain (costs off)
+select seq
+from dialogs
+where dialog_key = make_dialog_key(:first_user_id, :second_user_id)
+order by seq;
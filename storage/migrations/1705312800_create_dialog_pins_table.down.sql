@@ -0,0 +1,5 @@
+BEGIN;
+
+drop table dialog_pins;
+
+COMMIT;
@@ -0,0 +1,16 @@
+BEGIN;
+
+create table dialog_pins
+(
+    message_id     integer
+        primary key,
+    first_user_id  uuid not null,
+    second_user_id uuid not null,
+    pinned_by      uuid not null,
+    created_at     timestamp default CURRENT_TIMESTAMP
+);
+
+create index dialog_pins_first_user_id_second_user_id_idx
+    on dialog_pins (first_user_id, second_user_id);
+
+COMMIT;
@@ -0,0 +1,14 @@
+BEGIN;
+
+create table dialog_settings
+(
+    user_id     uuid not null,
+    peer_id     uuid not null,
+    muted_until timestamp with time zone,
+    archived    boolean        default false not null,
+    folders     varchar(100)[] default '{}' not null,
+    updated_at  timestamp      default CURRENT_TIMESTAMP,
+    primary key (user_id, peer_id)
+);
+
+COMMIT;
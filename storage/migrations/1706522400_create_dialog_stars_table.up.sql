@@ -0,0 +1,14 @@
+BEGIN;
+
+create table dialog_stars
+(
+    user_id    uuid    not null,
+    message_id integer not null,
+    created_at timestamp default CURRENT_TIMESTAMP,
+    primary key (user_id, message_id)
+);
+
+create index dialog_stars_user_id_created_at_idx
+    on dialog_stars (user_id, created_at desc);
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+alter table dialogs
+    drop column forwarded_from_user_id,
+    drop column forwarded_from_message_id;
+
+COMMIT;
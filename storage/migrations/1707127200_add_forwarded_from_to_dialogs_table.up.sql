@@ -0,0 +1,7 @@
+BEGIN;
+
+alter table dialogs
+    add column forwarded_from_user_id    uuid,
+    add column forwarded_from_message_id integer;
+
+COMMIT;
@@ -0,0 +1,12 @@
+BEGIN;
+
+create table dialog_drafts
+(
+    user_id    uuid                     not null,
+    peer_id    uuid                     not null,
+    text       varchar(1000)            not null,
+    updated_at timestamp with time zone not null,
+    primary key (user_id, peer_id)
+);
+
+COMMIT;
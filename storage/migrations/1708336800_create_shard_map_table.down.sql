@@ -0,0 +1,5 @@
+BEGIN;
+
+drop table shard_map;
+
+COMMIT;
@@ -0,0 +1,12 @@
+BEGIN;
+
+create table shard_map
+(
+    version     integer
+        primary key,
+    shard_index integer not null,
+    shard_count integer not null,
+    created_at  timestamp default CURRENT_TIMESTAMP
+);
+
+COMMIT;
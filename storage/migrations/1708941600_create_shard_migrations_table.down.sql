@@ -0,0 +1,6 @@
+BEGIN;
+
+drop table shard_migration_checkpoints;
+drop table shard_migrations;
+
+COMMIT;
@@ -0,0 +1,21 @@
+BEGIN;
+
+create table shard_migrations
+(
+    to_version   integer
+        primary key,
+    from_version integer     not null,
+    phase        varchar(20) not null,
+    updated_at   timestamp default CURRENT_TIMESTAMP
+);
+
+create table shard_migration_checkpoints
+(
+    to_version      integer not null,
+    shard_index     integer not null,
+    last_message_id varchar(36) not null,
+    updated_at      timestamp default CURRENT_TIMESTAMP,
+    primary key (to_version, shard_index)
+);
+
+COMMIT;
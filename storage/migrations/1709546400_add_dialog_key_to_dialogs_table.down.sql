@@ -0,0 +1,12 @@
+BEGIN;
+
+drop procedure backfill_dialogs_dialog_key(integer);
+drop trigger dialogs_set_dialog_key on dialogs;
+drop function dialogs_set_dialog_key();
+
+alter table dialogs
+    drop column dialog_key;
+
+drop function make_dialog_key(uuid, uuid);
+
+COMMIT;
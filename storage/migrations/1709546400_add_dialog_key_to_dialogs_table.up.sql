@@ -0,0 +1,53 @@
+BEGIN;
+
+create function make_dialog_key(first_user_id uuid, second_user_id uuid) returns text
+    language sql
+    immutable as
+$$
+select least(first_user_id, second_user_id)::text || ':' || greatest(first_user_id, second_user_id)::text
+$$;
+
+alter table dialogs
+    add column dialog_key text;
+
+create function dialogs_set_dialog_key() returns trigger
+    language plpgsql as
+$$
+begin
+    new.dialog_key := make_dialog_key(new.sender_id, new.receiver_id);
+    return new;
+end
+$$;
+
+create trigger dialogs_set_dialog_key
+    before insert or update of sender_id, receiver_id
+    on dialogs
+    for each row
+execute procedure dialogs_set_dialog_key();
+
+-- Commits after every batch, so rows stay locked only for the duration of one batch.
+create procedure backfill_dialogs_dialog_key(batch_size integer)
+    language plpgsql as
+$$
+declare
+    last_id integer := 0;
+    max_id  integer;
+begin
+    select coalesce(max(id), 0) into max_id from dialogs;
+
+    while last_id < max_id
+        loop
+            update dialogs
+            set dialog_key = make_dialog_key(sender_id, receiver_id)
+            where id > last_id
+              and id <= last_id + batch_size
+              and dialog_key is null;
+
+            last_id := last_id + batch_size;
+
+            commit;
+        end loop;
+end
+$$;
+
+COMMIT;
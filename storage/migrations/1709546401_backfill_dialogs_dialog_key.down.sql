@@ -0,0 +1 @@
+-- The backfilled column is dropped by the down migration of 1709546400_add_dialog_key_to_dialogs_table.
@@ -0,0 +1 @@
+drop index concurrently dialogs_dialog_key_created_at_id_idx;
@@ -0,0 +1,3 @@
+-- Built concurrently to keep writes going, which is not allowed in a transaction block either.
+create index concurrently dialogs_dialog_key_created_at_id_idx
+    on dialogs (dialog_key, created_at, id);
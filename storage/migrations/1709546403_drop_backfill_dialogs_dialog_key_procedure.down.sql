@@ -0,0 +1,28 @@
+BEGIN;
+
+-- Commits after every batch, so rows stay locked only for the duration of one batch.
+create procedure backfill_dialogs_dialog_key(batch_size integer)
+    language plpgsql as
+$$
+declare
+    last_id integer := 0;
+    max_id  integer;
+begin
+    select coalesce(max(id), 0) into max_id from dialogs;
+
+    while last_id < max_id
+        loop
+            update dialogs
+            set dialog_key = make_dialog_key(sender_id, receiver_id)
+            where id > last_id
+              and id <= last_id + batch_size
+              and dialog_key is null;
+
+            last_id := last_id + batch_size;
+
+            commit;
+        end loop;
+end
+$$;
+
+COMMIT;
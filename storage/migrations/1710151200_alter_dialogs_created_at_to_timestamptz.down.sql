@@ -0,0 +1,8 @@
+BEGIN;
+
+set local timezone = 'UTC';
+
+alter table dialogs
+    alter column created_at type timestamp;
+
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN;
+
+-- Stored values are UTC. With the session in UTC PostgreSQL 12+ changes the type without rewriting the table.
+set local timezone = 'UTC';
+
+alter table dialogs
+    alter column created_at type timestamptz;
+
+COMMIT;
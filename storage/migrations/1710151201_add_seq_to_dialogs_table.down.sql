@@ -0,0 +1,12 @@
+BEGIN;
+
+drop procedure backfill_dialogs_seq(integer);
+drop trigger dialogs_set_seq on dialogs;
+drop function dialogs_set_seq();
+
+alter table dialogs
+    drop column seq;
+
+drop table dialog_sequences;
+
+COMMIT;
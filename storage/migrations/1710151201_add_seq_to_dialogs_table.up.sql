@@ -0,0 +1,86 @@
+BEGIN;
+
+create table dialog_sequences
+(
+    dialog_key text
+        primary key,
+    last_seq   bigint not null
+);
+
+alter table dialogs
+    add column seq bigint;
+
+-- The counter row of a dialog is locked until the insert commits, so concurrent sends get consecutive numbers
+-- and a rolled back send gives its number back.
+create function dialogs_set_seq() returns trigger
+    language plpgsql as
+$$
+begin
+    if new.seq is not null then
+        -- Copied messages keep their numbers, the dialog continues after them.
+        insert into dialog_sequences as s (dialog_key, last_seq)
+        values (new.dialog_key, new.seq)
+        on conflict (dialog_key) do update set last_seq = greatest(s.last_seq, excluded.last_seq);
+
+        return new;
+    end if;
+
+    update dialog_sequences
+    set last_seq = last_seq + 1
+    where dialog_key = new.dialog_key
+    returning last_seq into new.seq;
+
+    if not found then
+        -- The first message since this migration is numbered after the messages numbered by the backfill.
+        insert into dialog_sequences as s (dialog_key, last_seq)
+        values (new.dialog_key, (select count(*) + 1 from dialogs where dialog_key = new.dialog_key))
+        on conflict (dialog_key) do update set last_seq = s.last_seq + 1
+        returning last_seq into new.seq;
+    end if;
+
+    return new;
+end
+$$;
+
+-- Runs after dialogs_set_dialog_key, triggers of the same event fire in name order.
+create trigger dialogs_set_seq
+    before insert
+    on dialogs
+    for each row
+execute procedure dialogs_set_seq();
+
+-- Numbers messages sent before this migration dialog by dialog, committing after every batch of dialogs.
+create procedure backfill_dialogs_seq(batch_size integer)
+    language plpgsql as
+$$
+declare
+    last_dialog_key text := '';
+    dialog_keys     text[];
+begin
+    loop
+        select array_agg(dialog_key order by dialog_key)
+        into dialog_keys
+        from (select distinct dialog_key
+              from dialogs
+              where dialog_key > last_dialog_key
+              order by dialog_key
+              limit batch_size) batch;
+
+        exit when dialog_keys is null;
+
+        update dialogs d
+        set seq = numbered.seq
+        from (select id, row_number() over (partition by dialog_key order by created_at, id) as seq
+              from dialogs
+              where dialog_key = any (dialog_keys)
+                and seq is null) numbered
+        where d.id = numbered.id;
+
+        last_dialog_key := dialog_keys[array_length(dialog_keys, 1)];
+
+        commit;
+    end loop;
+end
+$$;
+
+COMMIT;
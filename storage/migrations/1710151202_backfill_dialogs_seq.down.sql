@@ -0,0 +1 @@
+-- The backfilled column is dropped by the down migration of 1710151201_add_seq_to_dialogs_table.
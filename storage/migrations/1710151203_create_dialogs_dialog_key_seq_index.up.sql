@@ -0,0 +1,3 @@
+-- Built concurrently to keep writes going, which is not allowed in a transaction block either.
+create unique index concurrently dialogs_dialog_key_seq_idx
+    on dialogs (dialog_key, seq);
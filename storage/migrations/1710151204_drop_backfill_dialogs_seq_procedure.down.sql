@@ -0,0 +1,37 @@
+BEGIN;
+
+-- Numbers messages sent before this migration dialog by dialog, committing after every batch of dialogs.
+create procedure backfill_dialogs_seq(batch_size integer)
+    language plpgsql as
+$$
+declare
+    last_dialog_key text := '';
+    dialog_keys     text[];
+begin
+    loop
+        select array_agg(dialog_key order by dialog_key)
+        into dialog_keys
+        from (select distinct dialog_key
+              from dialogs
+              where dialog_key > last_dialog_key
+              order by dialog_key
+              limit batch_size) batch;
+
+        exit when dialog_keys is null;
+
+        update dialogs d
+        set seq = numbered.seq
+        from (select id, row_number() over (partition by dialog_key order by created_at, id) as seq
+              from dialogs
+              where dialog_key = any (dialog_keys)
+                and seq is null) numbered
+        where d.id = numbered.id;
+
+        last_dialog_key := dialog_keys[array_length(dialog_keys, 1)];
+
+        commit;
+    end loop;
+end
+$$;
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+drop procedure backfill_dialogs_seq(integer);
+
+COMMIT;
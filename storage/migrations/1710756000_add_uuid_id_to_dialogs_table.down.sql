@@ -0,0 +1,13 @@
+BEGIN;
+
+drop procedure backfill_dialogs_new_id(integer);
+drop trigger dialogs_set_new_id on dialogs;
+drop function dialogs_set_new_id();
+
+alter table dialogs
+    drop column new_id,
+    drop column new_forwarded_from_message_id;
+
+drop function uuid_v7_at(timestamptz);
+
+COMMIT;
@@ -0,0 +1,62 @@
+BEGIN;
+
+-- Builds a UUIDv7 for messages stored before ids were generated by the service, so they sort before newer ones.
+create function uuid_v7_at(ts timestamptz) returns uuid
+    language sql
+    volatile as
+$$
+select (lpad(to_hex((extract(epoch from ts) * 1000)::bigint), 12, '0')
+    || '7' || substr(r, 1, 3)
+    || substr('89ab', 1 + (random() * 3)::integer, 1)
+    || substr(r, 4, 15))::uuid
+from md5(random()::text || clock_timestamp()::text) r
+$$;
+
+alter table dialogs
+    add column new_id                        uuid,
+    add column new_forwarded_from_message_id varchar(36);
+
+-- Fills the new columns for messages stored by instances that do not know about them yet.
+create function dialogs_set_new_id() returns trigger
+    language plpgsql as
+$$
+begin
+    new.new_id := coalesce(new.new_id, uuid_v7_at(coalesce(new.created_at, now())));
+    new.new_forwarded_from_message_id := coalesce(new.new_forwarded_from_message_id, new.forwarded_from_message_id::text);
+    return new;
+end
+$$;
+
+create trigger dialogs_set_new_id
+    before insert
+    on dialogs
+    for each row
+execute procedure dialogs_set_new_id();
+
+-- Commits after every batch, so rows stay locked only for the duration of one batch.
+create procedure backfill_dialogs_new_id(batch_size integer)
+    language plpgsql as
+$$
+declare
+    last_id integer := 0;
+    max_id  integer;
+begin
+    select coalesce(max(id), 0) into max_id from dialogs;
+
+    while last_id < max_id
+        loop
+            update dialogs
+            set new_id                        = uuid_v7_at(created_at),
+                new_forwarded_from_message_id = forwarded_from_message_id::text
+            where id > last_id
+              and id <= last_id + batch_size
+              and new_id is null;
+
+            last_id := last_id + batch_size;
+
+            commit;
+        end loop;
+end
+$$;
+
+COMMIT;
@@ -0,0 +1 @@
+-- The backfilled columns are dropped by the down migration of 1710756000_add_uuid_id_to_dialogs_table.
@@ -0,0 +1,2 @@
+-- A procedure can commit only outside a transaction block, keep this the only statement of the migration.
+call backfill_dialogs_new_id(10000);
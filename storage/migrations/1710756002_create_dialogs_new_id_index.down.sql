@@ -0,0 +1 @@
+drop index concurrently dialogs_new_id_idx;
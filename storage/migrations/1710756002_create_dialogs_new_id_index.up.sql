@@ -0,0 +1,3 @@
+-- Becomes the primary key, built concurrently to keep writes going.
+create unique index concurrently dialogs_new_id_idx
+    on dialogs (new_id);
@@ -0,0 +1,3 @@
+-- Keeps integer ids resolvable once the primary key moves to the new id.
+create unique index concurrently dialogs_legacy_id_idx
+    on dialogs (id);
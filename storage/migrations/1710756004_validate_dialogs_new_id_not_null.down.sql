@@ -0,0 +1,6 @@
+BEGIN;
+
+alter table dialogs
+    drop constraint dialogs_new_id_not_null;
+
+COMMIT;
@@ -0,0 +1,10 @@
+BEGIN;
+
+-- Validation does not block writes, and a valid check lets set not null skip the table scan.
+alter table dialogs
+    add constraint dialogs_new_id_not_null check (new_id is not null) not valid;
+
+alter table dialogs
+    validate constraint dialogs_new_id_not_null;
+
+COMMIT;
@@ -0,0 +1,41 @@
+BEGIN;
+
+drop trigger dialogs_set_new_id on dialogs;
+drop function dialogs_set_new_id();
+drop procedure backfill_dialogs_new_id(integer);
+drop function uuid_v7_at(timestamptz);
+
+alter table dialogs
+    alter column new_id set not null;
+
+alter table dialogs
+    drop constraint dialogs_new_id_not_null,
+    drop constraint dialogs_pkey;
+
+alter table dialogs
+    rename column id to legacy_id;
+
+alter table dialogs
+    alter column legacy_id drop not null,
+    alter column legacy_id drop default;
+
+alter table dialogs
+    rename column new_id to id;
+
+alter table dialogs
+    add constraint dialogs_pkey primary key using index dialogs_new_id_idx;
+
+alter table dialogs
+    drop column forwarded_from_message_id;
+
+alter table dialogs
+    rename column new_forwarded_from_message_id to forwarded_from_message_id;
+
+-- Pins and stars keep the id they were made with, integer ids resolve through legacy_id.
+alter table dialog_pins
+    alter column message_id type varchar(36);
+
+alter table dialog_stars
+    alter column message_id type varchar(36);
+
+COMMIT;
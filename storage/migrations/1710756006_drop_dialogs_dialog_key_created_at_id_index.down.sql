@@ -0,0 +1,2 @@
+create index concurrently dialogs_dialog_key_created_at_id_idx
+    on dialogs (dialog_key, created_at, id);
@@ -0,0 +1,2 @@
+-- Superseded by dialogs_dialog_key_seq_idx, and its id column holds legacy ids now.
+drop index concurrently dialogs_dialog_key_created_at_id_idx;
@@ -0,0 +1,36 @@
+BEGIN;
+
+create or replace function dialogs_set_seq() returns trigger
+    language plpgsql as
+$$
+begin
+    if new.seq is not null then
+        -- Copied messages keep their numbers, the dialog continues after them.
+        insert into dialog_sequences as s (dialog_key, last_seq)
+        values (new.dialog_key, new.seq)
+        on conflict (dialog_key) do update set last_seq = greatest(s.last_seq, excluded.last_seq);
+
+        return new;
+    end if;
+
+    update dialog_sequences
+    set last_seq = last_seq + 1
+    where dialog_key = new.dialog_key
+    returning last_seq into new.seq;
+
+    if not found then
+        -- The first message since this migration is numbered after the messages numbered by the backfill.
+        insert into dialog_sequences as s (dialog_key, last_seq)
+        values (new.dialog_key, (select count(*) + 1 from dialogs where dialog_key = new.dialog_key))
+        on conflict (dialog_key) do update set last_seq = s.last_seq + 1
+        returning last_seq into new.seq;
+    end if;
+
+    return new;
+end
+$$;
+
+alter table dialog_sequences
+    drop column first_created_at;
+
+COMMIT;
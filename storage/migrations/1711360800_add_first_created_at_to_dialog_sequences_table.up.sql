@@ -0,0 +1,43 @@
+BEGIN;
+
+-- Lower bound of created_at in a dialog, lets dialog reads skip partitions from before the dialog started.
+alter table dialog_sequences
+    add column first_created_at timestamptz;
+
+update dialog_sequences s
+set first_created_at = (select d.created_at from dialogs d where d.dialog_key = s.dialog_key and d.seq = 1);
+
+create or replace function dialogs_set_seq() returns trigger
+    language plpgsql as
+$$
+begin
+    if new.seq is not null then
+        -- Copied messages keep their numbers, the dialog continues after them.
+        insert into dialog_sequences as s (dialog_key, last_seq, first_created_at)
+        values (new.dialog_key, new.seq, new.created_at)
+        on conflict (dialog_key) do update set last_seq         = greatest(s.last_seq, excluded.last_seq),
+                                               first_created_at = least(s.first_created_at, excluded.first_created_at);
+
+        return new;
+    end if;
+
+    update dialog_sequences
+    set last_seq = last_seq + 1
+    where dialog_key = new.dialog_key
+    returning last_seq into new.seq;
+
+    if not found then
+        -- The first message since this migration is numbered after the messages numbered by the backfill.
+        insert into dialog_sequences as s (dialog_key, last_seq, first_created_at)
+        values (new.dialog_key,
+                (select count(*) + 1 from dialogs where dialog_key = new.dialog_key),
+                coalesce((select created_at from dialogs where dialog_key = new.dialog_key and seq = 1), new.created_at))
+        on conflict (dialog_key) do update set last_seq = s.last_seq + 1
+        returning last_seq into new.seq;
+    end if;
+
+    return new;
+end
+$$;
+
+COMMIT;
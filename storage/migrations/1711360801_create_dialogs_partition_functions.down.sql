@@ -0,0 +1,7 @@
+BEGIN;
+
+drop function expire_dialogs_partitions(timestamptz, boolean);
+drop function ensure_dialogs_partitions(integer);
+drop function dialogs_partition_upper_bound(regclass);
+
+COMMIT;
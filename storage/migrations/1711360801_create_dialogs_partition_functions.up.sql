@@ -0,0 +1,75 @@
+BEGIN;
+
+create function dialogs_partition_upper_bound(partition regclass) returns timestamptz
+    language sql
+    stable as
+$$
+select substring(pg_get_expr(c.relpartbound, c.oid) from 'TO \(''([^'']+)''\)')::timestamptz
+from pg_class c
+where c.oid = partition
+$$;
+
+-- Creates monthly partitions after the last one up to months_ahead months after the current month and returns their names.
+-- Row triggers are created on every partition, since PostgreSQL 12 does not allow them on a partitioned table.
+create function ensure_dialogs_partitions(months_ahead integer) returns setof text
+    language plpgsql as
+$$
+declare
+    current_month  timestamptz := date_trunc('month', now() at time zone 'UTC') at time zone 'UTC';
+    partition_from timestamptz;
+    partition_to   timestamptz;
+    partition_name text;
+begin
+    perform pg_advisory_xact_lock(hashtext('dialogs_partitions'));
+
+    select coalesce(max(dialogs_partition_upper_bound(i.inhrelid)), current_month)
+    into partition_from
+    from pg_inherits i
+    where i.inhparent = 'dialogs'::regclass;
+
+    while partition_from <= ((current_month at time zone 'UTC') + make_interval(months => months_ahead)) at time zone 'UTC'
+        loop
+            partition_to := ((partition_from at time zone 'UTC') + interval '1 month') at time zone 'UTC';
+            partition_name := 'dialogs_' || to_char(partition_from at time zone 'UTC', 'YYYY_MM');
+
+            execute format('create table %I partition of dialogs for values from (%L) to (%L)',
+                           partition_name, partition_from, partition_to);
+            execute format('create trigger dialogs_set_dialog_key before insert or update of sender_id, receiver_id on %I '
+                               'for each row execute procedure dialogs_set_dialog_key()', partition_name);
+            execute format('create trigger dialogs_set_seq before insert on %I '
+                               'for each row execute procedure dialogs_set_seq()', partition_name);
+
+            return next partition_name;
+
+            partition_from := partition_to;
+        end loop;
+end
+$$;
+
+-- Detaches partitions with rows older than older_than only and returns their names, drops them when asked to.
+create function expire_dialogs_partitions(older_than timestamptz, drop_expired boolean) returns setof text
+    language plpgsql as
+$$
+declare
+    partition regclass;
+begin
+    perform pg_advisory_xact_lock(hashtext('dialogs_partitions'));
+
+    for partition in select i.inhrelid::regclass
+                     from pg_inherits i
+                     where i.inhparent = 'dialogs'::regclass
+                       and dialogs_partition_upper_bound(i.inhrelid) <= older_than
+                     order by dialogs_partition_upper_bound(i.inhrelid)
+        loop
+            execute format('alter table dialogs detach partition %s', partition);
+
+            if drop_expired then
+                execute format('drop table %s', partition);
+            end if;
+
+            return next partition::text;
+        end loop;
+end
+$$;
+
+COMMIT;
@@ -0,0 +1 @@
+drop index concurrently dialogs_legacy_id_created_at_idx;
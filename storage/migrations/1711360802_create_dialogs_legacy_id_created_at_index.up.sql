@@ -0,0 +1,4 @@
+-- The following indexes match the indexes of the partitioned dialogs table,
+-- so the existing table is attached as a partition without building them under a lock.
+create unique index concurrently dialogs_legacy_id_created_at_idx
+    on dialogs (id, created_at);
@@ -0,0 +1 @@
+drop index concurrently dialogs_legacy_dialog_key_seq_idx;
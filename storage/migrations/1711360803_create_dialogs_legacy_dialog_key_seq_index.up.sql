@@ -0,0 +1,2 @@
+create index concurrently dialogs_legacy_dialog_key_seq_idx
+    on dialogs (dialog_key, seq);
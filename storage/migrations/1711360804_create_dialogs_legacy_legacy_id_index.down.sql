@@ -0,0 +1 @@
+drop index concurrently dialogs_legacy_legacy_id_idx;
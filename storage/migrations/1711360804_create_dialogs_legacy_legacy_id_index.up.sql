@@ -0,0 +1,2 @@
+create index concurrently dialogs_legacy_legacy_id_idx
+    on dialogs (legacy_id);
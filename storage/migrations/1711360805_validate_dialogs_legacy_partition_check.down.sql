@@ -0,0 +1,6 @@
+BEGIN;
+
+alter table dialogs
+    drop constraint dialogs_legacy_partition_check;
+
+COMMIT;
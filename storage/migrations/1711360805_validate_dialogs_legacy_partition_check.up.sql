@@ -0,0 +1,17 @@
+BEGIN;
+
+-- The existing table becomes the partition of everything before the month after next,
+-- a validated check proves that without scanning the table under a lock.
+do
+$$
+    begin
+        execute format('alter table dialogs add constraint dialogs_legacy_partition_check '
+                           'check (created_at is not null and created_at < %L) not valid',
+                       (date_trunc('month', now() at time zone 'UTC') + interval '2 months') at time zone 'UTC');
+    end
+$$;
+
+alter table dialogs
+    validate constraint dialogs_legacy_partition_check;
+
+COMMIT;
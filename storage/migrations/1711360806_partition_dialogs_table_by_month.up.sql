@@ -0,0 +1,50 @@
+BEGIN;
+
+alter table dialogs
+    alter column created_at set not null;
+
+-- Partitioned indexes cannot be unique without the partition key, the sequence and the id generator keep them unique.
+alter table dialogs
+    drop constraint dialogs_pkey;
+
+drop index dialogs_dialog_key_seq_idx;
+drop index dialogs_legacy_id_idx;
+
+alter table dialogs
+    rename to dialogs_legacy;
+
+create table dialogs
+(
+    like dialogs_legacy including defaults
+) partition by range (created_at);
+
+create unique index dialogs_id_created_at_idx
+    on dialogs (id, created_at);
+
+create index dialogs_dialog_key_seq_idx
+    on dialogs (dialog_key, seq);
+
+create index dialogs_legacy_id_idx
+    on dialogs (legacy_id);
+
+do
+$$
+    declare
+        legacy_upper_bound text;
+    begin
+        select substring(pg_get_constraintdef(oid) from '''([^'']+)''')
+        into legacy_upper_bound
+        from pg_constraint
+        where conname = 'dialogs_legacy_partition_check';
+
+        execute format('alter table dialogs attach partition dialogs_legacy for values from (minvalue) to (%L)',
+                       legacy_upper_bound);
+    end
+$$;
+
+alter table dialogs_legacy
+    drop constraint dialogs_legacy_partition_check;
+
+select ensure_dialogs_partitions(3);
+
+COMMIT;
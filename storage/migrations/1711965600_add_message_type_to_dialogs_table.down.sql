@@ -0,0 +1,6 @@
+BEGIN;
+
+alter table dialogs
+    drop column message_type;
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+-- Every message is a text message for now, the type lets other kinds of messages get their own retention.
+-- A constant default is stored in the catalog, the partitions are not rewritten.
+alter table dialogs
+    add column message_type varchar(32) not null default 'text';
+
+COMMIT;
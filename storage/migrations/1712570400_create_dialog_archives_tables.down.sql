@@ -0,0 +1,7 @@
+BEGIN;
+
+-- The archive files stay in the archive storage, restoring them needs these tables.
+drop table dialog_archive_messages;
+drop table dialog_archives;
+
+COMMIT;
@@ -0,0 +1,41 @@
+BEGIN;
+
+create table dialog_archives
+(
+    id             bigserial
+        primary key,
+    first_user_id  uuid        not null,
+    second_user_id uuid        not null,
+    month          date        not null,
+    first_seq      bigint      not null,
+    last_seq       bigint      not null,
+    message_count  integer     not null,
+    object_key     text        not null
+        unique,
+    checksum       char(64)    not null,
+    created_at     timestamptz not null default now()
+);
+
+create index dialog_archives_first_user_id_second_user_id_last_seq_idx
+    on dialog_archives (first_user_id, second_user_id, last_seq);
+
+create index dialog_archives_month_idx
+    on dialog_archives (month);
+
+-- Ids of archived messages, pinned and starred messages keep resolving after they leave the dialogs table.
+create table dialog_archive_messages
+(
+    message_id uuid
+        primary key,
+    legacy_id  integer,
+    archive_id bigint not null
+        references dialog_archives (id) on delete cascade
+);
+
+create index dialog_archive_messages_legacy_id_idx
+    on dialog_archive_messages (legacy_id);
+
+create index dialog_archive_messages_archive_id_idx
+    on dialog_archive_messages (archive_id);
+
+COMMIT;
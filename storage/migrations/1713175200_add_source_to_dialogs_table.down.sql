@@ -0,0 +1,7 @@
+BEGIN;
+
+-- Imported messages become indistinguishable from messages stored here, their legacy ids may repeat.
+alter table dialogs
+    drop column source;
+
+COMMIT;
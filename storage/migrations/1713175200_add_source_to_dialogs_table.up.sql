@@ -0,0 +1,9 @@
+BEGIN;
+
+-- Messages imported from the monolith keep the ids of the monolith as legacy ids, which may repeat the legacy ids
+-- of messages stored here before ids became UUIDs. The source tells them apart.
+-- A constant default is stored in the catalog, the partitions are not rewritten.
+alter table dialogs
+    add column source varchar(16) not null default 'dialog';
+
+COMMIT;
@@ -0,0 +1,19 @@
+BEGIN;
+
+-- The inbox keeps one row per dialog and participant, dialogs live on shards and cannot be listed by user there.
+create table dialog_inbox
+(
+    user_id         uuid                     not null,
+    peer_id         uuid                     not null,
+    last_message_id uuid                     not null,
+    last_sender_id  uuid                     not null,
+    last_text       varchar(1000)            not null,
+    last_seq        bigint                   not null,
+    last_message_at timestamp with time zone not null,
+    unread_count    integer default 0        not null,
+    primary key (user_id, peer_id)
+);
+
+create index dialog_inbox_user_id_last_message_at_idx on dialog_inbox (user_id, last_message_at desc, peer_id desc);
+
+COMMIT;
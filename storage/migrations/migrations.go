@@ -0,0 +1,25 @@
+// Package migrations embeds the schema migrations into the binary, so that it migrates from any working directory.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed *.sql sqlite/*.sql
+var files embed.FS
+
+// Postgres holds the migrations of the postgres backend, SQLite the ones of the sqlite backend.
+var (
+	Postgres fs.FS = files
+	SQLite   fs.FS = sub(files, "sqlite")
+)
+
+func sub(fsys fs.FS, dir string) fs.FS { //nolint:ireturn
+	subFS, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+
+	return subFS
+}
@@ -0,0 +1,5 @@
+drop table dialog_drafts;
+drop table dialog_stars;
+drop table dialog_settings;
+drop table dialog_pins;
+drop table dialogs;
@@ -0,0 +1,69 @@
+-- The schema of the Postgres migrations up to the dialog archive, without partitions, shards and archives.
+-- Migrations of the sqlite driver run in a transaction already.
+
+create table dialogs
+(
+    id                        text      not null
+        primary key,
+    legacy_id                 integer,
+    sender_id                 text      not null,
+    receiver_id               text      not null,
+    dialog_key                text      not null,
+    text                      text      not null,
+    message_type              text      not null default 'text',
+    forwarded_from_user_id    text,
+    forwarded_from_message_id text,
+    seq                       integer   not null,
+    created_at                timestamp not null
+);
+
+create unique index dialogs_dialog_key_seq_idx
+    on dialogs (dialog_key, seq);
+
+create index dialogs_legacy_id_idx
+    on dialogs (legacy_id);
+
+create table dialog_pins
+(
+    message_id     text      not null
+        primary key,
+    first_user_id  text      not null,
+    second_user_id text      not null,
+    pinned_by      text      not null,
+    created_at     timestamp not null
+);
+
+create index dialog_pins_first_user_id_second_user_id_idx
+    on dialog_pins (first_user_id, second_user_id);
+
+create table dialog_settings
+(
+    user_id     text      not null,
+    peer_id     text      not null,
+    muted_until timestamp,
+    archived    boolean   not null default false,
+    -- JSON array of folder names.
+    folders     text      not null default '[]',
+    updated_at  timestamp not null,
+    primary key (user_id, peer_id)
+);
+
+create table dialog_stars
+(
+    user_id    text      not null,
+    message_id text      not null,
+    created_at timestamp not null,
+    primary key (user_id, message_id)
+);
+
+create index dialog_stars_user_id_created_at_idx
+    on dialog_stars (user_id, created_at desc);
+
+create table dialog_drafts
+(
+    user_id    text      not null,
+    peer_id    text      not null,
+    text       text      not null,
+    updated_at timestamp not null,
+    primary key (user_id, peer_id)
+);
@@ -0,0 +1,2 @@
+alter table dialogs
+    drop column source;
@@ -0,0 +1,4 @@
+-- Messages imported from the monolith keep the ids of the monolith as legacy ids, the source tells them apart
+-- from the legacy ids of messages stored here.
+alter table dialogs
+    add column source text not null default 'dialog';
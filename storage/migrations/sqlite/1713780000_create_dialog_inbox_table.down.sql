@@ -0,0 +1 @@
+drop table dialog_inbox;
@@ -0,0 +1,14 @@
+create table dialog_inbox
+(
+    user_id         text      not null,
+    peer_id         text      not null,
+    last_message_id text      not null,
+    last_sender_id  text      not null,
+    last_text       text      not null,
+    last_seq        integer   not null,
+    last_message_at timestamp not null,
+    unread_count    integer   not null default 0,
+    primary key (user_id, peer_id)
+);
+
+create index dialog_inbox_user_id_last_message_at_idx on dialog_inbox (user_id, last_message_at desc, peer_id desc);